@@ -2,10 +2,13 @@ module club.xiaojiawei/hs-script-update
 
 go 1.23.1
 
-require github.com/lxn/walk v0.0.0-20210112085537-c389da54e794
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/lxn/walk v0.0.0-20210112085537-c389da54e794
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
+)
 
 require (
 	github.com/lxn/win v0.0.0-20210218163916-a377121e959e // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 	gopkg.in/Knetic/govaluate.v3 v3.0.0 // indirect
 )
@@ -0,0 +1,327 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Entry 压缩包中的条目
+type Entry struct {
+	// Name 条目相对路径，使用 / 分隔，目录不带结尾的 /
+	Name string
+	// Mode 文件权限
+	Mode os.FileMode
+	// Size 未压缩大小
+	Size int64
+	// CompressedSize 压缩后大小，格式不支持时为 0
+	CompressedSize int64
+	// IsDir 是否为目录
+	IsDir bool
+}
+
+// WalkFunc 遍历回调，r 为条目内容（目录为 nil），仅在回调执行期间有效
+type WalkFunc func(entry *Entry, r io.Reader) error
+
+// Archive 压缩包接口
+type Archive interface {
+	// Entries 列出所有条目
+	Entries() ([]*Entry, error)
+
+	// Walk 按顺序遍历所有条目
+	Walk(fn WalkFunc) error
+
+	// Close 关闭压缩包
+	Close() error
+}
+
+// ErrUnsupportedFormat 不支持的压缩包格式
+var ErrUnsupportedFormat = errors.New("不支持的压缩包格式")
+
+// Format 压缩包格式
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+)
+
+var (
+	magicZip      = []byte("PK\x03\x04")
+	magicZipEmpty = []byte("PK\x05\x06")
+	magicGzip     = []byte{0x1f, 0x8b}
+	magicZstd     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicTar      = []byte("ustar")
+)
+
+// tarMagicOffset tar 头部中 magic 字段的偏移
+const tarMagicOffset = 257
+
+// DetectFormat 检测压缩包格式，优先根据文件头判断，无法识别时根据扩展名判断
+func DetectFormat(filePath string) (Format, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, tarMagicOffset+len(magicTar))
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, magicZip), bytes.HasPrefix(header, magicZipEmpty):
+		return FormatZip, nil
+	case bytes.HasPrefix(header, magicGzip):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(header, magicZstd):
+		return FormatTarZst, nil
+	case len(header) >= tarMagicOffset+len(magicTar) &&
+		bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(magicTar)], magicTar):
+		return FormatTar, nil
+	}
+
+	return DetectFormatByName(filePath)
+}
+
+// DetectFormatByName 根据扩展名判断压缩包格式
+func DetectFormatByName(filePath string) (Format, error) {
+	name := strings.ToLower(filepath.Base(filePath))
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return FormatZip, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return FormatTarZst, nil
+	case strings.HasSuffix(name, ".tar"):
+		return FormatTar, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, filePath)
+}
+
+// Open 打开压缩包，使用默认的解压限制
+func Open(filePath string) (Archive, error) {
+	return OpenWithLimits(filePath, DefaultLimits())
+}
+
+// OpenWithLimits 打开压缩包，返回的 Archive 会对条目路径、类型、大小和数量进行安全检查
+func OpenWithLimits(filePath string, limits Limits) (Archive, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := DetectFormat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var inner Archive
+	switch format {
+	case FormatZip:
+		inner, err = openZip(filePath)
+	case FormatTar, FormatTarGz, FormatTarZst:
+		inner, err = openTar(filePath, format)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkedArchive{inner: inner, limits: limits, archiveSize: info.Size()}, nil
+}
+
+// FindRoot 查找压缩包内的根目录前缀
+// 如果所有条目都位于同一个顶层目录下，返回 "该目录/"，否则返回空字符串
+func FindRoot(entries []*Entry) string {
+	root := ""
+	for _, e := range entries {
+		idx := strings.Index(e.Name, "/")
+		if idx < 0 {
+			if e.IsDir && (root == "" || root == e.Name) {
+				root = e.Name
+				continue
+			}
+			// 顶层存在文件，说明没有统一的根目录
+			return ""
+		}
+		top := e.Name[:idx]
+		if root == "" {
+			root = top
+		} else if root != top {
+			return ""
+		}
+	}
+	if root == "" {
+		return ""
+	}
+	return root + "/"
+}
+
+// SafeJoin 将条目路径拼接到目标目录，并确保结果不会逃逸出目标目录（防止 zip slip 攻击）
+func SafeJoin(destDir, name string) (string, error) {
+	cleanDest := filepath.Clean(destDir)
+	fpath := filepath.Join(cleanDest, filepath.FromSlash(name))
+	if !strings.HasPrefix(fpath, cleanDest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("非法的文件路径: %s", name)
+	}
+	return fpath, nil
+}
+
+// normalizeName 规范化条目路径，拒绝绝对路径和包含 .. 的路径
+func normalizeName(name string) (string, error) {
+	original := name
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':') {
+		return "", fmt.Errorf("非法的文件路径: %s", original)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("非法的文件路径: %s", original)
+		}
+	}
+
+	name = path.Clean(name)
+	if name == "." {
+		return "", nil
+	}
+	return name, nil
+}
+
+// checkedArchive 对条目进行路径、类型、大小和数量检查的包装
+type checkedArchive struct {
+	inner       Archive
+	limits      Limits
+	archiveSize int64
+}
+
+// newTracker 创建一次遍历使用的限制检查器
+func (c *checkedArchive) newTracker() *limitTracker {
+	return &limitTracker{limits: c.limits, archiveSize: c.archiveSize}
+}
+
+// Entries 列出所有条目
+func (c *checkedArchive) Entries() ([]*Entry, error) {
+	entries, err := c.inner.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := c.newTracker()
+	result := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		name, err := normalizeName(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			continue
+		}
+		e.Name = name
+		if err := tracker.check(e); err != nil {
+			return nil, err
+		}
+		e.Mode = normalizeMode(e)
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Walk 按顺序遍历所有条目
+func (c *checkedArchive) Walk(fn WalkFunc) error {
+	tracker := c.newTracker()
+	return c.inner.Walk(func(entry *Entry, r io.Reader) error {
+		name, err := normalizeName(entry.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+		entry.Name = name
+		if err := tracker.check(entry); err != nil {
+			return err
+		}
+		entry.Mode = normalizeMode(entry)
+
+		if entry.IsDir || r == nil {
+			return fn(entry, nil)
+		}
+		if entry.Size < 0 {
+			return fmt.Errorf("文件大小非法: %s", entry.Name)
+		}
+
+		sr := &sizeCheckedReader{r: r, name: entry.Name, remaining: entry.Size}
+		if err := fn(entry, sr); err != nil {
+			return err
+		}
+		return sr.drain()
+	})
+}
+
+// Close 关闭压缩包
+func (c *checkedArchive) Close() error {
+	return c.inner.Close()
+}
+
+// sizeCheckedReader 确保读取的内容与声明的大小一致
+type sizeCheckedReader struct {
+	r         io.Reader
+	name      string
+	remaining int64
+	touched   bool
+	done      bool
+}
+
+// Read 读取内容，实际大小与声明大小不一致时返回错误
+func (s *sizeCheckedReader) Read(p []byte) (int, error) {
+	s.touched = true
+	if s.done {
+		return 0, io.EOF
+	}
+
+	if s.remaining <= 0 {
+		// 声明的大小已读完，确认后面没有多余的数据
+		var probe [1]byte
+		if n, _ := io.ReadFull(s.r, probe[:]); n > 0 {
+			return 0, fmt.Errorf("文件实际大小超过声明大小: %s", s.name)
+		}
+		s.done = true
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > s.remaining {
+		p = p[:s.remaining]
+	}
+	n, err := s.r.Read(p)
+	s.remaining -= int64(n)
+	if errors.Is(err, io.EOF) {
+		if s.remaining > 0 {
+			return n, fmt.Errorf("文件实际大小小于声明大小: %s", s.name)
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// drain 回调读取了部分内容时，读完剩余部分以完成大小校验
+func (s *sizeCheckedReader) drain() error {
+	if !s.touched || s.done {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, s)
+	return err
+}
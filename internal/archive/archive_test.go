@@ -0,0 +1,219 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// testFile 写入测试压缩包的文件
+type testFile struct {
+	name    string
+	content string
+}
+
+// writeZip 创建 zip 压缩包
+func writeZip(t *testing.T, filePath string, files []testFile) {
+	t.Helper()
+	out, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, f.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTar 按 format 创建 tar 压缩包，FormatTar 时不压缩
+func writeTar(t *testing.T, filePath string, format Format, files []testFile) {
+	t.Helper()
+	out, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var w io.WriteCloser = nopCloser{out}
+	switch format {
+	case FormatTarGz:
+		w = gzip.NewWriter(out)
+	case FormatTarZst:
+		if w, err = zstd.NewWriter(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, f.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nopCloser 不压缩时 tar 直接写入文件
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	files := []testFile{{"hs-script/app.txt", "app"}}
+
+	cases := []struct {
+		name   string
+		format Format
+	}{
+		{"package.zip", FormatZip},
+		{"package.tar", FormatTar},
+		{"package.tar.gz", FormatTarGz},
+		{"package.tar.zst", FormatTarZst},
+		// 文件头优先于扩展名
+		{"package-gz.zip", FormatTarGz},
+	}
+	for _, c := range cases {
+		filePath := filepath.Join(dir, c.name)
+		if c.format == FormatZip {
+			writeZip(t, filePath, files)
+		} else {
+			writeTar(t, filePath, c.format, files)
+		}
+		got, err := DetectFormat(filePath)
+		if err != nil {
+			t.Errorf("DetectFormat(%s) 返回错误: %v", c.name, err)
+			continue
+		}
+		if got != c.format {
+			t.Errorf("DetectFormat(%s) = %s，期望 %s", c.name, got, c.format)
+		}
+	}
+}
+
+func TestDetectFormatByName(t *testing.T) {
+	cases := map[string]Format{
+		"a.zip":     FormatZip,
+		"A.ZIP":     FormatZip,
+		"a.tar":     FormatTar,
+		"a.tgz":     FormatTarGz,
+		"a.tar.gz":  FormatTarGz,
+		"a.tzst":    FormatTarZst,
+		"a.tar.zst": FormatTarZst,
+	}
+	for name, want := range cases {
+		got, err := DetectFormatByName(name)
+		if err != nil || got != want {
+			t.Errorf("DetectFormatByName(%s) = %s, %v，期望 %s", name, got, err, want)
+		}
+	}
+
+	for _, name := range []string{"a.7z", "a.rar", "a"} {
+		if _, err := DetectFormatByName(name); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("DetectFormatByName(%s) 应返回 ErrUnsupportedFormat，实际: %v", name, err)
+		}
+	}
+}
+
+func TestOpenAndWalk(t *testing.T) {
+	dir := t.TempDir()
+	files := []testFile{
+		{"hs-script/app.txt", "app"},
+		{"hs-script/lib/a.jar", "jar"},
+	}
+
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz, FormatTarZst} {
+		filePath := filepath.Join(dir, "package."+string(format))
+		if format == FormatZip {
+			writeZip(t, filePath, files)
+		} else {
+			writeTar(t, filePath, format, files)
+		}
+
+		a, err := Open(filePath)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		entries, err := a.Entries()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if root := FindRoot(entries); root != "hs-script/" {
+			t.Errorf("%s: FindRoot = %q", format, root)
+		}
+
+		contents := make(map[string]string)
+		err = a.Walk(func(entry *Entry, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			contents[entry.Name] = string(data)
+			return err
+		})
+		a.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for _, f := range files {
+			if contents[f.name] != f.content {
+				t.Errorf("%s: %s 的内容 = %q，期望 %q", format, f.name, contents[f.name], f.content)
+			}
+		}
+	}
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "package.7z")
+	if err := os.WriteFile(filePath, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(filePath); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("应返回 ErrUnsupportedFormat，实际: %v", err)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	cases := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"hs/a.txt", "hs/lib/b.jar"}, "hs/"},
+		{[]string{"a.txt", "hs/b.txt"}, ""},
+		{[]string{"hs/a.txt", "other/b.txt"}, ""},
+		{nil, ""},
+	}
+	for _, c := range cases {
+		var entries []*Entry
+		for _, name := range c.names {
+			entries = append(entries, &Entry{Name: name})
+		}
+		if got := FindRoot(entries); got != c.want {
+			t.Errorf("FindRoot(%v) = %q，期望 %q", c.names, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+// Limits 解压资源限制，值小于等于 0 表示不限制
+type Limits struct {
+	// MaxTotalSize 解压后的最大总字节数
+	MaxTotalSize int64
+	// MaxEntries 最大条目数
+	MaxEntries int
+	// MaxCompressionRatio 最大压缩比（未压缩大小 / 压缩大小）
+	MaxCompressionRatio int64
+	// MaxPathDepth 条目路径的最大层级
+	MaxPathDepth int
+}
+
+// DefaultLimits 根据配置返回默认的解压限制
+func DefaultLimits() Limits {
+	return Limits{
+		MaxTotalSize:        config.MaxExtractSize,
+		MaxEntries:          config.MaxArchiveEntries,
+		MaxCompressionRatio: config.MaxCompressionRatio,
+		MaxPathDepth:        config.MaxArchivePathDepth,
+	}
+}
+
+// LimitCode 安全检查失败的类型
+type LimitCode string
+
+const (
+	LimitTotalSize        LimitCode = "total_size"
+	LimitEntries          LimitCode = "entries"
+	LimitCompressionRatio LimitCode = "compression_ratio"
+	LimitPathDepth        LimitCode = "path_depth"
+	LimitSymlink          LimitCode = "symlink"
+	LimitSpecialFile      LimitCode = "special_file"
+)
+
+// LimitError 压缩包未通过安全检查
+type LimitError struct {
+	Code   LimitCode
+	Entry  string
+	Limit  int64
+	Actual int64
+}
+
+// Error 返回错误描述
+func (e *LimitError) Error() string {
+	switch e.Code {
+	case LimitTotalSize:
+		return fmt.Sprintf("解压后总大小超过限制 (%d MB)，条目: %s", e.Limit>>20, e.Entry)
+	case LimitEntries:
+		return fmt.Sprintf("条目数量超过限制 (%d)", e.Limit)
+	case LimitCompressionRatio:
+		return fmt.Sprintf("压缩比 %d 超过限制 (%d)，可能是压缩炸弹，条目: %s", e.Actual, e.Limit, e.Entry)
+	case LimitPathDepth:
+		return fmt.Sprintf("路径层级 %d 超过限制 (%d)，条目: %s", e.Actual, e.Limit, e.Entry)
+	case LimitSymlink:
+		return fmt.Sprintf("不允许包含符号链接或硬链接: %s", e.Entry)
+	case LimitSpecialFile:
+		return fmt.Sprintf("不允许包含设备文件等特殊文件: %s", e.Entry)
+	}
+	return fmt.Sprintf("压缩包未通过安全检查 (%s): %s", e.Code, e.Entry)
+}
+
+// limitTracker 记录遍历过程中的累计数据
+type limitTracker struct {
+	limits      Limits
+	archiveSize int64
+	entries     int
+	totalSize   int64
+}
+
+// check 检查单个条目，并累计条目数量和大小
+func (t *limitTracker) check(entry *Entry) error {
+	if entry.Mode&os.ModeSymlink != 0 {
+		return &LimitError{Code: LimitSymlink, Entry: entry.Name}
+	}
+	if entry.Mode&(os.ModeDevice|os.ModeCharDevice|os.ModeNamedPipe|os.ModeSocket|os.ModeIrregular) != 0 {
+		return &LimitError{Code: LimitSpecialFile, Entry: entry.Name}
+	}
+
+	t.entries++
+	if t.limits.MaxEntries > 0 && t.entries > t.limits.MaxEntries {
+		return &LimitError{Code: LimitEntries, Entry: entry.Name, Limit: int64(t.limits.MaxEntries), Actual: int64(t.entries)}
+	}
+
+	depth := strings.Count(entry.Name, "/") + 1
+	if t.limits.MaxPathDepth > 0 && depth > t.limits.MaxPathDepth {
+		return &LimitError{Code: LimitPathDepth, Entry: entry.Name, Limit: int64(t.limits.MaxPathDepth), Actual: int64(depth)}
+	}
+
+	if entry.IsDir {
+		return nil
+	}
+
+	t.totalSize += entry.Size
+	if t.limits.MaxTotalSize > 0 && t.totalSize > t.limits.MaxTotalSize {
+		return &LimitError{Code: LimitTotalSize, Entry: entry.Name, Limit: t.limits.MaxTotalSize, Actual: t.totalSize}
+	}
+
+	if t.limits.MaxCompressionRatio > 0 {
+		// 单个条目的压缩比（仅 zip 等记录了压缩大小的格式）
+		if entry.CompressedSize > 0 {
+			if ratio := entry.Size / entry.CompressedSize; ratio > t.limits.MaxCompressionRatio {
+				return &LimitError{Code: LimitCompressionRatio, Entry: entry.Name, Limit: t.limits.MaxCompressionRatio, Actual: ratio}
+			}
+		}
+		// 整个压缩包的压缩比
+		if t.archiveSize > 0 {
+			if ratio := t.totalSize / t.archiveSize; ratio > t.limits.MaxCompressionRatio {
+				return &LimitError{Code: LimitCompressionRatio, Entry: entry.Name, Limit: t.limits.MaxCompressionRatio, Actual: ratio}
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeMode 规范化权限：目录 0755，可执行文件 0755，其他文件 0644
+func normalizeMode(entry *Entry) os.FileMode {
+	if entry.IsDir || entry.Mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
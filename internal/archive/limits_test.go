@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"普通路径", "hs/lib/a.jar", "hs/lib/a.jar", false},
+		{"反斜杠转换为斜杠", `hs\lib\a.jar`, "hs/lib/a.jar", false},
+		{"目录末尾的斜杠", "hs/lib/", "hs/lib", false},
+		{"清理多余的 .", "./hs/./a.txt", "hs/a.txt", false},
+		{"当前目录", "./", "", false},
+		{"绝对路径", "/etc/passwd", "", true},
+		{"反斜杠绝对路径", `\Windows\a.dll`, "", true},
+		{"盘符", "C:/Windows/a.dll", "", true},
+		{"上级目录", "../a.txt", "", true},
+		{"中间的上级目录", "hs/../../a.txt", "", true},
+		{"反斜杠上级目录", `hs\..\a.txt`, "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := normalizeName(c.input)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("normalizeName(%q) 错误 = %v，期望出错: %v", c.input, err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("normalizeName(%q) = %q，期望 %q", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	dest := t.TempDir()
+	cases := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"文件", "a.txt", filepath.Join(dest, "a.txt"), false},
+		{"子目录", "lib/a.jar", filepath.Join(dest, "lib", "a.jar"), false},
+		{"清理后仍在目录内", "lib/../a.txt", filepath.Join(dest, "a.txt"), false},
+		{"目录本身", ".", "", true},
+		{"上级目录", "../a.txt", "", true},
+		{"同名前缀的兄弟目录", "../" + filepath.Base(dest) + "-other/a.txt", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := SafeJoin(dest, c.input)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("SafeJoin(%q) 错误 = %v，期望出错: %v", c.input, err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("SafeJoin(%q) = %q，期望 %q", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestLimitTrackerCheck(t *testing.T) {
+	cases := []struct {
+		name        string
+		limits      Limits
+		archiveSize int64
+		entries     []*Entry
+		want        LimitCode
+	}{
+		{
+			"不限制",
+			Limits{},
+			1,
+			[]*Entry{{Name: "a.txt", Size: 1 << 30}, {Name: "b/c/d/e.txt", Size: 1 << 30}},
+			"",
+		},
+		{
+			"符号链接",
+			Limits{},
+			0,
+			[]*Entry{{Name: "link", Mode: os.ModeSymlink}},
+			LimitSymlink,
+		},
+		{
+			"设备文件",
+			Limits{},
+			0,
+			[]*Entry{{Name: "dev", Mode: os.ModeDevice}},
+			LimitSpecialFile,
+		},
+		{
+			"命名管道",
+			Limits{},
+			0,
+			[]*Entry{{Name: "fifo", Mode: os.ModeNamedPipe}},
+			LimitSpecialFile,
+		},
+		{
+			"条目数量",
+			Limits{MaxEntries: 2},
+			0,
+			[]*Entry{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			LimitEntries,
+		},
+		{
+			"目录也计入条目数量",
+			Limits{MaxEntries: 1},
+			0,
+			[]*Entry{{Name: "a", IsDir: true}, {Name: "a/b"}},
+			LimitEntries,
+		},
+		{
+			"路径层级",
+			Limits{MaxPathDepth: 2},
+			0,
+			[]*Entry{{Name: "a/b"}, {Name: "a/b/c"}},
+			LimitPathDepth,
+		},
+		{
+			"总大小累计",
+			Limits{MaxTotalSize: 100},
+			0,
+			[]*Entry{{Name: "a", Size: 60}, {Name: "b", Size: 60}},
+			LimitTotalSize,
+		},
+		{
+			"目录不计入总大小",
+			Limits{MaxTotalSize: 100},
+			0,
+			[]*Entry{{Name: "a", IsDir: true, Size: 1000}, {Name: "a/b", Size: 100}},
+			"",
+		},
+		{
+			"单个条目的压缩比",
+			Limits{MaxCompressionRatio: 10},
+			0,
+			[]*Entry{{Name: "a", Size: 1100, CompressedSize: 100}},
+			LimitCompressionRatio,
+		},
+		{
+			"整个压缩包的压缩比",
+			Limits{MaxCompressionRatio: 10},
+			100,
+			[]*Entry{{Name: "a", Size: 600}, {Name: "b", Size: 600}},
+			LimitCompressionRatio,
+		},
+		{
+			"压缩比在限制内",
+			Limits{MaxCompressionRatio: 10},
+			100,
+			[]*Entry{{Name: "a", Size: 1000, CompressedSize: 100}},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tracker := &limitTracker{limits: c.limits, archiveSize: c.archiveSize}
+			var err error
+			for _, entry := range c.entries {
+				if err = tracker.check(entry); err != nil {
+					break
+				}
+			}
+			if c.want == "" {
+				if err != nil {
+					t.Fatalf("不应返回错误: %v", err)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("应返回 LimitError，实际: %v", err)
+			}
+			if limitErr.Code != c.want {
+				t.Errorf("错误类型 = %s，期望 %s", limitErr.Code, c.want)
+			}
+		})
+	}
+}
+
+func TestOpenWithLimits(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "package.zip")
+	writeZip(t, filePath, []testFile{
+		{"hs/a.txt", strings.Repeat("a", 100)},
+		{"hs/b.txt", strings.Repeat("b", 100)},
+		{"hs/c.txt", strings.Repeat("c", 100)},
+	})
+
+	cases := []struct {
+		name   string
+		limits Limits
+		want   LimitCode
+	}{
+		{"在限制内", Limits{MaxEntries: 3, MaxTotalSize: 300}, ""},
+		{"条目数量", Limits{MaxEntries: 2}, LimitEntries},
+		{"总大小", Limits{MaxTotalSize: 250}, LimitTotalSize},
+		{"路径层级", Limits{MaxPathDepth: 1}, LimitPathDepth},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := OpenWithLimits(filePath, c.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+
+			_, err = a.Entries()
+			if c.want == "" {
+				if err != nil {
+					t.Fatalf("不应返回错误: %v", err)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("应返回 LimitError，实际: %v", err)
+			}
+			if limitErr.Code != c.want {
+				t.Errorf("错误类型 = %s，期望 %s", limitErr.Code, c.want)
+			}
+		})
+	}
+}
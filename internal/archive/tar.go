@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// tarArchive tar 格式压缩包（支持 gzip、zstd 压缩）
+// tar 只能顺序读取，每次遍历都会重新打开文件
+type tarArchive struct {
+	filePath string
+	format   Format
+}
+
+// openTar 打开 tar 压缩包
+func openTar(filePath string, format Format) (*tarArchive, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+	return &tarArchive{filePath: filePath, format: format}, nil
+}
+
+// Entries 列出所有条目
+func (t *tarArchive) Entries() ([]*Entry, error) {
+	var entries []*Entry
+	err := t.iterate(func(entry *Entry, _ *tar.Reader) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Walk 按顺序遍历所有条目
+func (t *tarArchive) Walk(fn WalkFunc) error {
+	return t.iterate(func(entry *Entry, tr *tar.Reader) error {
+		if entry.IsDir {
+			return fn(entry, nil)
+		}
+		return fn(entry, tr)
+	})
+}
+
+// Close 关闭压缩包
+func (t *tarArchive) Close() error {
+	return nil
+}
+
+// iterate 打开文件并依次读取 tar 头部
+func (t *tarArchive) iterate(fn func(entry *Entry, tr *tar.Reader) error) error {
+	file, err := os.Open(t.filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	switch t.format {
+	case FormatTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("读取 gzip 数据失败: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case FormatTarZst:
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("读取 zstd 数据失败: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 条目失败: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		// 符号链接、设备文件等类型保留在 Mode 中，由上层统一检查
+		mode := header.FileInfo().Mode()
+		if header.Typeflag == tar.TypeLink {
+			mode |= os.ModeSymlink
+		}
+
+		entry := &Entry{
+			Name:  header.Name,
+			Mode:  mode,
+			Size:  header.Size,
+			IsDir: header.Typeflag == tar.TypeDir,
+		}
+		if err := fn(entry, tr); err != nil {
+			return err
+		}
+	}
+}
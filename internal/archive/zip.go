@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"archive/zip"
+)
+
+// zipArchive ZIP 格式压缩包
+type zipArchive struct {
+	reader *zip.ReadCloser
+}
+
+// openZip 打开 ZIP 压缩包
+func openZip(filePath string) (*zipArchive, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchive{reader: r}, nil
+}
+
+// Entries 列出所有条目
+func (z *zipArchive) Entries() ([]*Entry, error) {
+	entries := make([]*Entry, 0, len(z.reader.File))
+	for _, f := range z.reader.File {
+		entries = append(entries, zipEntry(f))
+	}
+	return entries, nil
+}
+
+// Walk 按顺序遍历所有条目
+func (z *zipArchive) Walk(fn WalkFunc) error {
+	for _, f := range z.reader.File {
+		entry := zipEntry(f)
+		if entry.IsDir {
+			if err := fn(entry, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := walkZipFile(f, entry, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkZipFile 打开单个文件条目并执行回调
+func walkZipFile(f *zip.File, entry *Entry, fn WalkFunc) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return fn(entry, rc)
+}
+
+// Close 关闭压缩包
+func (z *zipArchive) Close() error {
+	return z.reader.Close()
+}
+
+// zipEntry 将 zip.File 转换为条目
+func zipEntry(f *zip.File) *Entry {
+	info := f.FileInfo()
+	return &Entry{
+		Name:           f.Name,
+		Mode:           f.Mode(),
+		Size:           int64(f.UncompressedSize64),
+		CompressedSize: int64(f.CompressedSize64),
+		IsDir:          info.IsDir(),
+	}
+}
@@ -0,0 +1,192 @@
+package component
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+const (
+	// App 主程序
+	App = "app"
+	// Runtime JVM 版内置的 Java 运行时
+	Runtime = "runtime"
+	// BasePlugins 随主程序发布的基础插件
+	BasePlugins = "base-plugins"
+)
+
+// Names 全部组件，按显示顺序排列
+var Names = []string{App, Runtime, BasePlugins}
+
+// Of 返回相对路径所属的组件
+func Of(relPath string) string {
+	relPath = strings.Trim(strings.ReplaceAll(relPath, "\\", "/"), "/")
+	if underDir(relPath, config.RuntimeDir) {
+		return Runtime
+	}
+	for _, plugin := range config.JVMUpdatePluginDirs {
+		if underDir(relPath, config.PluginDir+"/"+plugin) {
+			return BasePlugins
+		}
+	}
+	return App
+}
+
+// underDir 判断路径是否为目录本身或其下的内容
+func underDir(relPath, dir string) bool {
+	return relPath == dir || strings.HasPrefix(relPath, dir+"/")
+}
+
+// Component 已安装的组件
+type Component struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	Files     int       `json:"files"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Ref 发布清单中引用的组件版本
+// 更新包中不包含该组件时，从 URL 单独下载
+type Ref struct {
+	Version string `json:"version"`
+	URL     string `json:"url,omitempty"`
+	// SHA256 下载文件的校验值
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// State 安装目录中各组件的状态
+type State struct {
+	Components []Component `json:"components"`
+}
+
+// Get 返回指定组件，不存在时返回 nil
+func (s *State) Get(name string) *Component {
+	for i := range s.Components {
+		if s.Components[i].Name == name {
+			return &s.Components[i]
+		}
+	}
+	return nil
+}
+
+// Set 添加或替换组件
+func (s *State) Set(c Component) {
+	if existing := s.Get(c.Name); existing != nil {
+		*existing = c
+		return
+	}
+	s.Components = append(s.Components, c)
+}
+
+// Remove 移除组件
+func (s *State) Remove(name string) {
+	for i := range s.Components {
+		if s.Components[i].Name == name {
+			s.Components = append(s.Components[:i], s.Components[i+1:]...)
+			return
+		}
+	}
+}
+
+// LoadState 读取组件状态，文件不存在时返回空状态
+func LoadState(targetDir string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(config.ComponentStatePath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析组件状态失败: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveState 写入组件状态
+func SaveState(targetDir string, state *State) error {
+	sort.Slice(state.Components, func(i, j int) bool {
+		return index(state.Components[i].Name) < index(state.Components[j].Name)
+	})
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	statePath := filepath.Join(targetDir, filepath.FromSlash(config.ComponentStatePath))
+	return utils.WriteFileAtomic(statePath, strings.NewReader(string(data)), 0644)
+}
+
+// index 返回组件的显示顺序
+func index(name string) int {
+	for i, n := range Names {
+		if n == name {
+			return i
+		}
+	}
+	return len(Names)
+}
+
+// Split 按组件拆分文件校验值
+func Split(files map[string]string) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for relPath, hash := range files {
+		name := Of(relPath)
+		if result[name] == nil {
+			result[name] = make(map[string]string)
+		}
+		result[name][relPath] = hash
+	}
+	return result
+}
+
+// Hash 根据组件中各文件的校验值计算组件的校验值
+func Hash(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for relPath := range files {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, relPath := range paths {
+		fmt.Fprintf(h, "%s\x00%s\n", relPath, strings.ToLower(files[relPath]))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashDir 计算目录中全部文件的校验值，键为相对于 baseDir 的路径
+func HashDir(baseDir, relDir string) (map[string]string, error) {
+	files := make(map[string]string)
+	root := filepath.Join(baseDir, filepath.FromSlash(relDir))
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		hash, err := utils.HashFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -9,8 +9,8 @@ const ProjectName = "Hearthstone-Script"
 // UpdaterName 更新器名称
 const UpdaterName = "update.exe"
 
-// UpdaterBackupName 更新器备份名称
-const UpdaterBackupName = "update.exe.bak"
+// UpdateRulesName 更新规则文件名（可放在更新包或安装目录的根目录）
+const UpdateRulesName = "update-rules.json"
 
 // JVMPreserveDirs JVM版需要保留的目录
 var JVMPreserveDirs = []string{"config", "data"}
@@ -23,3 +23,109 @@ var JVMUpdatePluginDirs = []string{
 
 // NativePreserveDirs Native版需要保留的目录
 var NativePreserveDirs = []string{"config", "data"}
+
+// JVMPackageExt JVM版更新包扩展名，发布附件中没有该版本的更新包时使用
+var JVMPackageExt = ".zip"
+
+// NativePackageExt Native版更新包扩展名，发布附件中没有该版本的更新包时使用
+// 发布附件中有 .zip、.tar.gz、.tar.zst 等格式的更新包时按附件的格式下载
+var NativePackageExt = ".zip"
+
+// MaxExtractSize 解压后的最大总字节数
+var MaxExtractSize int64 = 4 << 30
+
+// MaxArchiveEntries 更新包的最大条目数
+var MaxArchiveEntries = 100000
+
+// MaxCompressionRatio 更新包的最大压缩比
+var MaxCompressionRatio int64 = 200
+
+// MaxArchivePathDepth 更新包条目路径的最大层级
+var MaxArchivePathDepth = 32
+
+// PackageManifestName 更新包中的文件清单
+const PackageManifestName = "update-manifest.json"
+
+// InstallManifestPath 安装目录中记录已安装文件校验值的清单
+const InstallManifestPath = "data/install-manifest.json"
+
+// JVMEntryPoints JVM版必须存在的入口文件（支持通配符）
+var JVMEntryPoints = []string{"hs-script.exe", "lib/*.jar"}
+
+// NativeEntryPoints Native版必须存在的入口文件（支持通配符）
+var NativeEntryPoints = []string{"hs-script.exe"}
+
+// HealthCheckArg 主程序健康检查参数
+const HealthCheckArg = "--health-check"
+
+// HealthCheckReadyLine 主程序健康检查就绪时输出的标记
+const HealthCheckReadyLine = "READY"
+
+// MigrationsName 更新包中的配置迁移文件
+const MigrationsName = "update-migrations.json"
+
+// ConfigBackupDir 配置迁移前备份原配置的目录
+const ConfigBackupDir = "data/backup"
+
+// ConfigDir 配置目录，迁移前整体备份
+const ConfigDir = "config"
+
+// PluginDir 插件目录
+const PluginDir = "plugin"
+
+// PluginDisabledDir 不兼容插件的隔离目录
+const PluginDisabledDir = "plugin-disabled"
+
+// PluginDescriptorName 插件目录中的描述文件
+const PluginDescriptorName = "plugin.json"
+
+// PluginDisabledNoteName 隔离插件时写入的说明文件
+const PluginDisabledNoteName = "DISABLED.txt"
+
+// HooksName 更新包中的钩子声明文件
+const HooksName = "update-hooks.json"
+
+// UpdateHistoryPath 安装目录中的更新历史（每行一条 JSON 记录）
+const UpdateHistoryPath = "data/update-history.jsonl"
+
+// LogDir 日志目录（相对于安装目录）
+const LogDir = "log"
+
+// LogFileName 日志文件名
+const LogFileName = "updater.log"
+
+// LogMaxSize 单个日志文件的最大字节数
+var LogMaxSize int64 = 5 << 20
+
+// LogMaxBackups 保留的历史日志文件数量
+var LogMaxBackups = 3
+
+// VersionStampPath 更新完成时写入的版本记录
+const VersionStampPath = "data/version.json"
+
+// JVMVariantFiles JVM版特有的文件（支持通配符），切换到 Native 版时移除
+var JVMVariantFiles = []string{"lib/*.jar", "jre"}
+
+// NativeVariantFiles Native版特有的文件（支持通配符），切换到 JVM 版时移除
+var NativeVariantFiles = []string{}
+
+// RuntimeDir JVM版内置的 Java 运行时目录
+const RuntimeDir = "jre"
+
+// ComponentStatePath 安装目录中记录各组件版本和校验值的状态文件
+const ComponentStatePath = "data/components.json"
+
+// ShutdownRequestPath 更新器请求主程序退出时写入的文件，主程序检测到后应保存数据并退出
+const ShutdownRequestPath = "data/shutdown.request"
+
+// ShutdownAckPath 主程序收到退出请求后写入的确认文件
+const ShutdownAckPath = "data/shutdown.ack"
+
+// ShutdownTimeout 等待主程序退出的默认超时时间（秒）
+var ShutdownTimeout = 30
+
+// PauseArg 主程序以暂停状态启动的参数
+const PauseArg = "--pause"
+
+// UpdateLockPath 安装目录中的更新锁文件，位于安装目录中，不同用户和同一目录的不同写法使用同一个锁
+const UpdateLockPath = "data/update.lock"
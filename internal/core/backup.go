@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// backup 更新前对安装目录中部分文件的备份
+type backup struct {
+	targetDir string
+	dir       string
+	seen      map[string]bool
+	// copied 已复制到备份目录的相对路径
+	copied []string
+	// moved 已移动到备份目录的相对路径
+	moved []string
+	// missing 备份时不存在的相对路径，恢复时删除
+	missing []string
+}
+
+// newBackup 在 data/backup 下创建以时间命名的备份，label 不为空时追加到目录名
+func (u *Updater) newBackup(label string) *backup {
+	name := time.Now().Format("20060102-150405")
+	if label != "" {
+		name += "-" + label
+	}
+	return &backup{
+		targetDir: u.targetDir,
+		dir:       filepath.Join(u.targetDir, filepath.FromSlash(config.ConfigBackupDir), name),
+		seen:      make(map[string]bool),
+	}
+}
+
+// copy 复制文件或目录到备份中
+func (b *backup) copy(relPath string) error {
+	if b.seen[relPath] {
+		return nil
+	}
+	b.seen[relPath] = true
+
+	src := filepath.Join(b.targetDir, filepath.FromSlash(relPath))
+	dst := filepath.Join(b.dir, filepath.FromSlash(relPath))
+	var err error
+	switch {
+	case !utils.Exists(src):
+		b.missing = append(b.missing, relPath)
+		return nil
+	case utils.IsDirectory(src):
+		err = utils.CopyDirectory(context.Background(), src, dst, nil, nil)
+	default:
+		err = utils.CopyFile(src, dst)
+	}
+	if err != nil {
+		return fmt.Errorf("备份 %s 失败: %w", relPath, err)
+	}
+	b.copied = append(b.copied, relPath)
+	return nil
+}
+
+// move 将文件或目录移动到备份中，即从安装目录中删除
+func (b *backup) move(relPath string) error {
+	if b.seen[relPath] {
+		return nil
+	}
+	b.seen[relPath] = true
+
+	src := filepath.Join(b.targetDir, filepath.FromSlash(relPath))
+	dst := filepath.Join(b.dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("移除 %s 失败: %w", relPath, err)
+	}
+	b.moved = append(b.moved, relPath)
+	return nil
+}
+
+// save 将即将被覆盖的文件移动到备份中，备份目录与安装目录位于同一个卷，只需重命名，不再复制一份
+// 无法移动时（如文件被占用或位于其他卷）改为复制，被占用的文件在写入时按文件占用策略处理
+func (b *backup) save(relPath string) error {
+	if b.seen[relPath] {
+		return nil
+	}
+	if !utils.Exists(filepath.Join(b.targetDir, filepath.FromSlash(relPath))) {
+		return b.copy(relPath)
+	}
+	if err := b.move(relPath); err == nil {
+		return nil
+	}
+	delete(b.seen, relPath)
+	return b.copy(relPath)
+}
+
+// restore 用备份恢复安装目录中的文件
+// 单个文件恢复失败时继续恢复其余文件，返回所有失败的原因；正在运行的更新器不会被覆盖，跳过
+func (b *backup) restore() error {
+	var errs []error
+	for _, relPath := range b.copied {
+		src := filepath.Join(b.dir, filepath.FromSlash(relPath))
+		dst := filepath.Join(b.targetDir, filepath.FromSlash(relPath))
+		if utils.IsCurrentProcess(dst) {
+			continue
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", relPath, err))
+			continue
+		}
+		var err error
+		if utils.IsDirectory(src) {
+			err = utils.CopyDirectory(context.Background(), src, dst, nil, nil)
+		} else {
+			err = utils.CopyFile(src, dst)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", relPath, err))
+		}
+	}
+	for _, relPath := range b.moved {
+		dst := filepath.Join(b.targetDir, filepath.FromSlash(relPath))
+		if utils.IsCurrentProcess(dst) {
+			continue
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", relPath, err))
+			continue
+		}
+		if err := os.Rename(filepath.Join(b.dir, filepath.FromSlash(relPath)), dst); err != nil {
+			errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", relPath, err))
+		}
+	}
+	for _, relPath := range b.missing {
+		dst := filepath.Join(b.targetDir, filepath.FromSlash(relPath))
+		if utils.IsCurrentProcess(dst) {
+			continue
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			errs = append(errs, fmt.Errorf("删除 %s 失败: %w", relPath, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// discard 删除备份目录
+func (b *backup) discard() error {
+	return os.RemoveAll(b.dir)
+}
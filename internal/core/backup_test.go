@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/install"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// newTestBackup 在临时安装目录中创建备份
+func newTestBackup(t *testing.T) *backup {
+	t.Helper()
+	u := &Updater{targetDir: t.TempDir()}
+	return u.newBackup("test")
+}
+
+func TestBackupRestore(t *testing.T) {
+	b := newTestBackup(t)
+	writeTestFile(t, b.targetDir, "lib/app.jar", "old app")
+	writeTestFile(t, b.targetDir, "config/app.yml", "old config")
+	writeTestFile(t, b.targetDir, "plugin/a/a.jar", "old plugin")
+
+	for _, relPath := range []string{"lib/app.jar", "config/app.yml", "lib/new.jar"} {
+		if err := b.copy(relPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.move("plugin/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟更新写入的文件
+	writeTestFile(t, b.targetDir, "lib/app.jar", "new app")
+	writeTestFile(t, b.targetDir, "config/app.yml", "new config")
+	writeTestFile(t, b.targetDir, "lib/new.jar", "new")
+	writeTestFile(t, b.targetDir, "plugin/a/a.jar", "new plugin")
+
+	if err := b.restore(); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"lib/app.jar":    "old app",
+		"config/app.yml": "old config",
+		"plugin/a/a.jar": "old plugin",
+	}
+	for relPath, want := range cases {
+		if got := readTestFile(t, b.targetDir, relPath); got != want {
+			t.Errorf("%s 的内容 = %q，期望 %q", relPath, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(b.targetDir, "lib", "new.jar")); !os.IsNotExist(err) {
+		t.Errorf("新建的文件应被删除: %v", err)
+	}
+}
+
+func TestBackupRestoreContinuesAfterFailure(t *testing.T) {
+	b := newTestBackup(t)
+	writeTestFile(t, b.targetDir, "a/b.txt", "old b")
+	writeTestFile(t, b.targetDir, "c.txt", "old c")
+	writeTestFile(t, b.targetDir, "d/e.txt", "old e")
+	for _, relPath := range []string{"a/b.txt", "c.txt", "new.txt"} {
+		if err := b.copy(relPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.move("d"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a 被替换为文件后，a/b.txt 无法删除
+	if err := os.RemoveAll(filepath.Join(b.targetDir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, b.targetDir, "a", "file")
+	writeTestFile(t, b.targetDir, "c.txt", "new c")
+	writeTestFile(t, b.targetDir, "new.txt", "new")
+
+	if err := b.restore(); err == nil {
+		t.Error("无法恢复的文件应返回错误")
+	}
+	if got := readTestFile(t, b.targetDir, "c.txt"); got != "old c" {
+		t.Errorf("失败之后的文件未恢复: c.txt = %q", got)
+	}
+	if got := readTestFile(t, b.targetDir, "d/e.txt"); got != "old e" {
+		t.Errorf("失败之后的目录未恢复: d/e.txt = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(b.targetDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("失败之后新建的文件未删除: %v", err)
+	}
+}
+
+func TestBackupRestoreSkipsCurrentProcess(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("无法获取可执行文件路径: %v", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		t.Skipf("无法解析可执行文件路径: %v", err)
+	}
+
+	b := &backup{targetDir: filepath.Dir(exe), dir: t.TempDir(), copied: []string{filepath.Base(exe)}}
+	if err := b.restore(); err != nil {
+		t.Fatalf("正在运行的程序应被跳过: %v", err)
+	}
+	if _, err := os.Stat(exe); err != nil {
+		t.Errorf("正在运行的程序不应被删除: %v", err)
+	}
+}
+
+func TestBackupFiles(t *testing.T) {
+	u := NewUpdater("", t.TempDir(), false, 0, "")
+	u.installed = &install.Info{Variant: install.VariantJVM}
+	writeTestFile(t, u.targetDir, "lib/app.jar", "old app")
+	writeTestFile(t, u.targetDir, "config/app.properties", "a=1")
+	writeTestFile(t, u.targetDir, "config/user.yml", "user")
+
+	plan := &updatePlan{entries: []planEntry{
+		{relPath: "lib/", isDir: true, action: rules.ActionInclude},
+		{relPath: "lib/app.jar", action: rules.ActionInclude},
+		{relPath: "lib/new.jar", action: rules.ActionInclude},
+		{relPath: "plugin/", isDir: true, action: rules.ActionInclude},
+		{relPath: "config/app.properties", action: rules.ActionMerge},
+		{relPath: "config/user.yml", action: rules.ActionPreserve},
+	}}
+	b, err := u.backupFiles(context.Background(), plan, install.VariantJVM, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 将被覆盖的文件移入备份，合并的配置需要保留原文件，只能复制
+	if _, err := os.Stat(filepath.Join(u.targetDir, "lib", "app.jar")); !os.IsNotExist(err) {
+		t.Errorf("将被覆盖的文件应移入备份: %v", err)
+	}
+	if got := readTestFile(t, b.dir, "lib/app.jar"); got != "old app" {
+		t.Errorf("备份中的 lib/app.jar = %q", got)
+	}
+	if got := readTestFile(t, u.targetDir, "config/app.properties"); got != "a=1" {
+		t.Errorf("合并的配置应保留在原位置: %q", got)
+	}
+	if got := readTestFile(t, b.dir, "config/app.properties"); got != "a=1" {
+		t.Errorf("备份中的 config/app.properties = %q", got)
+	}
+	if utils.Exists(filepath.Join(b.dir, "config", "user.yml")) {
+		t.Error("保留的文件不需要备份")
+	}
+
+	// 模拟写入后恢复
+	writeTestFile(t, u.targetDir, "lib/app.jar", "new app")
+	writeTestFile(t, u.targetDir, "lib/new.jar", "new")
+	writeTestFile(t, u.targetDir, "plugin/a.jar", "new")
+	writeTestFile(t, u.targetDir, "config/app.properties", "a=1\nb=2")
+	if err := b.restore(); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"lib/app.jar":           "old app",
+		"config/app.properties": "a=1",
+		"config/user.yml":       "user",
+	}
+	for relPath, want := range cases {
+		if got := readTestFile(t, u.targetDir, relPath); got != want {
+			t.Errorf("%s 的内容 = %q，期望 %q", relPath, got, want)
+		}
+	}
+	for _, relPath := range []string{"lib/new.jar", "plugin"} {
+		if utils.Exists(filepath.Join(u.targetDir, filepath.FromSlash(relPath))) {
+			t.Errorf("新建的 %s 应被删除", relPath)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/component"
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/progress"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// runtimeTempDir 下载 Java 运行时使用的临时目录
+const runtimeTempDir = "_temp_runtime"
+
+// runtimeUpdate Java 运行时的更新方式
+type runtimeUpdate struct {
+	ref *component.Ref
+	// skip 版本未变化，不从更新包复制
+	skip bool
+	// download 更新包中不包含运行时，需要单独下载
+	download bool
+	// sourceDir 下载并解压后的运行时目录
+	sourceDir string
+	// files 运行时中各文件的校验值
+	files map[string]string
+}
+
+// planRuntime 根据发布清单中引用的运行时版本决定如何更新 Java 运行时
+// 版本未变化时从更新计划中排除运行时目录，更新包中不包含运行时时改为单独下载
+func (u *Updater) planRuntime(plan *updatePlan, isJvmVersion bool) (*updatePlan, error) {
+	if !isJvmVersion || u.packageManifest == nil {
+		return plan, nil
+	}
+	ref, ok := u.packageManifest.Components[component.Runtime]
+	if !ok || ref.Version == "" {
+		return plan, nil
+	}
+
+	runtime := &runtimeUpdate{ref: &ref}
+	u.runtime = runtime
+	installed := u.components.Get(component.Runtime)
+	if installed != nil && installed.Version == ref.Version &&
+		utils.IsDirectory(filepath.Join(u.targetDir, config.RuntimeDir)) {
+		u.logDetail(fmt.Sprintf("Java 运行时 %s 未变化，跳过更新", ref.Version))
+		runtime.skip = true
+		// 沿用上次记录的运行时文件校验值
+		if u.previous != nil {
+			runtime.files = component.Split(u.previous.Files)[component.Runtime]
+		}
+		u.ruleSet = rules.NewRuleSet([]rules.Rule{{Pattern: config.RuntimeDir, Action: rules.ActionExclude}}, u.ruleSet.Rules())
+		return u.buildPlan()
+	}
+
+	if planWrites(plan, config.RuntimeDir) {
+		u.logDetail(fmt.Sprintf("Java 运行时: %s -> %s（从更新包复制）", installedVersion(installed), ref.Version))
+		return plan, nil
+	}
+	if ref.URL == "" {
+		return nil, fmt.Errorf("更新包中不包含 Java 运行时 %s，且文件清单中没有下载地址", ref.Version)
+	}
+	u.logDetail(fmt.Sprintf("Java 运行时: %s -> %s（单独下载）", installedVersion(installed), ref.Version))
+	runtime.download = true
+	return plan, nil
+}
+
+// installedVersion 返回已安装组件的版本
+func installedVersion(c *component.Component) string {
+	if c == nil {
+		return displayVersion("")
+	}
+	return displayVersion(c.Version)
+}
+
+// downloadRuntime 下载并解压 Java 运行时到临时目录，不修改安装目录
+func (u *Updater) downloadRuntime(ctx context.Context) error {
+	ref := u.runtime.ref
+	u.logStatus(fmt.Sprintf("更新组件 %s: 下载 Java 运行时 %s...", component.Runtime, ref.Version))
+
+	tempDir := filepath.Join(u.targetDir, runtimeTempDir)
+	if err := os.RemoveAll(tempDir); err != nil {
+		return err
+	}
+	if err := utils.CreateDirectory(tempDir); err != nil {
+		return err
+	}
+
+	name := path.Base(strings.SplitN(ref.URL, "?", 2)[0])
+	archivePath := filepath.Join(tempDir, name)
+	u.tracker.Start(progress.PhaseDownload, 0)
+	err := utils.Download(ctx, ref.URL, archivePath, func(done, total int64) {
+		u.tracker.Set(name, done, total)
+	})
+	if err != nil {
+		return fmt.Errorf("下载 Java 运行时失败: %w", err)
+	}
+	if ref.SHA256 != "" {
+		hash, err := utils.HashFile(archivePath)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hash, ref.SHA256) {
+			return fmt.Errorf("Java 运行时校验值不一致: 期望 %s，实际 %s", ref.SHA256, hash)
+		}
+	}
+
+	extractDir := filepath.Join(tempDir, "extract")
+	if err := utils.ExtractArchive(ctx, archivePath, extractDir, nil); err != nil {
+		return fmt.Errorf("解压 Java 运行时失败: %w", err)
+	}
+	u.runtime.sourceDir = utils.FindExtractedDirectory(extractDir)
+	u.logDetail(fmt.Sprintf("Java 运行时已下载: %s", name))
+	return nil
+}
+
+// replaceRuntime 用下载的 Java 运行时替换安装目录中的运行时
+// 原运行时移入更新备份，直到所有步骤完成后才随备份删除，之前的步骤失败时随备份恢复
+func (u *Updater) replaceRuntime(b *backup) error {
+	u.logStatus(fmt.Sprintf("更新组件 %s: 替换 Java 运行时...", component.Runtime))
+	runtimeDir := filepath.Join(u.targetDir, config.RuntimeDir)
+	if utils.Exists(runtimeDir) {
+		if err := b.move(config.RuntimeDir); err != nil {
+			return fmt.Errorf("替换 Java 运行时失败: %w", err)
+		}
+	} else if err := b.copy(config.RuntimeDir); err != nil {
+		// 原来没有运行时，只记录路径，恢复时删除
+		return err
+	}
+	if err := os.Rename(u.runtime.sourceDir, runtimeDir); err != nil {
+		return fmt.Errorf("替换 Java 运行时失败: %w", err)
+	}
+
+	files, err := component.HashDir(u.targetDir, config.RuntimeDir)
+	if err != nil {
+		return fmt.Errorf("计算 Java 运行时校验值失败: %w", err)
+	}
+	u.runtime.files = files
+	u.logDetail(fmt.Sprintf("Java 运行时已更新到 %s（%d 个文件）", u.runtime.ref.Version, len(files)))
+	return nil
+}
+
+// cleanupRuntime 删除下载 Java 运行时使用的临时目录
+func (u *Updater) cleanupRuntime() {
+	if err := os.RemoveAll(filepath.Join(u.targetDir, runtimeTempDir)); err != nil {
+		u.logWarn(fmt.Sprintf("删除临时目录失败: %v", err))
+	}
+}
+
+// saveComponents 按本次写入的文件更新各组件的版本和校验值
+func (u *Updater) saveComponents(files map[string]string, isJvmVersion bool) {
+	split := component.Split(files)
+	for _, name := range component.Names {
+		componentFiles := split[name]
+		if len(componentFiles) == 0 {
+			if !isJvmVersion && name != component.App {
+				u.components.Remove(name)
+			}
+			continue
+		}
+
+		version := u.toVersion
+		if name == component.Runtime {
+			version = ""
+			if u.runtime != nil {
+				version = u.runtime.ref.Version
+			}
+		}
+		u.components.Set(component.Component{
+			Name:      name,
+			Version:   version,
+			Hash:      component.Hash(componentFiles),
+			Files:     len(componentFiles),
+			UpdatedAt: time.Now(),
+		})
+	}
+	if err := component.SaveState(u.targetDir, u.components); err != nil {
+		u.logWarn(fmt.Sprintf("写入组件状态失败: %v", err))
+	}
+}
+
+// componentProgress 按组件显示文件写入进度
+type componentProgress struct {
+	u       *Updater
+	totals  map[string]int
+	bytes   int64
+	current string
+}
+
+// newComponentProgress 根据更新计划统计各组件的文件数量和需要写入的字节数
+func (u *Updater) newComponentProgress(plan *updatePlan) *componentProgress {
+	p := &componentProgress{u: u, totals: make(map[string]int)}
+	for _, entry := range plan.entries {
+		if !entry.isDir {
+			p.totals[component.Of(entry.relPath)]++
+			p.bytes += entry.size
+		}
+	}
+	return p
+}
+
+// start 输出各组件需要处理的文件数量，并开始写入文件阶段
+func (p *componentProgress) start() {
+	for _, name := range component.Names {
+		if p.totals[name] > 0 {
+			p.u.logDetail(fmt.Sprintf("组件 %s: %d 个文件", name, p.totals[name]))
+		}
+	}
+	p.u.tracker.Start(progress.PhaseCopy, p.bytes)
+}
+
+// update 写入文件的过程中更新状态和进度
+func (p *componentProgress) update(relPath string, n int64) {
+	name := component.Of(relPath)
+	if name != p.current {
+		p.current = name
+		p.u.logStatus(fmt.Sprintf("更新组件 %s（%d 个文件）...", name, p.totals[name]))
+	}
+	p.u.tracker.Add(relPath, n)
+}
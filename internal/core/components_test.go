@@ -0,0 +1,111 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/component"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+)
+
+func TestPlanRuntime(t *testing.T) {
+	withRuntime := map[string]string{
+		"hs-script/hs-script.exe": "exe",
+		"hs-script/lib/app.jar":   "app",
+		"hs-script/jre/bin/java":  "java 21",
+	}
+	withoutRuntime := map[string]string{
+		"hs-script/hs-script.exe": "exe",
+		"hs-script/lib/app.jar":   "app",
+	}
+
+	cases := []struct {
+		name         string
+		files        map[string]string
+		isJvm        bool
+		ref          *component.Ref
+		installed    string // 已安装的运行时版本
+		wantRuntime  bool
+		wantSkip     bool
+		wantDownload bool
+		wantErr      bool
+		wantJre      bool // 更新计划中是否包含运行时目录
+	}{
+		{"Native 版不处理运行时", withoutRuntime, false, &component.Ref{Version: "21"}, "", false, false, false, false, false},
+		{"清单中没有引用运行时", withRuntime, true, nil, "21", false, false, false, false, true},
+		{"版本未变化时跳过", withRuntime, true, &component.Ref{Version: "21"}, "21", true, true, false, false, false},
+		{"版本变化时从更新包复制", withRuntime, true, &component.Ref{Version: "21"}, "17", true, false, false, false, true},
+		{"更新包中没有运行时时单独下载", withoutRuntime, true, &component.Ref{Version: "21", URL: "https://example.com/jre.zip"}, "17", true, false, true, false, false},
+		{"没有下载地址", withoutRuntime, true, &component.Ref{Version: "21"}, "17", false, false, false, true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "jre/bin/java", "java")
+			packagePath := filepath.Join(t.TempDir(), "update.zip")
+			writeTestZip(t, packagePath, c.files)
+
+			u := NewUpdater(packagePath, dir, false, 0, "")
+			u.ruleSet = rules.NewRuleSet(rules.Default(c.isJvm))
+			u.packageManifest = &Manifest{}
+			if c.ref != nil {
+				u.packageManifest.Components = map[string]component.Ref{component.Runtime: *c.ref}
+			}
+			u.components = &component.State{}
+			if c.installed != "" {
+				u.components.Set(component.Component{Name: component.Runtime, Version: c.installed})
+			}
+			u.previous = &Manifest{Files: map[string]string{"jre/bin/java": "hash", "lib/app.jar": "app"}}
+
+			plan, err := u.buildPlan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan, err = u.planRuntime(plan, c.isJvm)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("planRuntime 错误 = %v，期望出错: %v", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if (u.runtime != nil) != c.wantRuntime {
+				t.Fatalf("runtime = %+v，期望存在: %v", u.runtime, c.wantRuntime)
+			}
+			if u.runtime != nil && (u.runtime.skip != c.wantSkip || u.runtime.download != c.wantDownload) {
+				t.Errorf("skip = %v，download = %v，期望 %v、%v", u.runtime.skip, u.runtime.download, c.wantSkip, c.wantDownload)
+			}
+			if c.wantSkip && u.runtime.files["jre/bin/java"] != "hash" {
+				t.Errorf("跳过时应沿用上次记录的校验值: %v", u.runtime.files)
+			}
+			if got := planWrites(plan, "jre"); got != c.wantJre {
+				t.Errorf("更新计划包含运行时 = %v，期望 %v", got, c.wantJre)
+			}
+		})
+	}
+}
+
+func TestSaveComponents(t *testing.T) {
+	dir := t.TempDir()
+	u := NewUpdater("", dir, false, 0, "")
+	u.SetVersions("v4.1.0", "v4.2.0")
+	u.components = &component.State{}
+	u.components.Set(component.Component{Name: component.Runtime, Version: "17"})
+	u.components.Set(component.Component{Name: component.BasePlugins, Version: "v4.1.0"})
+
+	// 切换到 Native 版后不再有运行时和基础插件
+	u.saveComponents(map[string]string{"hs-script.exe": "a"}, false)
+
+	state, err := component.LoadState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app := state.Get(component.App); app == nil || app.Version != "v4.2.0" || app.Files != 1 {
+		t.Errorf("主程序组件 = %+v", app)
+	}
+	for _, name := range []string{component.Runtime, component.BasePlugins} {
+		if c := state.Get(name); c != nil {
+			t.Errorf("Native 版不应保留组件 %s: %+v", name, c)
+		}
+	}
+}
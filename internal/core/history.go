@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+// HistoryEntry 更新历史记录
+type HistoryEntry struct {
+	Time         time.Time `json:"time"`
+	FromVersion  string    `json:"fromVersion,omitempty"`
+	ToVersion    string    `json:"toVersion,omitempty"`
+	Variant      string    `json:"variant,omitempty"`
+	Source       string    `json:"source,omitempty"`
+	Package      string    `json:"package"`
+	PackageHash  string    `json:"packageHash,omitempty"`
+	Duration     int64     `json:"durationMs"`
+	FilesChanged int       `json:"filesChanged"`
+	Result       string    `json:"result"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// NewHistoryEntry 根据更新结果摘要生成历史记录
+func NewHistoryEntry(summary *UpdateSummary) *HistoryEntry {
+	result := "success"
+	if !summary.Success {
+		result = "failed"
+	}
+	return &HistoryEntry{
+		Time:         summary.StartTime,
+		FromVersion:  summary.FromVersion,
+		ToVersion:    summary.ToVersion,
+		Variant:      summary.Variant,
+		Source:       summary.Source,
+		Package:      summary.Package,
+		PackageHash:  summary.PackageHash,
+		Duration:     summary.Duration,
+		FilesChanged: summary.FilesChanged,
+		Result:       result,
+		Error:        summary.Error,
+	}
+}
+
+// AppendHistory 在安装目录的更新历史末尾追加一条记录
+func AppendHistory(targetDir string, entry *HistoryEntry) error {
+	historyPath := filepath.Join(targetDir, filepath.FromSlash(config.UpdateHistoryPath))
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// LoadHistory 读取安装目录的更新历史，按时间顺序返回，文件不存在时返回空列表
+// 无法解析的行会被跳过
+func LoadHistory(targetDir string) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	file, err := os.Open(filepath.Join(targetDir, filepath.FromSlash(config.UpdateHistoryPath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn(fmt.Sprintf("跳过无法解析的更新历史（第 %d 行）: %v", lineNo, err))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+func TestNewHistoryEntry(t *testing.T) {
+	cases := []struct {
+		name    string
+		summary UpdateSummary
+		want    string
+	}{
+		{"成功", UpdateSummary{Success: true}, "success"},
+		{"失败", UpdateSummary{Error: "更新失败"}, "failed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.summary.Package = "update.zip"
+			entry := NewHistoryEntry(&c.summary)
+			if entry.Result != c.want || entry.Package != "update.zip" || entry.Error != c.summary.Error {
+				t.Errorf("NewHistoryEntry = %+v，期望结果 %s", entry, c.want)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	entries, err := LoadHistory(dir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("没有更新历史时 LoadHistory = %v, %v，期望空列表", entries, err)
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	want := []HistoryEntry{
+		{Time: start, FromVersion: "v4.1.0", ToVersion: "v4.2.0", Package: "a.zip", FilesChanged: 3, Result: "success"},
+		{Time: start.Add(time.Hour), ToVersion: "v4.3.0", Package: "b.zip", Result: "failed", Error: "校验失败"},
+	}
+	for i := range want {
+		if err := AppendHistory(dir, &want[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 无法解析的行和空行被跳过
+	historyPath := filepath.Join(dir, filepath.FromSlash(config.UpdateHistoryPath))
+	file, err := os.OpenFile(historyPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{broken\n\n")
+	file.Close()
+
+	got, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("读取到 %d 条记录，期望 %d 条", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Package != want[i].Package ||
+			got[i].Result != want[i].Result || got[i].Error != want[i].Error {
+			t.Errorf("第 %d 条记录 = %+v，期望 %+v", i+1, got[i], want[i])
+		}
+	}
+}
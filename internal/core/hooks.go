@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/hooks"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// loadHooks 读取更新包中声明的钩子
+func (u *Updater) loadHooks() error {
+	data, err := utils.ReadArchiveFile(u.packagePath, config.HooksName)
+	if err != nil {
+		return fmt.Errorf("读取更新包中的钩子失败: %w", err)
+	}
+
+	var declared []hooks.Hook
+	if data != nil {
+		if declared, err = hooks.Parse(data); err != nil {
+			return fmt.Errorf("更新包中的钩子无效: %w", err)
+		}
+		u.logDetail(fmt.Sprintf("更新包声明了 %d 个钩子", len(declared)))
+	}
+
+	u.hooks = &hooks.Runner{
+		TargetDir: u.targetDir,
+		Hooks:     declared,
+		Env:       u.updateEnv(),
+		Log:       u.logDetail,
+	}
+	return nil
+}
+
+// runHooks 执行指定阶段的钩子，ctx 取消时停止正在执行的命令
+func (u *Updater) runHooks(ctx context.Context, event hooks.Event) error {
+	if u.hooks == nil {
+		return nil
+	}
+	return u.hooks.Run(ctx, event)
+}
+
+// updateEnv 返回传递给钩子和迁移脚本的环境变量
+func (u *Updater) updateEnv() []string {
+	return []string{
+		"HS_UPDATE_FROM_VERSION=" + u.fromVersion,
+		"HS_UPDATE_TO_VERSION=" + u.toVersion,
+		"HS_UPDATE_TARGET_DIR=" + u.targetDir,
+		"HS_UPDATE_PACKAGE=" + u.packagePath,
+	}
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// lockPath 返回目标目录的更新锁文件路径
+func lockPath(targetDir string) string {
+	return filepath.Join(targetDir, filepath.FromSlash(config.UpdateLockPath))
+}
+
+// acquireLock 获取目标目录的更新锁，防止多个更新器同时更新同一目录
+func (u *Updater) acquireLock(ctx context.Context) (*utils.InstanceLock, error) {
+	lock, err := utils.AcquireInstanceLock(ctx, lockPath(u.targetDir), u.targetDir, u.wait, func(owner utils.LockOwner) {
+		u.logStatus(fmt.Sprintf("已有更新正在进行 (PID: %d)，等待其完成...", owner.PID))
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrInstanceLocked) {
+			return nil, fmt.Errorf("%w，可使用 --wait 等待其完成", err)
+		}
+		return nil, fmt.Errorf("获取更新锁失败: %w", err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// captureMainCommand 在主程序退出前读取其启动参数和环境变量，更新后以相同方式重新启动
+func (u *Updater) captureMainCommand() {
+	if u.mainProgram == "" || u.mainPid <= 0 {
+		return
+	}
+
+	info, err := utils.Processes.Command(u.mainPid)
+	if err != nil {
+		u.logWarn(fmt.Sprintf("读取主程序命令行失败: %v", err))
+		return
+	}
+	if !sameProgram(info.Args[0], u.mainProgram) {
+		u.logDetail(fmt.Sprintf("主程序进程 (%s) 与 --main-program 不一致，不沿用其启动参数", info.Args[0]))
+		return
+	}
+
+	if !u.mainArgsSet {
+		u.mainArgs = info.Args[1:]
+		u.logDetail(fmt.Sprintf("沿用主程序的启动参数: %s", strings.Join(u.mainArgs, " ")))
+	}
+	u.mainEnv = info.Env
+}
+
+// sameProgram 按文件名判断两个路径是否为同一程序（不区分大小写，忽略 .exe）
+func sameProgram(a, b string) bool {
+	name := func(p string) string {
+		return strings.TrimSuffix(strings.ToLower(filepath.Base(p)), ".exe")
+	}
+	return name(a) == name(b)
+}
+
+// launchOptions 生成重新启动主程序的参数
+// 主程序更新前处于暂停状态时以暂停状态启动，指定 --resume-paused 时恢复运行
+func (u *Updater) launchOptions() utils.LaunchOptions {
+	args := make([]string, 0, len(u.mainArgs)+1)
+	for _, arg := range u.mainArgs {
+		if arg != config.PauseArg {
+			args = append(args, arg)
+		}
+	}
+	if u.isPause {
+		if u.resumePaused {
+			u.logDetail("主程序更新前处于暂停状态，将恢复运行")
+		} else {
+			u.logDetail("主程序更新前处于暂停状态，将以暂停状态启动")
+			args = append(args, config.PauseArg)
+		}
+	}
+	return utils.LaunchOptions{Args: args, Dir: u.targetDir, Env: u.mainEnv}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/migrate"
+	"club.xiaojiawei/hs-script-update/internal/model"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// resolveVersions 确定迁移使用的旧版本和新版本
+// 旧版本默认取探测到的安装版本，新版本默认从更新包文件名中解析
+func (u *Updater) resolveVersions() {
+	if u.fromVersion == "" && u.installed != nil {
+		u.fromVersion = u.installed.Version
+	}
+	if u.fromVersion == "" && u.previous != nil {
+		u.fromVersion = u.previous.Version
+	}
+	if u.toVersion == "" {
+		u.toVersion = model.VersionFromFileName(filepath.Base(u.packagePath))
+	}
+}
+
+// loadMigrations 读取更新包中的配置迁移，并选出需要执行的部分
+func (u *Updater) loadMigrations() ([]migrate.Migration, error) {
+	u.resolveVersions()
+	data, err := utils.ReadArchiveFile(u.packagePath, config.MigrationsName)
+	if err != nil {
+		return nil, fmt.Errorf("读取更新包中的配置迁移失败: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	migrations, err := migrate.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("更新包中的配置迁移无效: %w", err)
+	}
+
+	if u.fromVersion == "" {
+		u.logWarn("无法确定当前版本，只执行配置合并步骤")
+	}
+	selected := migrate.Select(migrations, u.fromVersion, u.toVersion)
+	u.logDetail(fmt.Sprintf("配置迁移: %s -> %s，共 %d 个，需要执行 %d 个",
+		displayVersion(u.fromVersion), displayVersion(u.toVersion), len(migrations), len(selected)))
+	return selected, nil
+}
+
+// backupConfig 备份配置目录及迁移涉及的文件
+func (u *Updater) backupConfig(migrations []migrate.Migration) (*backup, error) {
+	b := u.newBackup("")
+	for _, relPath := range append([]string{config.ConfigDir}, migrate.Files(migrations)...) {
+		if err := b.copy(relPath); err != nil {
+			return nil, err
+		}
+	}
+
+	u.logDetail(fmt.Sprintf("原配置已备份到: %s", b.dir))
+	return b, nil
+}
+
+// runMigrations 执行配置迁移，失败时恢复备份
+func (u *Updater) runMigrations(migrations []migrate.Migration, backup *backup) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	runner := &migrate.Runner{
+		TargetDir: u.targetDir,
+		PackageFile: func(relPath string) ([]byte, error) {
+			return utils.ReadArchiveFile(u.packagePath, relPath)
+		},
+		Env: u.updateEnv(),
+		Log: u.logDetail,
+	}
+	if err := runner.Run(migrations); err != nil {
+		u.logDetail(fmt.Sprintf("配置迁移失败，恢复原配置: %v", err))
+		if restoreErr := backup.restore(); restoreErr != nil {
+			return fmt.Errorf("配置迁移失败: %w（恢复备份失败: %v，备份位于 %s）", err, restoreErr, backup.dir)
+		}
+		return fmt.Errorf("配置迁移失败，已恢复原配置: %w", err)
+	}
+	return nil
+}
+
+// displayVersion 版本为空时显示为未知
+func displayVersion(version string) string {
+	if version == "" {
+		return "未知"
+	}
+	return version
+}
@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/model"
+	"club.xiaojiawei/hs-script-update/internal/plugin"
+	"club.xiaojiawei/hs-script-update/internal/repository"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// maxPluginCandidates 查找兼容版本时最多检查的新版本数量
+const maxPluginCandidates = 10
+
+// PluginInfo 已安装插件信息
+type PluginInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Dir       string `json:"dir"`
+	Requires  string `json:"requires,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Updatable bool   `json:"updatable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PluginUpdate 插件更新检查结果
+type PluginUpdate struct {
+	ID             string `json:"id"`
+	CurrentVersion string `json:"currentVersion"`
+	LatestVersion  string `json:"latestVersion,omitempty"`
+	HasUpdate      bool   `json:"hasUpdate"`
+	DownloadURL    string `json:"downloadUrl,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+
+	plugin *plugin.Plugin
+}
+
+// PluginManager 插件管理器，独立于主程序检查和更新第三方插件
+type PluginManager struct {
+	targetDir   string
+	coreVersion string
+	preview     bool
+}
+
+// NewPluginManager 创建插件管理器
+// coreVersion 为空时读取安装目录文件清单中记录的版本
+func NewPluginManager(targetDir, coreVersion string, preview bool) *PluginManager {
+	if coreVersion == "" {
+		if manifest, err := LoadInstallManifest(targetDir); err == nil {
+			coreVersion = manifest.Version
+		}
+	}
+	return &PluginManager{targetDir: targetDir, coreVersion: coreVersion, preview: preview}
+}
+
+// CoreVersion 返回用于判断兼容性的主程序版本
+func (pm *PluginManager) CoreVersion() string {
+	return pm.coreVersion
+}
+
+// List 列出已安装的插件
+func (pm *PluginManager) List() ([]PluginInfo, error) {
+	plugins, err := plugin.Scan(pm.pluginDir())
+	if err != nil {
+		return nil, fmt.Errorf("扫描插件目录失败: %w", err)
+	}
+
+	infos := make([]PluginInfo, 0, len(plugins))
+	for _, p := range plugins {
+		info := PluginInfo{ID: p.ID(), Dir: p.DirName}
+		if p.Err != nil {
+			info.Error = p.Err.Error()
+		}
+		if d := p.Descriptor; d != nil {
+			info.Name = d.Name
+			info.Version = d.Version
+			info.Requires = d.Requires
+			if d.Repository != nil {
+				info.Source = fmt.Sprintf("%s:%s/%s", d.Repository.Type, d.Repository.User, d.Repository.Project)
+				info.Updatable = true
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Check 检查插件是否有兼容的新版本，id 为空时检查所有可更新的插件
+func (pm *PluginManager) Check(ctx context.Context, id string) ([]PluginUpdate, error) {
+	plugins, err := plugin.Scan(pm.pluginDir())
+	if err != nil {
+		return nil, fmt.Errorf("扫描插件目录失败: %w", err)
+	}
+
+	updates := []PluginUpdate{}
+	for _, p := range plugins {
+		if id != "" && p.ID() != id {
+			continue
+		}
+		if p.Descriptor == nil || p.Descriptor.Repository == nil {
+			if id != "" {
+				return nil, fmt.Errorf("插件 %s 未声明发布仓库，无法独立更新", id)
+			}
+			continue
+		}
+		updates = append(updates, pm.checkPlugin(ctx, p))
+	}
+
+	if id != "" && len(updates) == 0 {
+		return nil, fmt.Errorf("未找到插件: %s", id)
+	}
+	return updates, nil
+}
+
+// checkPlugin 从插件的发布仓库中查找最新的兼容版本
+func (pm *PluginManager) checkPlugin(ctx context.Context, p *plugin.Plugin) PluginUpdate {
+	descriptor := p.Descriptor
+	update := PluginUpdate{ID: descriptor.ID, CurrentVersion: descriptor.Version, plugin: p}
+
+	source := descriptor.Repository
+	repo, err := repository.NewProjectRepository(source.Type, source.User, source.Project)
+	if err != nil {
+		update.Reason = err.Error()
+		return update
+	}
+
+	releases, err := repo.GetReleases(ctx)
+	if err != nil {
+		update.Reason = err.Error()
+		return update
+	}
+
+	// 按版本从新到旧排列
+	var candidates []model.Release
+	for _, release := range releases {
+		if release.IsPreRelease && !pm.preview {
+			continue
+		}
+		if model.CompareVersion(release.TagName, descriptor.Version) > 0 {
+			candidates = append(candidates, release)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CompareTo(&candidates[j]) > 0
+	})
+	if len(candidates) == 0 {
+		update.Reason = "已是最新版本"
+		return update
+	}
+	update.LatestVersion = candidates[0].TagName
+
+	for i := range candidates {
+		if i >= maxPluginCandidates {
+			break
+		}
+		release := &candidates[i]
+		compatible, err := pm.isCompatible(ctx, repo, source, release)
+		if err != nil {
+			update.Reason = fmt.Sprintf("读取 %s 的插件描述失败: %v", release.TagName, err)
+			continue
+		}
+		if !compatible {
+			continue
+		}
+
+		update.LatestVersion = release.TagName
+		update.HasUpdate = true
+		update.Reason = ""
+		update.DownloadURL = repository.GetAssetDownloadURL(repo, release, source.AssetName(descriptor.ID, release.TagName))
+		return update
+	}
+
+	if update.Reason == "" {
+		update.Reason = fmt.Sprintf("新版本均不兼容当前主程序版本 %s", pm.coreVersion)
+	}
+	return update
+}
+
+// isCompatible 读取指定版本的插件描述，判断是否兼容当前主程序版本
+func (pm *PluginManager) isCompatible(ctx context.Context, repo repository.Repository, source *plugin.Source, release *model.Release) (bool, error) {
+	if pm.coreVersion == "" {
+		// 无法确定主程序版本时不做限制
+		return true, nil
+	}
+
+	data, err := utils.Get(ctx, repo.GetRawFileURL(release.TagName, source.DescriptorPath()))
+	if err != nil {
+		return false, err
+	}
+	descriptor, err := plugin.ParseDescriptor([]byte(data))
+	if err != nil {
+		return false, err
+	}
+
+	versionRange, _ := plugin.ParseRange(descriptor.Requires)
+	return versionRange.Contains(pm.coreVersion), nil
+}
+
+// Update 下载并安装插件的最新兼容版本，返回检查结果
+// 新版本先解压到插件目录中的临时目录，校验通过后再替换旧版本
+func (pm *PluginManager) Update(ctx context.Context, id string) (*PluginUpdate, error) {
+	updates, err := pm.Check(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update := &updates[0]
+	if !update.HasUpdate {
+		return update, nil
+	}
+
+	pluginDir := pm.pluginDir()
+	packagePath := filepath.Join(pluginDir, "."+id+".download")
+	stagingDir := filepath.Join(pluginDir, "."+id+".installing")
+	defer os.Remove(packagePath)
+	defer os.RemoveAll(stagingDir)
+
+	slog.Info(fmt.Sprintf("下载插件 %s %s: %s", id, update.LatestVersion, update.DownloadURL))
+	if err := utils.Download(ctx, update.DownloadURL, packagePath, nil); err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, err
+	}
+	if err := utils.ExtractArchive(ctx, packagePath, stagingDir, nil); err != nil {
+		return nil, fmt.Errorf("解压插件失败: %w", err)
+	}
+
+	// 插件包可能带有一层根目录
+	extractedDir := stagingDir
+	if _, err := os.Stat(filepath.Join(extractedDir, config.PluginDescriptorName)); os.IsNotExist(err) {
+		extractedDir = utils.FindExtractedDirectory(stagingDir)
+	}
+	descriptor, err := plugin.LoadDescriptor(extractedDir)
+	if err != nil {
+		return nil, fmt.Errorf("新版本插件描述无效: %w", err)
+	}
+	if descriptor == nil || descriptor.ID != id {
+		return nil, fmt.Errorf("新版本插件包中缺少 %s 或 id 不是 %s", config.PluginDescriptorName, id)
+	}
+
+	if err := pm.replace(update.plugin, extractedDir, filepath.Join(pluginDir, id)); err != nil {
+		return nil, err
+	}
+	slog.Info(fmt.Sprintf("插件 %s 已更新: %s -> %s", id, update.CurrentVersion, descriptor.Version))
+	return update, nil
+}
+
+// replace 用新版本替换旧插件目录，失败时恢复旧版本
+func (pm *PluginManager) replace(old *plugin.Plugin, newDir, dst string) error {
+	backupDir := filepath.Join(filepath.Dir(old.Path), "."+old.DirName+".old")
+	if err := os.RemoveAll(backupDir); err != nil {
+		return err
+	}
+	if err := os.Rename(old.Path, backupDir); err != nil {
+		return fmt.Errorf("移动旧版本插件失败（插件可能正在使用）: %w", err)
+	}
+
+	if err := os.Rename(newDir, dst); err != nil {
+		if restoreErr := os.Rename(backupDir, old.Path); restoreErr != nil {
+			return fmt.Errorf("安装新版本插件失败: %w（恢复旧版本失败: %v，旧版本位于 %s）", err, restoreErr, backupDir)
+		}
+		return fmt.Errorf("安装新版本插件失败，已恢复旧版本: %w", err)
+	}
+
+	if err := os.RemoveAll(backupDir); err != nil {
+		slog.Warn(fmt.Sprintf("删除旧版本插件失败: %v", err))
+	}
+	return nil
+}
+
+// pluginDir 返回插件目录
+func (pm *PluginManager) pluginDir() string {
+	return filepath.Join(pm.targetDir, config.PluginDir)
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/plugin"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// writePlugins 在安装目录中创建插件，descriptor 为空时不写入描述文件
+func writePlugins(t *testing.T, dir string, plugins map[string]string) {
+	t.Helper()
+	for name, descriptor := range plugins {
+		writeTestFile(t, dir, "plugin/"+name+"/"+name+".jar", "jar")
+		if descriptor != "" {
+			writeTestFile(t, dir, "plugin/"+name+"/"+config.PluginDescriptorName, descriptor)
+		}
+	}
+}
+
+func TestNewPluginManager(t *testing.T) {
+	dir := t.TempDir()
+	if err := SaveInstallManifest(dir, &Manifest{Version: "v4.2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name        string
+		coreVersion string
+		want        string
+	}{
+		{"读取文件清单中的版本", "", "v4.2.0"},
+		{"指定的版本优先", "v5.0.0", "v5.0.0"},
+	}
+	for _, c := range cases {
+		if got := NewPluginManager(dir, c.coreVersion, false).CoreVersion(); got != c.want {
+			t.Errorf("%s: CoreVersion = %s，期望 %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPluginManagerList(t *testing.T) {
+	dir := t.TempDir()
+	writePlugins(t, dir, map[string]string{
+		"local":   `{"id":"local","name":"本地插件","version":"1.0.0","requires":">=4.0.0"}`,
+		"remote":  `{"id":"remote","version":"2.0.0","repository":{"type":"github","user":"u","project":"p"}}`,
+		"broken":  `{`,
+		".remote": `{"id":"remote","version":"3.0.0"}`,
+	})
+
+	infos, err := NewPluginManager(dir, "v4.2.0", false).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("应列出 3 个插件（跳过临时目录），实际: %+v", infos)
+	}
+	if infos[0].Dir != "broken" || infos[0].Error == "" {
+		t.Errorf("描述无效的插件应带有错误: %+v", infos[0])
+	}
+	want := PluginInfo{ID: "local", Name: "本地插件", Version: "1.0.0", Dir: "local", Requires: ">=4.0.0"}
+	if !reflect.DeepEqual(infos[1], want) {
+		t.Errorf("List()[1] = %+v，期望 %+v", infos[1], want)
+	}
+	if infos[2].Source != "github:u/p" || !infos[2].Updatable {
+		t.Errorf("声明了发布仓库的插件应可更新: %+v", infos[2])
+	}
+}
+
+func TestPluginManagerCheckErrors(t *testing.T) {
+	dir := t.TempDir()
+	writePlugins(t, dir, map[string]string{"local": `{"id":"local","version":"1.0.0"}`})
+	pm := NewPluginManager(dir, "v4.2.0", false)
+
+	cases := []struct {
+		id      string
+		wantErr string
+	}{
+		{"local", "未声明发布仓库"},
+		{"missing", "未找到插件"},
+	}
+	for _, c := range cases {
+		if _, err := pm.Check(context.Background(), c.id); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("Check(%s) 错误 = %v，期望包含 %q", c.id, err, c.wantErr)
+		}
+	}
+
+	// 不指定插件时跳过不能独立更新的插件
+	updates, err := pm.Check(context.Background(), "")
+	if err != nil || len(updates) != 0 {
+		t.Errorf("Check() = %+v, %v，期望空列表", updates, err)
+	}
+}
+
+func TestPluginManagerReplace(t *testing.T) {
+	dir := t.TempDir()
+	writePlugins(t, dir, map[string]string{"a": `{"id":"a","version":"1.0.0"}`})
+	pluginDir := filepath.Join(dir, config.PluginDir)
+	old := &plugin.Plugin{DirName: "a", Path: filepath.Join(pluginDir, "a")}
+	pm := NewPluginManager(dir, "v4.2.0", false)
+
+	// 新版本目录不存在时恢复旧版本
+	if err := pm.replace(old, filepath.Join(pluginDir, ".a.installing"), old.Path); err == nil {
+		t.Fatal("安装新版本失败时应返回错误")
+	}
+	if got := readTestFile(t, dir, "plugin/a/"+config.PluginDescriptorName); !strings.Contains(got, "1.0.0") {
+		t.Errorf("应恢复旧版本插件: %s", got)
+	}
+
+	writeTestFile(t, dir, "plugin/.a.installing/"+config.PluginDescriptorName, `{"id":"a","version":"2.0.0"}`)
+	if err := pm.replace(old, filepath.Join(pluginDir, ".a.installing"), old.Path); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, dir, "plugin/a/"+config.PluginDescriptorName); !strings.Contains(got, "2.0.0") {
+		t.Errorf("应替换为新版本插件: %s", got)
+	}
+	for _, name := range []string{".a.old", ".a.installing"} {
+		if utils.Exists(filepath.Join(pluginDir, name)) {
+			t.Errorf("替换后不应残留 %s", name)
+		}
+	}
+}
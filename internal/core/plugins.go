@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/plugin"
+)
+
+// checkPlugins 检查第三方插件与新版本的兼容性，不兼容的插件移动到禁用目录
+func (u *Updater) checkPlugins() {
+	pluginDir := filepath.Join(u.targetDir, config.PluginDir)
+	plugins, err := plugin.Scan(pluginDir)
+	if err != nil {
+		u.logWarn(fmt.Sprintf("扫描插件目录失败: %v", err))
+		return
+	}
+	if len(plugins) == 0 {
+		return
+	}
+
+	u.logStatus("检查插件兼容性...")
+	if u.toVersion == "" {
+		u.logWarn("无法确定新版本，跳过插件兼容性检查")
+	}
+
+	basePlugins := make(map[string]bool)
+	for _, name := range config.JVMUpdatePluginDirs {
+		basePlugins[name] = true
+	}
+
+	disabledDir := filepath.Join(u.targetDir, config.PluginDisabledDir)
+	for _, p := range plugins {
+		report := PluginReport{ID: p.ID(), Dir: p.DirName}
+		if p.Descriptor != nil {
+			report.Name = p.Descriptor.Name
+			report.Version = p.Descriptor.Version
+			report.Requires = p.Descriptor.Requires
+		}
+
+		switch {
+		case basePlugins[p.DirName]:
+			report.Status = PluginUpdated
+		case p.Err != nil:
+			report.Status = PluginUnknown
+			report.Reason = fmt.Sprintf("读取 %s 失败: %v", config.PluginDescriptorName, p.Err)
+		case p.Descriptor == nil:
+			report.Status = PluginUnknown
+			report.Reason = fmt.Sprintf("缺少 %s", config.PluginDescriptorName)
+		case p.Descriptor.Requires == "" || u.toVersion == "":
+			report.Status = PluginCompatible
+		default:
+			versionRange, _ := plugin.ParseRange(p.Descriptor.Requires)
+			if versionRange.Contains(u.toVersion) {
+				report.Status = PluginCompatible
+				break
+			}
+
+			report.Reason = fmt.Sprintf("需要主程序版本 %s，当前版本 %s", p.Descriptor.Requires, u.toVersion)
+			disabledPath, err := plugin.Quarantine(p, disabledDir, report.Reason)
+			if disabledPath == "" {
+				// 移动失败时保留原位置，交由用户处理
+				report.Status = PluginUnknown
+				report.Reason = fmt.Sprintf("%s，禁用失败: %v", report.Reason, err)
+				break
+			}
+			if err != nil {
+				u.logWarn(err.Error())
+			}
+			report.Status = PluginDisabled
+			report.DisabledPath = disabledPath
+		}
+
+		u.logDetail(fmt.Sprintf("  插件 %-32s %-10s %s", report.ID, report.Status, report.Reason))
+		u.summary.Plugins = append(u.summary.Plugins, report)
+	}
+}
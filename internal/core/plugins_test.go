@@ -0,0 +1,88 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+func TestCheckPlugins(t *testing.T) {
+	basePlugin := config.JVMUpdatePluginDirs[0]
+	plugins := map[string]string{
+		basePlugin:     `{"id":"base","version":"1.0.0","requires":">=9.0.0"}`,
+		"no-desc":      "",
+		"broken":       `{`,
+		"any":          `{"id":"any","version":"1.0.0"}`,
+		"compatible":   `{"id":"compatible","version":"1.0.0","requires":">=4.0.0 <5.0.0"}`,
+		"incompatible": `{"id":"incompatible","version":"1.0.0","requires":">=5.0.0"}`,
+	}
+
+	cases := []struct {
+		name      string
+		toVersion string
+		want      map[string]PluginStatus
+	}{
+		{"检查兼容性", "v4.2.0", map[string]PluginStatus{
+			basePlugin:     PluginUpdated,
+			"no-desc":      PluginUnknown,
+			"broken":       PluginUnknown,
+			"any":          PluginCompatible,
+			"compatible":   PluginCompatible,
+			"incompatible": PluginDisabled,
+		}},
+		{"无法确定新版本时不禁用", "", map[string]PluginStatus{
+			basePlugin:     PluginUpdated,
+			"no-desc":      PluginUnknown,
+			"broken":       PluginUnknown,
+			"any":          PluginCompatible,
+			"compatible":   PluginCompatible,
+			"incompatible": PluginCompatible,
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, descriptor := range plugins {
+				writeTestFile(t, dir, "plugin/"+name+"/"+name+".jar", "jar")
+				if descriptor != "" {
+					writeTestFile(t, dir, "plugin/"+name+"/"+config.PluginDescriptorName, descriptor)
+				}
+			}
+
+			u := NewUpdater("", dir, false, 0, "")
+			u.SetVersions("", c.toVersion)
+			u.checkPlugins()
+
+			got := make(map[string]PluginStatus)
+			for _, report := range u.summary.Plugins {
+				got[report.Dir] = report.Status
+			}
+			for name, want := range c.want {
+				if got[name] != want {
+					t.Errorf("插件 %s 的状态 = %s，期望 %s", name, got[name], want)
+				}
+			}
+
+			disabled := c.want["incompatible"] == PluginDisabled
+			if utils.Exists(filepath.Join(dir, "plugin", "incompatible")) == disabled {
+				t.Errorf("不兼容的插件应移出插件目录: %v", disabled)
+			}
+			disabledDir := filepath.Join(dir, config.PluginDisabledDir, "incompatible")
+			if utils.Exists(filepath.Join(disabledDir, config.PluginDisabledNoteName)) != disabled ||
+				utils.Exists(filepath.Join(disabledDir, "incompatible.jar")) != disabled {
+				t.Errorf("禁用目录中应包含插件和禁用说明: %v", disabled)
+			}
+		})
+	}
+}
+
+func TestCheckPluginsEmpty(t *testing.T) {
+	u := NewUpdater("", t.TempDir(), false, 0, "")
+	u.SetVersions("", "v4.2.0")
+	u.checkPlugins()
+	if len(u.summary.Plugins) != 0 {
+		t.Errorf("没有插件目录时不应有检查结果: %+v", u.summary.Plugins)
+	}
+}
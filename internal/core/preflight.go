@@ -0,0 +1,206 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// maxReportedProblems 错误信息中最多列出的问题数量
+const maxReportedProblems = 10
+
+// planEntry 更新计划中的单个条目
+type planEntry struct {
+	relPath string
+	size    int64
+	isDir   bool
+	action  rules.Action
+}
+
+// updatePlan 根据更新包和保留规则生成的更新计划
+type updatePlan struct {
+	entries   []planEntry
+	totalSize int64 // 更新包解压后的总大小
+	largest   int64 // 最大的单个文件
+}
+
+// PreflightError 更新前检查未通过
+type PreflightError struct {
+	Problems        []string
+	spaceProblem    bool
+	writableProblem bool
+}
+
+// Error 返回错误描述及处理建议
+func (e *PreflightError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("更新前检查未通过:\n")
+	for i, problem := range e.Problems {
+		if i >= maxReportedProblems {
+			sb.WriteString(fmt.Sprintf("  ... 等共 %d 项问题\n", len(e.Problems)))
+			break
+		}
+		sb.WriteString("  - " + problem + "\n")
+	}
+	if e.spaceProblem {
+		sb.WriteString("\n请清理磁盘空间后重试。")
+	}
+	if e.writableProblem {
+		sb.WriteString("\n请以管理员身份运行更新器，或将程序安装到当前用户有写入权限的目录（如不要放在 Program Files 下）。")
+	}
+	return sb.String()
+}
+
+// buildPlan 读取更新包条目，按更新规则生成更新计划（不写入任何文件）
+func (u *Updater) buildPlan() (*updatePlan, error) {
+	a, err := archive.Open(u.packagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	root := archive.FindRoot(entries)
+	plan := &updatePlan{}
+	for _, entry := range entries {
+		plan.totalSize += entry.Size
+
+		relPath := strings.TrimPrefix(entry.Name, root)
+		if relPath == "" || relPath == strings.TrimSuffix(root, "/") {
+			continue
+		}
+		action := u.ruleSet.Match(relPath)
+		if action == rules.ActionExclude {
+			continue
+		}
+
+		plan.entries = append(plan.entries, planEntry{relPath: relPath, size: entry.Size, isDir: entry.IsDir, action: action})
+		if !entry.IsDir && entry.Size > plan.largest {
+			plan.largest = entry.Size
+		}
+	}
+	return plan, nil
+}
+
+// preflight 检查磁盘空间和写入权限，任何一项不满足都在修改安装目录之前中止
+func (u *Updater) preflight(plan *updatePlan) error {
+	result := &PreflightError{}
+
+	// 1. 计算每个卷需要的空间
+	required := make(map[string]uint64)
+	queryDirs := make(map[string]string)
+	addRequired := func(dir string, bytes int64) {
+		if bytes <= 0 {
+			return
+		}
+		volume := utils.VolumeOf(dir)
+		required[volume] += uint64(bytes)
+		if _, ok := queryDirs[volume]; !ok {
+			queryDirs[volume] = utils.NearestExistingDir(dir)
+		}
+	}
+
+	// 新文件覆盖旧文件后，只有增长的部分需要额外空间，但被覆盖的文件移入备份后，直到更新完成才会删除
+	var growth, backupSize int64
+	for _, entry := range plan.entries {
+		if entry.isDir || preserved(u.targetDir, entry) {
+			continue
+		}
+		size := existingSize(filepath.Join(u.targetDir, filepath.FromSlash(entry.relPath)))
+		growth += entry.size - size
+		backupSize += size
+	}
+	addRequired(u.targetDir, growth)
+	addRequired(u.targetDir, backupSize)
+	// 流式更新时每个文件先写入临时文件再替换
+	addRequired(u.targetDir, plan.largest)
+	if !u.streaming {
+		// 完整解压需要一份临时副本
+		addRequired(u.tempExtractDir, plan.totalSize)
+	}
+
+	volumes := make([]string, 0, len(required))
+	for volume := range required {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	for _, volume := range volumes {
+		free, err := utils.GetFreeSpace(queryDirs[volume])
+		if err != nil {
+			u.logWarn(err.Error())
+			continue
+		}
+		u.logDetail(fmt.Sprintf("磁盘 %s: 需要 %s，可用 %s", volume,
+			utils.FormatBytes(required[volume]), utils.FormatBytes(free)))
+		if free < required[volume] {
+			result.spaceProblem = true
+			result.Problems = append(result.Problems, fmt.Sprintf("磁盘 %s 空间不足: 需要 %s，可用 %s",
+				volume, utils.FormatBytes(required[volume]), utils.FormatBytes(free)))
+		}
+	}
+
+	// 2. 检查所有要写入的目录和要覆盖的文件
+	dirSet := map[string]bool{utils.NearestExistingDir(u.targetDir): true}
+	var files []string
+	for _, entry := range plan.entries {
+		if preserved(u.targetDir, entry) {
+			continue
+		}
+		dst := filepath.Join(u.targetDir, filepath.FromSlash(entry.relPath))
+		if entry.isDir {
+			dirSet[utils.NearestExistingDir(dst)] = true
+			continue
+		}
+		dirSet[utils.NearestExistingDir(filepath.Join(u.targetDir, filepath.FromSlash(path.Dir(entry.relPath))))] = true
+		files = append(files, dst)
+	}
+
+	dirs := make([]string, 0, len(dirSet))
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if err := utils.CheckWritable(dir); err != nil {
+			result.writableProblem = true
+			result.Problems = append(result.Problems, fmt.Sprintf("目录不可写: %s (%v)", dir, err))
+		}
+	}
+	for _, file := range files {
+		if err := utils.CheckFileWritable(file); err != nil {
+			result.writableProblem = true
+			result.Problems = append(result.Problems, err.Error())
+		}
+	}
+
+	if len(result.Problems) > 0 {
+		return result
+	}
+	u.logDetail(fmt.Sprintf("更新前检查通过: %d 个条目，%d 个目录可写", len(plan.entries), len(dirs)))
+	return nil
+}
+
+// preserved 条目按规则保留且安装目录中已存在，更新时不会写入
+func preserved(targetDir string, entry planEntry) bool {
+	return entry.action == rules.ActionPreserve && utils.Exists(filepath.Join(targetDir, filepath.FromSlash(entry.relPath)))
+}
+
+// existingSize 返回已存在文件的大小，不存在时返回 0
+func existingSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+)
+
+func TestBuildPlan(t *testing.T) {
+	packagePath := filepath.Join(t.TempDir(), "update.zip")
+	writeTestZip(t, packagePath, map[string]string{
+		"hs-script/hs-script.exe":                 "exe",
+		"hs-script/lib/app.jar":                   "app jar",
+		"hs-script/config/app.yml":                "a: 1",
+		"hs-script/" + config.PackageManifestName: "{}",
+	})
+
+	u := NewUpdater(packagePath, t.TempDir(), false, 0, "")
+	u.ruleSet = rules.NewRuleSet(rules.Default(true))
+	plan, err := u.buildPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]rules.Action)
+	for _, entry := range plan.entries {
+		got[entry.relPath] = entry.action
+	}
+	want := map[string]rules.Action{
+		"hs-script.exe":  rules.ActionInclude,
+		"lib/app.jar":    rules.ActionInclude,
+		"config/app.yml": rules.ActionPreserve,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("更新计划 = %v，期望 %v", got, want)
+	}
+	if plan.totalSize != 3+7+4+2 || plan.largest != 7 {
+		t.Errorf("totalSize = %d，largest = %d", plan.totalSize, plan.largest)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	cases := []struct {
+		name         string
+		setup        func(t *testing.T, dir string)
+		entries      []planEntry
+		wantSpace    bool
+		wantWritable bool
+	}{
+		{
+			"检查通过",
+			func(t *testing.T, dir string) { writeTestFile(t, dir, "lib/app.jar", "old") },
+			[]planEntry{
+				{relPath: "lib/", isDir: true, action: rules.ActionInclude},
+				{relPath: "lib/app.jar", size: 10, action: rules.ActionInclude},
+				{relPath: "new/a.txt", size: 10, action: rules.ActionInclude},
+			},
+			false, false,
+		},
+		{
+			"空间不足",
+			func(t *testing.T, dir string) {},
+			[]planEntry{{relPath: "huge.bin", size: 1 << 60, action: rules.ActionInclude}},
+			true, false,
+		},
+		{
+			"覆盖只读文件",
+			func(t *testing.T, dir string) {
+				writeTestFile(t, dir, "hs-script.exe", "old")
+				if err := os.Chmod(filepath.Join(dir, "hs-script.exe"), 0444); err != nil {
+					t.Fatal(err)
+				}
+			},
+			[]planEntry{{relPath: "hs-script.exe", size: 3, action: rules.ActionInclude}},
+			false, true,
+		},
+		{
+			"保留的只读文件不会被覆盖",
+			func(t *testing.T, dir string) {
+				writeTestFile(t, dir, "config/app.yml", "old")
+				if err := os.Chmod(filepath.Join(dir, "config", "app.yml"), 0444); err != nil {
+					t.Fatal(err)
+				}
+			},
+			[]planEntry{{relPath: "config/app.yml", size: 3, action: rules.ActionPreserve}},
+			false, false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c.setup(t, dir)
+			u := NewUpdater("", dir, false, 0, "")
+			plan := &updatePlan{entries: c.entries}
+			for _, entry := range c.entries {
+				plan.totalSize += entry.size
+				plan.largest = max(plan.largest, entry.size)
+			}
+
+			err := u.preflight(plan)
+			var preflightErr *PreflightError
+			if !c.wantSpace && !c.wantWritable {
+				if err != nil {
+					t.Fatalf("检查应通过: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &preflightErr) {
+				t.Fatalf("应返回 PreflightError，实际: %v", err)
+			}
+			if preflightErr.spaceProblem != c.wantSpace || preflightErr.writableProblem != c.wantWritable {
+				t.Errorf("空间问题 = %v，写入问题 = %v: %v", preflightErr.spaceProblem, preflightErr.writableProblem, err)
+			}
+		})
+	}
+}
+
+func TestPreflightErrorMessage(t *testing.T) {
+	err := &PreflightError{spaceProblem: true, writableProblem: true}
+	for i := 0; i < maxReportedProblems+2; i++ {
+		err.Problems = append(err.Problems, "问题")
+	}
+	message := err.Error()
+	if n := strings.Count(message, "  - 问题"); n != maxReportedProblems {
+		t.Errorf("应最多列出 %d 项问题，实际 %d 项", maxReportedProblems, n)
+	}
+	for _, want := range []string{"等共 12 项问题", "清理磁盘空间", "管理员身份"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("错误信息中缺少 %q:\n%s", want, message)
+		}
+	}
+}
+
+// writeTestZip 创建包含指定文件的 zip 更新包
+func writeTestZip(t *testing.T, zipPath string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestFile 在目录中创建文件及其上级目录
+func writeTestFile(t *testing.T, dir, relPath, content string) {
+	t.Helper()
+	filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile 读取目录中的文件
+func readTestFile(t *testing.T, dir, relPath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
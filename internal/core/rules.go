@@ -0,0 +1,42 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// loadRuleSet 加载更新规则
+// 匹配顺序：安装目录中的规则 > 更新包中的规则 > 内置规则，第一条匹配的规则生效
+func (u *Updater) loadRuleSet(isJvmVersion bool) (*rules.RuleSet, error) {
+	installRules, err := rules.Load(filepath.Join(u.targetDir, config.UpdateRulesName))
+	if err != nil {
+		return nil, fmt.Errorf("读取安装目录中的更新规则失败: %w", err)
+	}
+
+	var packageRules []rules.Rule
+	data, err := utils.ReadArchiveFile(u.packagePath, config.UpdateRulesName)
+	if err != nil {
+		return nil, fmt.Errorf("读取更新包中的更新规则失败: %w", err)
+	}
+	if data != nil {
+		if packageRules, err = rules.Parse(data); err != nil {
+			return nil, fmt.Errorf("更新包中的更新规则无效: %w", err)
+		}
+	}
+
+	defaultRules := rules.Default(isJvmVersion)
+	u.logDetail(fmt.Sprintf("更新规则: 安装目录 %d 条，更新包 %d 条，内置 %d 条",
+		len(installRules), len(packageRules), len(defaultRules)))
+
+	ruleSet := rules.NewRuleSet(installRules, packageRules, defaultRules)
+	for _, rule := range ruleSet.Rules() {
+		if rule.Action != rules.ActionInclude {
+			u.logDetail(fmt.Sprintf("  %-8s %s", rule.Action, rule.Pattern))
+		}
+	}
+	return ruleSet, nil
+}
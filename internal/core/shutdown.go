@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// shutdownPollInterval 等待主程序确认和退出时的检查间隔
+const shutdownPollInterval = 200 * time.Millisecond
+
+// ShutdownRequest 更新器写入的退出请求
+type ShutdownRequest struct {
+	PID         int       `json:"pid"`
+	UpdaterPID  int       `json:"updaterPid"`
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// ShutdownAck 主程序写入的退出确认
+type ShutdownAck struct {
+	PID int `json:"pid"`
+}
+
+// shutdownMainProgram 请求主程序退出并等待确认和退出，超时后才强制结束（--no-kill 时中止更新）
+func (u *Updater) shutdownMainProgram(ctx context.Context) error {
+	if !utils.IsProcessRunning(u.mainPid) {
+		u.logDetail("主程序未在运行")
+		return nil
+	}
+
+	requestPath := filepath.Join(u.targetDir, filepath.FromSlash(config.ShutdownRequestPath))
+	ackPath := filepath.Join(u.targetDir, filepath.FromSlash(config.ShutdownAckPath))
+	os.Remove(ackPath)
+	defer os.Remove(requestPath)
+	defer os.Remove(ackPath)
+
+	request := &ShutdownRequest{PID: u.mainPid, UpdaterPID: os.Getpid(), Reason: "update", RequestedAt: time.Now()}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if !utils.IsDirectory(u.targetDir) {
+		u.logWarn(fmt.Sprintf("目标目录不存在，无法写入退出请求: %s", u.targetDir))
+	} else if err := utils.WriteFileAtomic(requestPath, strings.NewReader(string(data)), 0644); err != nil {
+		u.logWarn(fmt.Sprintf("写入退出请求失败: %v", err))
+	} else {
+		u.logDetail(fmt.Sprintf("已请求主程序退出: %s", requestPath))
+	}
+
+	acked := false
+	deadline := time.Now().Add(u.shutdownTimeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !acked && readShutdownAck(ackPath, u.mainPid) {
+			acked = true
+			u.logDetail("主程序已确认退出请求，等待其保存数据并退出...")
+		}
+
+		err := utils.Processes.WaitForExit(u.mainPid, min(shutdownPollInterval, max(time.Until(deadline), 0)))
+		if err == nil {
+			u.logDetail("主程序已退出")
+			return nil
+		}
+		if !errors.Is(err, utils.ErrWaitTimeout) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+
+	if acked {
+		u.logWarn(fmt.Sprintf("主程序已确认退出请求，但在 %v 内未退出", u.shutdownTimeout))
+	} else {
+		u.logWarn(fmt.Sprintf("主程序在 %v 内未响应退出请求", u.shutdownTimeout))
+	}
+	if u.noKill {
+		return fmt.Errorf("主程序 (PID: %d) 未退出，已按 --no-kill 中止更新", u.mainPid)
+	}
+
+	u.logWarn(fmt.Sprintf("强制结束主程序 (PID: %d)，未保存的数据可能丢失", u.mainPid))
+	if err := utils.KillProcess(u.mainPid); err != nil {
+		return fmt.Errorf("杀死主程序失败: %w", err)
+	}
+	if err := utils.Processes.WaitForExit(u.mainPid, 5*time.Second); err != nil {
+		return fmt.Errorf("杀死主程序后等待退出失败: %w", err)
+	}
+	return nil
+}
+
+// readShutdownAck 读取确认文件，PID 与主程序一致（或未填写）时视为已确认
+func readShutdownAck(ackPath string, pid int) bool {
+	data, err := os.ReadFile(ackPath)
+	if err != nil {
+		return false
+	}
+	var ack ShutdownAck
+	if err := json.Unmarshal(data, &ack); err != nil {
+		// 主程序也可以只创建空文件作为确认
+		return len(strings.TrimSpace(string(data))) == 0
+	}
+	return ack.PID == 0 || ack.PID == pid
+}
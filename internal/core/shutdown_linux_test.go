@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// startMainProgram 启动模拟主程序的子进程，respond 为 true 时收到退出请求后写入确认并退出
+func startMainProgram(t *testing.T, dir string, respond bool) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动 sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		<-exited
+	})
+	if !respond {
+		return cmd
+	}
+
+	requestPath := filepath.Join(dir, filepath.FromSlash(config.ShutdownRequestPath))
+	ackPath := filepath.Join(dir, filepath.FromSlash(config.ShutdownAckPath))
+	go func() {
+		for i := 0; i < 100; i++ {
+			var request ShutdownRequest
+			if data, err := os.ReadFile(requestPath); err == nil && json.Unmarshal(data, &request) == nil {
+				os.WriteFile(ackPath, []byte(fmt.Sprintf(`{"pid":%d}`, request.PID)), 0644)
+				time.Sleep(100 * time.Millisecond)
+				cmd.Process.Kill()
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+	return cmd
+}
+
+func TestShutdownMainProgram(t *testing.T) {
+	cases := []struct {
+		name        string
+		respond     bool
+		noKill      bool
+		wantErr     bool
+		wantRunning bool
+	}{
+		{"确认后退出", true, true, false, false},
+		{"未响应时强制结束", false, false, false, false},
+		{"未响应且禁止强制结束", false, true, true, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cmd := startMainProgram(t, dir, c.respond)
+
+			u := NewUpdater("", dir, false, cmd.Process.Pid, "")
+			u.SetShutdown(time.Second, c.noKill)
+			err := u.shutdownMainProgram(context.Background())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("shutdownMainProgram 错误 = %v，期望出错: %v", err, c.wantErr)
+			}
+			if running := utils.IsProcessRunning(cmd.Process.Pid); running != c.wantRunning {
+				t.Errorf("主程序运行中 = %v，期望 %v", running, c.wantRunning)
+			}
+			for _, relPath := range []string{config.ShutdownRequestPath, config.ShutdownAckPath} {
+				if utils.Exists(filepath.Join(dir, filepath.FromSlash(relPath))) {
+					t.Errorf("结束后应删除 %s", relPath)
+				}
+			}
+		})
+	}
+}
+
+func TestShutdownMainProgramNotRunning(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("无法运行 true: %v", err)
+	}
+	u := NewUpdater("", t.TempDir(), false, cmd.Process.Pid, "")
+	if err := u.shutdownMainProgram(context.Background()); err != nil {
+		t.Errorf("主程序未运行时不应返回错误: %v", err)
+	}
+}
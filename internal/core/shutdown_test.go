@@ -0,0 +1,40 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadShutdownAck(t *testing.T) {
+	cases := []struct {
+		name    string
+		content *string
+		want    bool
+	}{
+		{"没有确认文件", nil, false},
+		{"空文件", ptr(""), true},
+		{"PID 一致", ptr(`{"pid":100}`), true},
+		{"未填写 PID", ptr(`{}`), true},
+		{"PID 不一致", ptr(`{"pid":200}`), false},
+		{"内容无效", ptr("ok"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ackPath := filepath.Join(t.TempDir(), "shutdown.ack")
+			if c.content != nil {
+				if err := os.WriteFile(ackPath, []byte(*c.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := readShutdownAck(ackPath, 100); got != c.want {
+				t.Errorf("readShutdownAck = %v，期望 %v", got, c.want)
+			}
+		})
+	}
+}
+
+// ptr 返回字符串的指针
+func ptr(s string) *string {
+	return &s
+}
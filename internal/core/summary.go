@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// PluginStatus 插件检查结果
+type PluginStatus string
+
+const (
+	// PluginUpdated 随更新包更新的基础插件
+	PluginUpdated PluginStatus = "updated"
+	// PluginCompatible 与新版本兼容
+	PluginCompatible PluginStatus = "compatible"
+	// PluginUnknown 缺少描述或版本范围，无法判断兼容性
+	PluginUnknown PluginStatus = "unknown"
+	// PluginDisabled 不兼容，已移动到禁用目录
+	PluginDisabled PluginStatus = "disabled"
+)
+
+// PluginReport 单个插件的检查结果
+type PluginReport struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name,omitempty"`
+	Version      string       `json:"version,omitempty"`
+	Dir          string       `json:"dir"`
+	Requires     string       `json:"requires,omitempty"`
+	Status       PluginStatus `json:"status"`
+	Reason       string       `json:"reason,omitempty"`
+	DisabledPath string       `json:"disabledPath,omitempty"`
+}
+
+// UpdateSummary 更新结果摘要
+type UpdateSummary struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// ErrorCode 失败时的错误码，见 ErrorCode
+	ErrorCode string `json:"errorCode,omitempty"`
+	// Canceled 更新被用户取消，安装目录已恢复到更新前的状态
+	Canceled     bool           `json:"canceled,omitempty"`
+	FromVersion  string         `json:"fromVersion,omitempty"`
+	ToVersion    string         `json:"toVersion,omitempty"`
+	Variant      string         `json:"variant,omitempty"`
+	Package      string         `json:"package"`
+	PackageHash  string         `json:"packageHash,omitempty"`
+	Source       string         `json:"source,omitempty"`
+	StartTime    time.Time      `json:"startTime"`
+	Duration     int64          `json:"durationMs"`
+	FilesChanged int            `json:"filesChanged"`
+	Plugins      []PluginReport `json:"plugins"`
+	// LockDecisions 文件被占用时按 --on-locked 策略做出的处理
+	LockDecisions []utils.LockDecision `json:"lockDecisions,omitempty"`
+	// SkippedFiles 被占用而按策略跳过、未更新的文件（相对于安装目录的路径）
+	SkippedFiles []string `json:"skippedFiles,omitempty"`
+}
+
+// JSON 返回摘要的 JSON 文本
+func (s *UpdateSummary) JSON() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf(`{"success":false,"error":%q}`, err.Error())
+	}
+	return string(data)
+}
+
+// PluginNotice 返回需要提示用户的插件信息，没有时返回空字符串
+func (s *UpdateSummary) PluginNotice() string {
+	var sb strings.Builder
+	for _, report := range s.Plugins {
+		switch report.Status {
+		case PluginDisabled:
+			sb.WriteString(fmt.Sprintf("  - %s %s 已禁用: %s\n", report.ID, report.Version, report.Reason))
+		case PluginUnknown:
+			sb.WriteString(fmt.Sprintf("  - %s 无法确认兼容性: %s\n", report.ID, report.Reason))
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "第三方插件:\n" + sb.String()
+}
+
+// SkippedNotice 返回被占用而未更新的文件，没有时返回空字符串
+func (s *UpdateSummary) SkippedNotice() string {
+	if len(s.SkippedFiles) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("以下文件被占用，未更新:\n")
+	for _, relPath := range s.SkippedFiles {
+		sb.WriteString("  - " + relPath + "\n")
+	}
+	return sb.String()
+}
+
+// Summary 返回本次更新的结果摘要
+func (u *Updater) Summary() *UpdateSummary {
+	return u.summary
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/hooks"
+	"club.xiaojiawei/hs-script-update/internal/install"
+	"club.xiaojiawei/hs-script-update/internal/migrate"
+	"club.xiaojiawei/hs-script-update/internal/progress"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// variantFiles 返回版本类型特有的文件
+func variantFiles(variant string) []string {
+	if variant == install.VariantJVM {
+		return config.JVMVariantFiles
+	}
+	return config.NativeVariantFiles
+}
+
+// variantLeftovers 切换版本类型时需要从安装目录中移除的旧版本类型特有文件
+// 更新包中会写入的路径不移除
+func (u *Updater) variantLeftovers(plan *updatePlan, variant string) []string {
+	if variant == u.installed.Variant {
+		return nil
+	}
+
+	var leftovers []string
+	for _, pattern := range variantFiles(u.installed.Variant) {
+		matches, err := filepath.Glob(filepath.Join(u.targetDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			relPath, err := filepath.Rel(u.targetDir, match)
+			if err != nil {
+				continue
+			}
+			relPath = filepath.ToSlash(relPath)
+			if !planWrites(plan, relPath) {
+				leftovers = append(leftovers, relPath)
+			}
+		}
+	}
+	sort.Strings(leftovers)
+	return leftovers
+}
+
+// planWrites 更新计划是否会写入该路径或其下的文件
+func planWrites(plan *updatePlan, relPath string) bool {
+	for _, entry := range plan.entries {
+		entryPath := strings.TrimSuffix(entry.relPath, "/")
+		if entryPath == relPath || strings.HasPrefix(entryPath, relPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// stateFiles 校验通过后写入的安装记录
+var stateFiles = []string{config.InstallManifestPath, config.VersionStampPath, config.ComponentStatePath}
+
+// backupFiles 写入文件前将被覆盖的文件移入备份，并记录将新建的文件和目录，取消或写入失败时用于恢复
+// 切换版本类型时还会将旧版本类型特有的文件移入备份，保留目录中的文件不会被修改，原样沿用到新版本类型
+func (u *Updater) backupFiles(ctx context.Context, plan *updatePlan, variant string, leftovers []string) (*backup, error) {
+	label := "update"
+	if variant != u.installed.Variant {
+		label = u.installed.Variant + "-to-" + variant
+	}
+	b := u.newBackup(label)
+	var total int64
+	for _, entry := range plan.entries {
+		if !entry.isDir && entry.action != rules.ActionPreserve {
+			total += existingSize(filepath.Join(u.targetDir, filepath.FromSlash(entry.relPath)))
+		}
+	}
+	u.tracker.Start(progress.PhaseBackup, total)
+
+	// 校验通过后会改写安装记录，之后的步骤失败时一并恢复
+	for _, relPath := range stateFiles {
+		if err := b.copy(relPath); err != nil {
+			return b, err
+		}
+	}
+
+	for _, entry := range plan.entries {
+		if err := ctx.Err(); err != nil {
+			return b, err
+		}
+		dst := filepath.Join(u.targetDir, filepath.FromSlash(entry.relPath))
+		if entry.isDir {
+			// 只记录新建的目录，恢复时删除
+			if !utils.Exists(dst) {
+				b.copy(strings.TrimSuffix(entry.relPath, "/"))
+			}
+			continue
+		}
+		if entry.action == rules.ActionPreserve && utils.Exists(dst) {
+			continue
+		}
+		// 正在运行的更新器不会被覆盖，由自更新流程处理
+		if utils.IsCurrentProcess(dst) {
+			continue
+		}
+		size := existingSize(dst)
+		var err error
+		if entry.action == rules.ActionMerge {
+			// 合并配置需要读取原文件，只能复制
+			err = b.copy(entry.relPath)
+		} else {
+			err = b.save(entry.relPath)
+		}
+		if err != nil {
+			return b, err
+		}
+		u.tracker.Add(entry.relPath, size)
+	}
+	if variant == u.installed.Variant {
+		return b, nil
+	}
+
+	for _, relPath := range leftovers {
+		u.logDetail(fmt.Sprintf("移除 %s 版文件: %s", u.installed.Variant, relPath))
+		if err := b.move(relPath); err != nil {
+			return b, err
+		}
+	}
+
+	u.logDetail(fmt.Sprintf("切换前的文件已备份到: %s", b.dir))
+	return b, nil
+}
+
+// restoreBackup 取消或更新失败时用备份恢复更新前的文件，恢复成功后删除备份并执行 on-rollback 钩子
+func (u *Updater) restoreBackup(b *backup, cause error) error {
+	u.logStatus("恢复更新前的文件...")
+	if err := b.restore(); err != nil {
+		return fmt.Errorf("%w（恢复备份失败: %v，备份位于 %s）", cause, err, b.dir)
+	}
+	if err := b.discard(); err != nil {
+		u.logWarn(fmt.Sprintf("删除备份失败: %v", err))
+	}
+	u.logDetail("已恢复更新前的文件")
+	if err := u.runHooks(context.Background(), hooks.EventOnRollback); err != nil {
+		u.logWarn(err.Error())
+	}
+	return cause
+}
+
+// reportDryRun 输出演练结果，不修改安装目录
+func (u *Updater) reportDryRun(plan *updatePlan, variant string, leftovers []string, migrations []migrate.Migration) {
+	var writes, preserves, merges int
+	var writeSize int64
+	for _, entry := range plan.entries {
+		if entry.isDir {
+			continue
+		}
+		dst := filepath.Join(u.targetDir, filepath.FromSlash(entry.relPath))
+		switch {
+		case entry.action == rules.ActionPreserve && utils.Exists(dst):
+			preserves++
+		case entry.action == rules.ActionMerge && utils.Exists(dst):
+			merges++
+		default:
+			writes++
+			writeSize += entry.size
+		}
+	}
+
+	u.logStatus("========================================")
+	u.logStatus("演练结果（未修改任何文件）")
+	u.logStatus("========================================")
+	if variant != u.installed.Variant {
+		u.logDetail(fmt.Sprintf("版本类型: %s -> %s", u.installed.Variant, variant))
+	} else {
+		u.logDetail(fmt.Sprintf("版本类型: %s", variant))
+	}
+	u.logDetail(fmt.Sprintf("版本: %s -> %s", displayVersion(u.fromVersion), displayVersion(u.toVersion)))
+	u.logDetail(fmt.Sprintf("写入文件: %d 个（%s），其中 %d 个内容有变化", writes,
+		utils.FormatBytes(uint64(writeSize)), u.summary.FilesChanged))
+	u.logDetail(fmt.Sprintf("保留文件: %d 个，合并配置: %d 个", preserves, merges))
+	if len(migrations) > 0 {
+		u.logDetail(fmt.Sprintf("配置迁移: %d 项", len(migrations)))
+	}
+	if u.runtime != nil {
+		switch {
+		case u.runtime.skip:
+			u.logDetail(fmt.Sprintf("Java 运行时: %s（未变化，跳过）", u.runtime.ref.Version))
+		case u.runtime.download:
+			u.logDetail(fmt.Sprintf("Java 运行时: %s（单独下载: %s）", u.runtime.ref.Version, u.runtime.ref.URL))
+		default:
+			u.logDetail(fmt.Sprintf("Java 运行时: %s（从更新包复制）", u.runtime.ref.Version))
+		}
+	}
+	if variant != u.installed.Variant {
+		for _, relPath := range leftovers {
+			u.logDetail(fmt.Sprintf("移除 %s 版文件: %s", u.installed.Variant, relPath))
+		}
+		u.logDetail(fmt.Sprintf("切换前的文件将备份到: %s",
+			filepath.Join(u.targetDir, filepath.FromSlash(config.ConfigBackupDir))))
+	}
+}
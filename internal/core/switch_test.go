@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+// jvmInstall JVM 版安装目录中的文件
+var jvmInstall = map[string]string{
+	"hs-script.exe":        "jvm exe",
+	"lib/app.jar":          "app",
+	"jre/bin/java":         "java",
+	"config/settings.yml":  "user settings",
+	"plugin/custom/a.jar":  "custom plugin",
+	"data/user/stats.json": "{}",
+}
+
+func TestSwitchVariantDryRun(t *testing.T) {
+	dir := t.TempDir()
+	for relPath, content := range jvmInstall {
+		writeTestFile(t, dir, relPath, content)
+	}
+	packagePath := filepath.Join(t.TempDir(), "native.zip")
+	writeTestZip(t, packagePath, map[string]string{"hs-script/hs-script.exe": "native exe"})
+
+	u := NewUpdater(packagePath, dir, false, 0, "")
+	u.SetSwitchVariant(true)
+	u.SetDryRun(true)
+	if err := u.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshotDir(t, dir); !reflect.DeepEqual(got, jvmInstall) {
+		t.Errorf("演练不应修改安装目录:\n实际 %v\n期望 %v", got, jvmInstall)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(config.UpdateHistoryPath))); !os.IsNotExist(err) {
+		t.Errorf("演练不应写入更新历史: %v", err)
+	}
+}
+
+func TestSwitchVariant(t *testing.T) {
+	dir := t.TempDir()
+	for relPath, content := range jvmInstall {
+		writeTestFile(t, dir, relPath, content)
+	}
+	packagePath := filepath.Join(t.TempDir(), "native.zip")
+	writeTestZip(t, packagePath, map[string]string{"hs-script/hs-script.exe": "native exe"})
+
+	u := NewUpdater(packagePath, dir, false, 0, "")
+	u.SetSwitchVariant(true)
+	if err := u.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// JVM 版特有的文件移入备份，保留目录沿用到新版本类型
+	files := snapshotDir(t, dir)
+	want := map[string]string{
+		"hs-script.exe":        "native exe",
+		"lib/app.jar":          "",
+		"jre/bin/java":         "",
+		"config/settings.yml":  "user settings",
+		"plugin/custom/a.jar":  "custom plugin",
+		"data/user/stats.json": "{}",
+	}
+	for relPath, content := range want {
+		if files[relPath] != content {
+			t.Errorf("切换后 %s 的内容 = %q，期望 %q", relPath, files[relPath], content)
+		}
+	}
+	var backedUp []string
+	for relPath := range files {
+		if rest, ok := strings.CutPrefix(relPath, config.ConfigBackupDir+"/"); ok {
+			backedUp = append(backedUp, rest[strings.Index(rest, "/")+1:])
+		}
+	}
+	sort.Strings(backedUp)
+	if want := []string{"hs-script.exe", "jre/bin/java", "lib/app.jar"}; !reflect.DeepEqual(backedUp, want) {
+		t.Errorf("备份中的文件 = %v，期望 %v", backedUp, want)
+	}
+}
+
+func TestSwitchVariantRestoreOnFailure(t *testing.T) {
+	cases := []struct {
+		name      string
+		streaming bool
+	}{
+		{"流式更新", true},
+		{"完整解压", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for relPath, content := range jvmInstall {
+				writeTestFile(t, dir, relPath, content)
+			}
+			// 写入文件之后执行的钩子失败
+			packagePath := filepath.Join(t.TempDir(), "native.zip")
+			writeTestZip(t, packagePath, map[string]string{
+				"hs-script/hs-script.exe":       "native exe",
+				"hs-script/" + config.HooksName: `{"hooks":[{"event":"post-copy","action":"delete","path":"../outside"}]}`,
+			})
+
+			u := NewUpdater(packagePath, dir, false, 0, "")
+			u.SetSwitchVariant(true)
+			u.SetStreaming(c.streaming)
+			if err := u.Update(context.Background()); err == nil {
+				t.Fatal("钩子失败时切换应返回错误")
+			}
+			if got := snapshotDir(t, dir); !reflect.DeepEqual(got, jvmInstall) {
+				t.Errorf("切换失败后应恢复安装目录:\n实际 %v\n期望 %v", got, jvmInstall)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(dir, filepath.FromSlash(config.ConfigBackupDir))); len(entries) > 0 {
+				t.Errorf("恢复成功后应删除备份，残留 %d 项", len(entries))
+			}
+		})
+	}
+}
+
+func TestSwitchVariantRejected(t *testing.T) {
+	dir := t.TempDir()
+	for relPath, content := range jvmInstall {
+		writeTestFile(t, dir, relPath, content)
+	}
+	packagePath := filepath.Join(t.TempDir(), "native.zip")
+	writeTestZip(t, packagePath, map[string]string{"hs-script/hs-script.exe": "native exe"})
+
+	u := NewUpdater(packagePath, dir, false, 0, "")
+	if err := u.Update(context.Background()); err == nil || !strings.Contains(err.Error(), "--switch-variant") {
+		t.Fatalf("未开启切换版本类型时应中止更新，实际: %v", err)
+	}
+	if got := snapshotDir(t, dir); !reflect.DeepEqual(got, jvmInstall) {
+		t.Errorf("中止更新时不应修改安装目录: %v", got)
+	}
+}
+
+// snapshotDir 读取安装目录中的所有文件，不包含更新历史和更新锁
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == config.UpdateHistoryPath || relPath == config.UpdateLockPath {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files[relPath] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
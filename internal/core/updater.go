@@ -1,112 +1,348 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/component"
 	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/hooks"
+	"club.xiaojiawei/hs-script-update/internal/install"
+	"club.xiaojiawei/hs-script-update/internal/logger"
+	"club.xiaojiawei/hs-script-update/internal/progress"
+	"club.xiaojiawei/hs-script-update/internal/rules"
 	"club.xiaojiawei/hs-script-update/internal/utils"
 )
 
 // ProgressCallback 进度回调接口
 type ProgressCallback interface {
 	SetStatus(status string)
-	SetProgress(current, max int)
+	SetProgress(event progress.Event)
 	AppendDetail(detail string)
 	ShowError(message string)
 	ShowSuccess(message string)
 }
 
+// CancelableProgress 支持取消更新的进度回调，写入文件完成后无法再取消时调用 SetCancelable(false)
+type CancelableProgress interface {
+	SetCancelable(cancelable bool)
+}
+
+// EventProgress 输出完整事件流的进度回调（如 --progress=jsonl），除 ProgressCallback 外
+// 还接收阶段开始和结束、处理完的文件、警告、需要用户操作的提示和带错误码的失败结果
+type EventProgress interface {
+	progress.Observer
+	ShowWarning(message string)
+	PromptRequired(file, question string)
+	Failed(code, message string)
+}
+
+// 更新失败的错误码，用于 --progress=jsonl 和 --json 输出
+const (
+	// ErrorCodeCanceled 更新被取消，安装目录已恢复
+	ErrorCodeCanceled = "canceled"
+	// ErrorCodeLocked 同一安装目录已有更新正在进行
+	ErrorCodeLocked = "locked"
+	// ErrorCodeUnsafePackage 更新包未通过安全检查
+	ErrorCodeUnsafePackage = "unsafe-package"
+	// ErrorCodePreflight 磁盘空间或写入权限检查未通过
+	ErrorCodePreflight = "preflight"
+	// ErrorCodeVerify 安装结果校验未通过
+	ErrorCodeVerify = "verify"
+	// ErrorCodeFailed 其他错误
+	ErrorCodeFailed = "failed"
+)
+
 // Updater 更新器核心
 type Updater struct {
-	zipFilePath    string
-	targetDir      string
-	tempExtractDir string
-	isPause        bool
-	mainPid        int
-	mainProgram    string
-	progress       ProgressCallback
+	packagePath     string
+	targetDir       string
+	tempExtractDir  string
+	isPause         bool
+	mainPid         int
+	mainProgram     string
+	mainArgs        []string
+	mainArgsSet     bool
+	mainEnv         []string
+	resumePaused    bool
+	streaming       bool
+	healthCheck     bool
+	healthTimeout   time.Duration
+	shutdownTimeout time.Duration
+	noKill          bool
+	switchVariant   bool
+	dryRun          bool
+	wait            bool
+	fromVersion     string
+	toVersion       string
+	ruleSet         *rules.RuleSet
+	hooks           *hooks.Runner
+	previous        *Manifest
+	packageManifest *Manifest
+	components      *component.State
+	runtime         *runtimeUpdate
+	installed       *install.Info
+	summary         *UpdateSummary
+	progress        ProgressCallback
+	tracker         *progress.Tracker
 }
 
 // NewUpdater 创建更新器实例
-func NewUpdater(zipFilePath, targetDir string, isPause bool, mainPid int, mainProgram string) *Updater {
-	return &Updater{
-		zipFilePath:    zipFilePath,
-		targetDir:      targetDir,
-		tempExtractDir: filepath.Join(targetDir, "_temp_update"),
-		isPause:        isPause,
-		mainPid:        mainPid,
-		mainProgram:    mainProgram,
+func NewUpdater(packagePath, targetDir string, isPause bool, mainPid int, mainProgram string) *Updater {
+	u := &Updater{
+		packagePath:     packagePath,
+		targetDir:       targetDir,
+		tempExtractDir:  filepath.Join(targetDir, "_temp_update"),
+		isPause:         isPause,
+		mainPid:         mainPid,
+		mainProgram:     mainProgram,
+		streaming:       true,
+		shutdownTimeout: time.Duration(config.ShutdownTimeout) * time.Second,
+		summary:         &UpdateSummary{Package: packagePath, Plugins: []PluginReport{}},
 	}
+	u.tracker = progress.NewTracker(u.reportProgress)
+	return u
+}
+
+// SetStreaming 设置是否使用流式更新（直接从压缩包写入目标目录，不做完整的临时解压）
+func (u *Updater) SetStreaming(streaming bool) {
+	u.streaming = streaming
+}
+
+// SetHealthCheck 设置更新完成后是否以健康检查参数启动主程序
+func (u *Updater) SetHealthCheck(enabled bool, timeout time.Duration) {
+	u.healthCheck = enabled
+	u.healthTimeout = timeout
+}
+
+// SetVersions 设置更新前后的版本，用于选择配置迁移，为空时自动识别
+func (u *Updater) SetVersions(fromVersion, toVersion string) {
+	u.fromVersion = fromVersion
+	u.toVersion = toVersion
+}
+
+// SetMainArgs 设置重新启动主程序时的参数，未设置时沿用主程序进程的启动参数
+func (u *Updater) SetMainArgs(args []string) {
+	u.mainArgs = args
+	u.mainArgsSet = true
+}
+
+// SetResumePaused 设置主程序更新前处于暂停状态时，更新后是否恢复运行
+func (u *Updater) SetResumePaused(resumePaused bool) {
+	u.resumePaused = resumePaused
+}
+
+// SetShutdown 设置等待主程序退出的超时时间，以及超时后是否禁止强制结束主程序
+func (u *Updater) SetShutdown(timeout time.Duration, noKill bool) {
+	u.shutdownTimeout = timeout
+	u.noKill = noKill
+}
+
+// SetSwitchVariant 设置是否允许使用另一版本类型的更新包切换安装目录的版本类型
+func (u *Updater) SetSwitchVariant(switchVariant bool) {
+	u.switchVariant = switchVariant
+}
+
+// SetDryRun 设置是否只演练更新，输出将要执行的操作而不修改安装目录
+func (u *Updater) SetDryRun(dryRun bool) {
+	u.dryRun = dryRun
+}
+
+// SetWait 设置已有更新正在进行时是否等待其完成，默认直接返回错误
+func (u *Updater) SetWait(wait bool) {
+	u.wait = wait
+}
+
+// SetSource 设置更新包的来源（如仓库源或下载地址），记录到更新历史中
+func (u *Updater) SetSource(source string) {
+	u.summary.Source = source
 }
 
 // SetProgressCallback 设置进度回调
 func (u *Updater) SetProgressCallback(callback ProgressCallback) {
 	u.progress = callback
+	if events, ok := callback.(EventProgress); ok {
+		u.tracker.SetObserver(events)
+	}
 }
 
-// logStatus 记录状态（同时输出到控制台和GUI）
+// logStatus 记录状态（同时输出到日志和GUI状态栏）
 func (u *Updater) logStatus(status string) {
-	fmt.Println(status)
+	slog.Info(status)
 	if u.progress != nil {
 		u.progress.SetStatus(status)
-		u.progress.AppendDetail(status)
 	}
 }
 
 // logDetail 记录详细信息
 func (u *Updater) logDetail(detail string) {
-	fmt.Println(detail)
+	slog.Info(detail)
+}
+
+// logWarn 记录警告
+func (u *Updater) logWarn(message string) {
+	slog.Warn(message)
+}
+
+// setCancelable 通知进度回调是否还能取消更新
+func (u *Updater) setCancelable(cancelable bool) {
+	if c, ok := u.progress.(CancelableProgress); ok {
+		c.SetCancelable(cancelable)
+	}
+}
+
+// reportProgress 将进度事件转发给进度回调
+func (u *Updater) reportProgress(event progress.Event) {
 	if u.progress != nil {
-		u.progress.AppendDetail(detail)
+		u.progress.SetProgress(event)
 	}
 }
 
-// updateProgress 更新进度
-func (u *Updater) updateProgress(current, max int) {
+// progressPhases 本次更新会经过的进度阶段，用于按权重分配总体进度
+func (u *Updater) progressPhases() []progress.Phase {
+	var phases []progress.Phase
+	if u.runtime != nil && u.runtime.download {
+		phases = append(phases, progress.PhaseDownload)
+	}
+	phases = append(phases, progress.PhaseBackup)
+	if !u.streaming {
+		phases = append(phases, progress.PhaseExtract)
+	}
+	return append(phases, progress.PhaseCopy)
+}
+
+// Update 执行更新，结果记录到 Summary 和安装目录的更新历史中
+// 写入文件完成之前 ctx 取消时中止更新，并将安装目录恢复到更新前的状态
+func (u *Updater) Update(ctx context.Context) error {
+	// 日志同时写入 GUI 的详细信息
+	events, _ := u.progress.(EventProgress)
 	if u.progress != nil {
-		u.progress.SetProgress(current, max)
+		logger.SetSink(func(level slog.Level, message string) {
+			if events != nil && level == slog.LevelWarn {
+				events.ShowWarning(strings.TrimPrefix(message, logger.Format(level, "")))
+			}
+			u.progress.AppendDetail(message)
+		})
+		defer logger.SetSink(nil)
+	}
+	// 弹窗询问用户之前通知宿主程序
+	if events != nil {
+		utils.PromptNotifier = events.PromptRequired
+		defer func() { utils.PromptNotifier = nil }()
+	}
+
+	// 同一目录同时只允许一个更新器运行，未获取到锁时不写入更新历史
+	lock, err := u.acquireLock(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		if u.progress != nil {
+			u.progress.ShowError(err.Error())
+		}
+		if events != nil {
+			events.Failed(ErrorCode(err), err.Error())
+		}
+		return err
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			u.logWarn(fmt.Sprintf("释放更新锁失败: %v", err))
+		}
+	}()
+
+	u.summary.StartTime = time.Now()
+	err = u.update(ctx)
+	u.summary.Duration = time.Since(u.summary.StartTime).Milliseconds()
+	u.summary.Success = err == nil
+	u.summary.Canceled = errors.Is(err, context.Canceled)
+	if err != nil {
+		u.summary.Error = err.Error()
+		u.summary.ErrorCode = ErrorCode(err)
+		slog.Error(fmt.Sprintf("更新失败: %v", err))
+		if events != nil {
+			events.Failed(u.summary.ErrorCode, errorMessage(err))
+		}
+	}
+	if u.summary.Canceled {
+		u.restartAfterCancel()
+	}
+
+	if utils.Exists(u.targetDir) && !u.dryRun {
+		if historyErr := AppendHistory(u.targetDir, NewHistoryEntry(u.summary)); historyErr != nil {
+			u.logWarn(fmt.Sprintf("写入更新历史失败: %v", historyErr))
+		}
+	}
+	return err
+}
+
+// restartAfterCancel 取消更新后重新启动主程序，安装目录已恢复到更新前的状态
+func (u *Updater) restartAfterCancel() {
+	if u.mainProgram == "" || u.dryRun {
+		return
+	}
+	// 等待主程序退出时取消，主程序仍在运行
+	if u.mainPid > 0 && utils.IsProcessRunning(u.mainPid) {
+		return
+	}
+	if err := utils.StartProgram(u.mainProgram, u.launchOptions()); err != nil {
+		u.logWarn(fmt.Sprintf("启动主程序失败: %v", err))
 	}
 }
 
-// Update 执行更新
-func (u *Updater) Update() error {
+// update 执行更新的各个步骤
+func (u *Updater) update(ctx context.Context) error {
 	u.logStatus("========================================")
 	u.logStatus("开始更新程序")
 	u.logStatus("========================================")
-	u.logDetail(fmt.Sprintf("更新包: %s", u.zipFilePath))
+	u.logDetail(fmt.Sprintf("更新包: %s", u.packagePath))
 	u.logDetail(fmt.Sprintf("目标目录: %s", u.targetDir))
 	u.logDetail(fmt.Sprintf("暂停状态: %v", u.isPause))
-	u.updateProgress(0, 100)
+	u.tracker.Start(progress.PhasePrepare, 0)
+
+	// 文件被占用时只允许结束安装目录中的进程
+	utils.InstallDir = u.targetDir
+	utils.LockRecorder = func(decision utils.LockDecision) {
+		u.summary.LockDecisions = append(u.summary.LockDecisions, decision)
+	}
+	defer func() { utils.LockRecorder = nil }()
 
 	// 0. 等待主程序退出
-	if u.mainPid > 0 {
+	if u.mainPid > 0 && !u.dryRun {
 		u.logDetail(fmt.Sprintf("主程序 PID: %d", u.mainPid))
-		u.logStatus("等待主程序退出...")
-		u.updateProgress(5, 100)
-		if err := utils.WaitForProcessExit(u.mainPid, 5); err != nil {
-			errMsg := fmt.Sprintf("等待主程序退出失败: %v", err)
+		u.captureMainCommand()
+		u.logStatus("请求主程序退出...")
+		if err := u.shutdownMainProgram(ctx); err != nil {
+			err = fmt.Errorf("等待主程序退出失败: %w", err)
 			if u.progress != nil {
-				u.progress.ShowError(errMsg)
+				u.progress.ShowError(errorMessage(err))
 			}
-			return fmt.Errorf("等待主程序退出失败: %w", err)
+			return err
 		}
 	}
 
 	// 1. 检查更新包是否存在
 	u.logStatus("检查更新包...")
-	u.updateProgress(10, 100)
-	if !utils.Exists(u.zipFilePath) {
-		errMsg := fmt.Sprintf("更新包不存在: %s", u.zipFilePath)
+	u.tracker.Step(0.2)
+	if !utils.Exists(u.packagePath) {
+		errMsg := fmt.Sprintf("更新包不存在: %s", u.packagePath)
 		if u.progress != nil {
 			u.progress.ShowError(errMsg)
 		}
-		return fmt.Errorf("更新包不存在: %s", u.zipFilePath)
+		return fmt.Errorf("更新包不存在: %s", u.packagePath)
+	}
+	if hash, err := utils.HashFile(u.packagePath); err == nil {
+		u.summary.PackageHash = hash
+	} else {
+		u.logWarn(fmt.Sprintf("计算更新包校验值失败: %v", err))
 	}
 
 	// 2. 检查目标目录是否存在
-	u.updateProgress(15, 100)
+	u.tracker.Step(0.4)
 	if !utils.Exists(u.targetDir) {
 		errMsg := fmt.Sprintf("目标目录不存在: %s", u.targetDir)
 		if u.progress != nil {
@@ -114,78 +350,269 @@ func (u *Updater) Update() error {
 		}
 		return fmt.Errorf("目标目录不存在: %s", u.targetDir)
 	}
+	if manifest, err := LoadInstallManifest(u.targetDir); err == nil {
+		u.previous = manifest
+	}
 
-	// 3. 清理临时目录
-	u.updateProgress(20, 100)
-	if utils.Exists(u.tempExtractDir) {
-		u.logStatus("清理旧的临时目录...")
-		if err := utils.Delete(u.tempExtractDir); err != nil {
-			return fmt.Errorf("清理临时目录失败: %w", err)
+	// 3. 判断是 JVM 版还是 Native 版，并确认更新包类型一致（或需要切换版本类型）
+	u.tracker.Step(0.6)
+	installed, err := install.Probe(u.targetDir)
+	var variant string
+	if err == nil {
+		u.installed = installed
+		variant, err = u.resolveVariant()
+	}
+	if err != nil {
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
 		}
+		return err
+	}
+	if installed.IsJVM() {
+		u.logDetail("检测到版本类型: JVM")
+	} else {
+		u.logDetail("检测到版本类型: Native")
+	}
+	isJvmVersion := variant == install.VariantJVM
+	u.summary.Variant = variant
+	if installed.Version != "" {
+		u.logDetail(fmt.Sprintf("当前版本: %s（来源: %s）", installed.Version, installed.VersionSource))
 	}
 
-	// 4. 创建临时目录并解压
-	u.updateProgress(25, 100)
-	if err := utils.CreateDirectory(u.tempExtractDir); err != nil {
-		return fmt.Errorf("创建临时目录失败: %w", err)
+	// 4. 加载更新规则，检查磁盘空间和写入权限
+	u.logStatus("检查磁盘空间和写入权限...")
+	u.tracker.Step(0.8)
+	ruleSet, err := u.loadRuleSet(isJvmVersion)
+	if err != nil {
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
+		}
+		return err
 	}
+	u.ruleSet = ruleSet
 
-	u.logStatus("解压更新包...")
-	u.updateProgress(30, 100)
-	if err := utils.Unzip(u.zipFilePath, u.tempExtractDir); err != nil {
-		u.cleanup()
-		errMsg := fmt.Sprintf("解压失败: %v", err)
+	// 读取文件清单和组件状态，决定各组件是否需要更新
+	u.packageManifest, err = u.loadPackageManifest()
+	if err == nil {
+		u.components, err = component.LoadState(u.targetDir)
+	}
+	var plan *updatePlan
+	if err == nil {
+		plan, err = u.buildPlan()
+	}
+	if err == nil {
+		plan, err = u.planRuntime(plan, isJvmVersion)
+	}
+	if err == nil {
+		err = u.preflight(plan)
+	}
+	var expected map[string]string
+	if err == nil {
+		if expected, err = u.expectedHashes(plan); err != nil {
+			err = fmt.Errorf("计算校验值失败: %w", err)
+		}
+		u.summary.FilesChanged = u.countChangedFiles(expected)
+		if u.runtime != nil {
+			for relPath, hash := range u.runtime.files {
+				expected[relPath] = hash
+			}
+		}
+	}
+	if err != nil {
 		if u.progress != nil {
-			u.progress.ShowError(errMsg)
+			u.progress.ShowError(errorMessage(err))
 		}
-		return fmt.Errorf("解压失败: %w", err)
+		return err
+	}
+	u.tracker.Plan(u.progressPhases()...)
+	u.tracker.Step(1)
+
+	// 读取配置迁移，并在复制文件前备份原配置
+	migrations, err := u.loadMigrations()
+	u.summary.FromVersion = u.fromVersion
+	u.summary.ToVersion = u.toVersion
+	var configBackup *backup
+	if err == nil {
+		err = u.loadHooks()
+	}
+	leftovers := u.variantLeftovers(plan, variant)
+	if err == nil && u.dryRun {
+		u.reportDryRun(plan, variant, leftovers, migrations)
+		u.tracker.Complete()
+		return nil
+	}
+	if err == nil && len(migrations) > 0 {
+		configBackup, err = u.backupConfig(migrations)
+	}
+	if err == nil && u.runtime != nil && u.runtime.download {
+		defer u.cleanupRuntime()
+		err = u.downloadRuntime(ctx)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = u.runHooks(ctx, hooks.EventPreUpdate)
+	}
+	if err != nil {
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
+		}
+		return err
 	}
 
-	// 5. 判断是 JVM 版还是 Native 版
-	u.updateProgress(50, 100)
-	isJvmVersion := utils.DetectJVMVersion(u.targetDir)
-	if isJvmVersion {
-		u.logDetail("检测到版本类型: JVM")
+	// 备份将被覆盖的文件，取消或写入失败时恢复；切换版本类型时还会移除旧版本类型特有的文件
+	if variant != installed.Variant {
+		u.logStatus("切换版本类型...")
 	} else {
-		u.logDetail("检测到版本类型: Native")
+		u.logStatus("备份将被覆盖的文件...")
+	}
+	updateBackup, err := u.backupFiles(ctx, plan, variant, leftovers)
+	if err != nil {
+		if variant != installed.Variant {
+			err = fmt.Errorf("切换版本类型失败: %w", err)
+		} else {
+			err = fmt.Errorf("备份文件失败: %w", err)
+		}
+		err = u.restoreBackup(updateBackup, err)
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
+		}
+		return err
 	}
 
-	// 6. 执行更新
-	u.logStatus("执行更新...")
-	u.updateProgress(60, 100)
-	if err := u.performUpdate(isJvmVersion); err != nil {
-		u.cleanup()
+	// 5. 执行更新
+	selfUpdateReady := false
+	if u.streaming {
+		u.logStatus("执行更新...")
+		err := u.performStreamingUpdate(ctx, isJvmVersion, plan)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			err = u.restoreBackup(updateBackup, err)
+			if u.progress != nil {
+				u.progress.ShowError(errorMessage(err))
+			}
+			return err
+		}
+
+		// 在删除更新包之前准备好新版本更新器
+		ready, err := utils.ExtractSelfUpdateFromArchive(u.packagePath, u.targetDir)
+		if err != nil {
+			u.logWarn(fmt.Sprintf("准备更新器自更新失败: %v", err))
+		}
+		selfUpdateReady = ready
+	} else {
+		ready, err := u.extractAndUpdate(ctx, isJvmVersion, plan)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			err = u.restoreBackup(updateBackup, err)
+			if u.progress != nil {
+				u.progress.ShowError(errorMessage(err))
+			}
+			return err
+		}
+		selfUpdateReady = ready
+	}
+
+	// 单独下载的 Java 运行时在其他文件写入后替换
+	if u.runtime != nil && u.runtime.download {
+		err := u.replaceRuntime(updateBackup)
+		if err == nil {
+			for relPath, hash := range u.runtime.files {
+				expected[relPath] = hash
+			}
+		} else {
+			err = u.restoreBackup(updateBackup, err)
+		}
+		if err != nil {
+			if u.progress != nil {
+				u.progress.ShowError(errorMessage(err))
+			}
+			return err
+		}
+	}
+
+	// 文件已全部写入，之后的步骤不再响应取消
+	u.setCancelable(false)
+	if ctx.Err() != nil {
+		u.logWarn("文件已写入，无法再取消更新，继续完成剩余步骤")
+	}
+	u.tracker.Start(progress.PhaseFinalize, 0)
+
+	if err := u.runHooks(context.Background(), hooks.EventPostCopy); err != nil {
+		err = u.restoreBackup(updateBackup, err)
 		if u.progress != nil {
-			u.progress.ShowError(fmt.Sprintf("更新失败: %v", err))
+			u.progress.ShowError(errorMessage(err))
 		}
 		return err
 	}
 
-	// 7. 清理临时目录
-	u.logStatus("清理临时文件...")
-	u.updateProgress(90, 100)
-	if err := utils.Delete(u.tempExtractDir); err != nil {
-		u.logDetail(fmt.Sprintf("警告: 清理临时目录失败: %v", err))
+	// 执行配置迁移
+	if len(migrations) > 0 {
+		u.logStatus("迁移配置文件...")
+		u.tracker.Step(0.2)
+		if err := u.runMigrations(migrations, configBackup); err != nil {
+			err = u.restoreBackup(updateBackup, err)
+			if u.progress != nil {
+				u.progress.ShowError(errorMessage(err))
+			}
+			return err
+		}
+	}
+
+	// 6. 校验安装结果，被占用而跳过的文件不参与校验
+	u.logStatus("校验安装结果...")
+	u.tracker.Step(0.4)
+	u.excludeSkippedFiles(expected)
+	if err := u.verifyInstall(expected, isJvmVersion); err != nil {
+		err = u.restoreBackup(updateBackup, err)
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
+		}
+		return err
 	}
 
-	// 8. 删除更新包（如果在目标目录中）
-	if strings.HasPrefix(u.zipFilePath, u.targetDir) {
-		u.logDetail(fmt.Sprintf("删除更新包: %s", u.zipFilePath))
-		if err := utils.Delete(u.zipFilePath); err != nil {
-			u.logDetail(fmt.Sprintf("警告: 删除更新包失败: %v", err))
+	// 检查第三方插件兼容性
+	u.tracker.Step(0.8)
+	u.checkPlugins()
+	notice := u.summary.PluginNotice() + u.summary.SkippedNotice()
+
+	if err := u.runHooks(context.Background(), hooks.EventPostUpdate); err != nil {
+		err = u.restoreBackup(updateBackup, err)
+		if u.progress != nil {
+			u.progress.ShowError(errorMessage(err))
+		}
+		return err
+	}
+	// 切换版本类型的备份保留在 data/backup 中，普通更新在所有步骤完成后删除备份
+	if variant == installed.Variant {
+		if err := updateBackup.discard(); err != nil {
+			u.logWarn(fmt.Sprintf("删除备份失败: %v", err))
+		}
+	}
+
+	// 7. 删除更新包（如果在目标目录中）
+	if strings.HasPrefix(u.packagePath, u.targetDir) {
+		u.logDetail(fmt.Sprintf("删除更新包: %s", u.packagePath))
+		if err := utils.Delete(u.packagePath); err != nil {
+			u.logWarn(fmt.Sprintf("删除更新包失败: %v", err))
 		}
 	}
 
 	u.logStatus("========================================")
 	u.logStatus("更新完成！")
 	u.logStatus("========================================")
-	u.updateProgress(100, 100)
+	u.tracker.Complete()
 
-	// 9. 启动主程序（如果提供了路径）
+	// 8. 启动主程序（如果提供了路径）
 	if u.mainProgram != "" {
-		if err := utils.StartProgram(u.mainProgram, u.isPause); err != nil {
-			u.logDetail(fmt.Sprintf("警告: 启动主程序失败: %v", err))
-			successMsg := fmt.Sprintf("软件已成功更新！\n\n但启动主程序失败：%v\n\n请手动启动程序。", err)
+		if err := utils.StartProgram(u.mainProgram, u.launchOptions()); err != nil {
+			u.logWarn(fmt.Sprintf("启动主程序失败: %v", err))
+			successMsg := fmt.Sprintf("软件已成功更新！\n\n但启动主程序失败：%v\n\n请手动启动程序。", err) + noticeSuffix(notice)
 			if u.progress != nil {
 				u.progress.ShowSuccess(successMsg)
 			} else {
@@ -193,7 +620,7 @@ func (u *Updater) Update() error {
 			}
 		} else {
 			u.logDetail(fmt.Sprintf("主程序已启动: %s", u.mainProgram))
-			successMsg := "软件已成功更新！\n\n主程序已自动启动。"
+			successMsg := "软件已成功更新！\n\n主程序已自动启动。" + noticeSuffix(notice)
 			if u.progress != nil {
 				u.progress.ShowSuccess(successMsg)
 			} else {
@@ -202,7 +629,7 @@ func (u *Updater) Update() error {
 		}
 	} else {
 		// 显示更新完成提示
-		successMsg := "软件已成功更新！\n\n您现在可以重新启动程序。"
+		successMsg := "软件已成功更新！\n\n您现在可以重新启动程序。" + noticeSuffix(notice)
 		if u.progress != nil {
 			u.progress.ShowSuccess(successMsg)
 		} else {
@@ -210,38 +637,128 @@ func (u *Updater) Update() error {
 		}
 	}
 
-	// 10. 处理自我更新
-	if err := utils.HandleSelfUpdate(u.tempExtractDir, u.targetDir); err != nil {
-		u.logDetail(fmt.Sprintf("警告: 更新器自更新失败: %v", err))
-		u.logDetail(fmt.Sprintf("请手动替换 %s", config.UpdaterName))
+	// 9. 处理自我更新
+	if selfUpdateReady {
+		if err := utils.ReplaceSelf(u.targetDir); err != nil {
+			u.logWarn(fmt.Sprintf("更新器自更新失败: %v", err))
+			u.logDetail(fmt.Sprintf("请手动替换 %s", config.UpdaterName))
+		}
 	}
 
 	return nil
 }
 
+// extractAndUpdate 完整解压到临时目录后再复制到目标目录
+// 删除临时目录前准备好新版本更新器，返回 true 表示需要调用 ReplaceSelf 完成替换
+func (u *Updater) extractAndUpdate(ctx context.Context, isJvmVersion bool, plan *updatePlan) (bool, error) {
+	// 清理临时目录
+	if utils.Exists(u.tempExtractDir) {
+		u.logStatus("清理旧的临时目录...")
+		if err := utils.Delete(u.tempExtractDir); err != nil {
+			return false, fmt.Errorf("清理临时目录失败: %w", err)
+		}
+	}
+
+	// 创建临时目录并解压
+	if err := utils.CreateDirectory(u.tempExtractDir); err != nil {
+		return false, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+
+	u.logStatus("解压更新包...")
+	u.tracker.Start(progress.PhaseExtract, plan.totalSize)
+	if err := utils.ExtractArchive(ctx, u.packagePath, u.tempExtractDir, u.tracker.Add); err != nil {
+		u.cleanup()
+		return false, fmt.Errorf("解压失败: %w", err)
+	}
+
+	// 执行更新
+	u.logStatus("执行更新...")
+	components := u.newComponentProgress(plan)
+	components.start()
+	if err := u.performUpdate(ctx, isJvmVersion, components.update); err != nil {
+		u.cleanup()
+		return false, fmt.Errorf("更新失败: %w", err)
+	}
+
+	// 在删除临时目录之前准备好新版本更新器
+	ready, err := utils.StageSelfUpdate(u.tempExtractDir, u.targetDir)
+	if err != nil {
+		u.logWarn(fmt.Sprintf("准备更新器自更新失败: %v", err))
+	}
+
+	// 清理临时目录
+	u.logStatus("清理临时文件...")
+	if err := utils.Delete(u.tempExtractDir); err != nil {
+		u.logWarn(fmt.Sprintf("清理临时目录失败: %v", err))
+	}
+	return ready, nil
+}
+
+// performStreamingUpdate 直接从压缩包更新目标目录
+func (u *Updater) performStreamingUpdate(ctx context.Context, isJvmVersion bool, plan *updatePlan) error {
+	if isJvmVersion {
+		u.logStatus("更新 JVM 版本...")
+	} else {
+		u.logStatus("更新 Native 版本...")
+	}
+
+	components := u.newComponentProgress(plan)
+	components.start()
+	if err := utils.ApplyArchive(ctx, u.packagePath, u.targetDir, u.ruleSet, components.update); err != nil {
+		return fmt.Errorf("更新文件失败: %w", err)
+	}
+
+	u.logDetail("文件更新完成")
+	return nil
+}
+
+// verifyInstall 重新计算写入文件的校验值，检查入口文件，并按需执行健康检查
+func (u *Updater) verifyInstall(expected map[string]string, isJvmVersion bool) error {
+	verifier := NewVerifier(u.targetDir)
+	result := verifier.Verify(expected, isJvmVersion)
+	if result.OK() && u.healthCheck {
+		u.logStatus("执行健康检查...")
+		result.Checked++
+		if err := verifier.HealthCheck(u.mainProgram, u.healthTimeout); err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("健康检查失败: %v", err))
+		}
+	}
+	if !result.OK() {
+		for _, failure := range result.Failures {
+			u.logDetail("校验失败: " + failure)
+		}
+		return &VerifyError{Result: result}
+	}
+	u.logDetail(fmt.Sprintf("校验通过: 共 %d 项", result.Checked))
+
+	variant := install.VariantOf(isJvmVersion)
+	if err := SaveInstallManifest(u.targetDir, &Manifest{Version: u.toVersion, Variant: variant, Files: expected}); err != nil {
+		u.logWarn(fmt.Sprintf("保存文件清单失败: %v", err))
+	}
+	stamp := &install.Stamp{Version: u.toVersion, Variant: variant, UpdatedAt: time.Now()}
+	if err := install.SaveStamp(u.targetDir, stamp); err != nil {
+		u.logWarn(fmt.Sprintf("写入版本记录失败: %v", err))
+	}
+	u.saveComponents(expected, isJvmVersion)
+	return nil
+}
+
 // performUpdate 执行更新操作
-func (u *Updater) performUpdate(isJvmVersion bool) error {
+func (u *Updater) performUpdate(ctx context.Context, isJvmVersion bool, onProgress utils.ProgressFunc) error {
 	extractedDir := utils.FindExtractedDirectory(u.tempExtractDir)
 
 	if isJvmVersion {
-		return u.updateJVMVersion(extractedDir)
+		return u.updateJVMVersion(ctx, extractedDir, onProgress)
 	}
-	return u.updateNativeVersion(extractedDir)
+	return u.updateNativeVersion(ctx, extractedDir, onProgress)
 }
 
 // updateJVMVersion 更新 JVM 版本
-func (u *Updater) updateJVMVersion(extractedDir string) error {
+func (u *Updater) updateJVMVersion(ctx context.Context, extractedDir string, onProgress utils.ProgressFunc) error {
 	u.logStatus("更新 JVM 版本...")
-	u.logDetail(fmt.Sprintf("保留目录: %s", strings.Join(config.JVMPreserveDirs, ", ")))
-	u.logDetail(fmt.Sprintf("要更新的插件: %s", strings.Join(config.JVMUpdatePluginDirs, ", ")))
-
-	// 复制文件，排除需要保留的目录和插件
-	if err := utils.CopyDirectory(
-		extractedDir,
-		u.targetDir,
-		config.JVMPreserveDirs,
-		config.JVMUpdatePluginDirs,
-	); err != nil {
+
+	// 按更新规则复制文件，保留配置、数据和第三方插件
+	if err := utils.CopyDirectory(ctx, extractedDir, u.targetDir, u.ruleSet, onProgress); err != nil {
 		return fmt.Errorf("复制文件失败: %w", err)
 	}
 
@@ -250,17 +767,11 @@ func (u *Updater) updateJVMVersion(extractedDir string) error {
 }
 
 // updateNativeVersion 更新 Native 版本
-func (u *Updater) updateNativeVersion(extractedDir string) error {
+func (u *Updater) updateNativeVersion(ctx context.Context, extractedDir string, onProgress utils.ProgressFunc) error {
 	u.logStatus("更新 Native 版本...")
-	u.logDetail(fmt.Sprintf("保留目录: %s", strings.Join(config.NativePreserveDirs, ", ")))
-
-	// 复制文件，只排除 config 和 data
-	if err := utils.CopyDirectory(
-		extractedDir,
-		u.targetDir,
-		config.NativePreserveDirs,
-		nil,
-	); err != nil {
+
+	// 按更新规则复制文件，保留配置和数据
+	if err := utils.CopyDirectory(ctx, extractedDir, u.targetDir, u.ruleSet, onProgress); err != nil {
 		return fmt.Errorf("复制文件失败: %w", err)
 	}
 
@@ -268,11 +779,73 @@ func (u *Updater) updateNativeVersion(extractedDir string) error {
 	return nil
 }
 
+// errorMessage 生成展示给用户的错误信息
+func errorMessage(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return "更新已取消，安装目录已恢复到更新前的状态。"
+	}
+	var limitErr *archive.LimitError
+	if errors.As(err, &limitErr) {
+		return fmt.Sprintf("更新包未通过安全检查，已中止更新:\n\n%v", limitErr)
+	}
+	var preflightErr *PreflightError
+	if errors.As(err, &preflightErr) {
+		return preflightErr.Error()
+	}
+	var verifyErr *VerifyError
+	if errors.As(err, &verifyErr) {
+		return verifyErr.Error()
+	}
+	return fmt.Sprintf("更新失败: %v", err)
+}
+
+// ErrorCode 返回更新失败的错误码
+func ErrorCode(err error) string {
+	var limitErr *archive.LimitError
+	var preflightErr *PreflightError
+	var verifyErr *VerifyError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCanceled
+	case errors.Is(err, utils.ErrInstanceLocked):
+		return ErrorCodeLocked
+	case errors.As(err, &limitErr):
+		return ErrorCodeUnsafePackage
+	case errors.As(err, &preflightErr):
+		return ErrorCodePreflight
+	case errors.As(err, &verifyErr):
+		return ErrorCodeVerify
+	}
+	return ErrorCodeFailed
+}
+
+// countChangedFiles 统计与上次安装相比内容发生变化的文件数量
+func (u *Updater) countChangedFiles(expected map[string]string) int {
+	if u.previous == nil {
+		return len(expected)
+	}
+	count := 0
+	for relPath, hash := range expected {
+		if !strings.EqualFold(u.previous.Files[relPath], hash) {
+			count++
+		}
+	}
+	return count
+}
+
+// noticeSuffix 将提示信息追加到成功信息之后
+func noticeSuffix(notice string) string {
+	if notice == "" {
+		return ""
+	}
+	return "\n\n" + notice
+}
+
 // cleanup 清理临时文件
 func (u *Updater) cleanup() {
 	if utils.Exists(u.tempExtractDir) {
 		if err := utils.Delete(u.tempExtractDir); err != nil {
-			fmt.Printf("清理临时目录失败: %v\n", err)
+			u.logWarn(fmt.Sprintf("清理临时目录失败: %v", err))
 		}
 	}
 }
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/install"
+)
+
+// packageVariant 根据更新包内容判断版本类型，包含 lib/*.jar 的是 JVM 版
+func packageVariant(packagePath string) (string, error) {
+	a, err := archive.Open(packagePath)
+	if err != nil {
+		return "", err
+	}
+	defer a.Close()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return "", err
+	}
+
+	root := archive.FindRoot(entries)
+	for _, entry := range entries {
+		relPath := strings.TrimPrefix(entry.Name, root)
+		if matched, _ := path.Match("lib/*.jar", relPath); matched && !entry.IsDir {
+			return install.VariantJVM, nil
+		}
+	}
+	return install.VariantNative, nil
+}
+
+// resolveVariant 确定更新后的版本类型
+// 更新包类型与安装目录不一致时，只有开启了切换版本类型才继续
+func (u *Updater) resolveVariant() (string, error) {
+	variant, err := packageVariant(u.packagePath)
+	if err != nil {
+		return "", fmt.Errorf("读取更新包失败: %w", err)
+	}
+	switch {
+	case variant != u.installed.Variant && !u.switchVariant:
+		return "", fmt.Errorf("更新包类型 (%s) 与安装目录类型 (%s，来源: %s) 不一致，已中止更新；如需切换版本类型请使用 --switch-variant",
+			variant, u.installed.Variant, u.installed.VariantSource)
+	case variant != u.installed.Variant:
+		u.logStatus(fmt.Sprintf("切换版本类型: %s -> %s", u.installed.Variant, variant))
+	case u.switchVariant:
+		u.logWarn(fmt.Sprintf("更新包与安装目录同为 %s 版，无需切换版本类型，按普通更新处理", variant))
+	}
+	return variant, nil
+}
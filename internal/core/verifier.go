@@ -0,0 +1,230 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/component"
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// Manifest 文件清单，记录相对路径与 SHA-256 值
+type Manifest struct {
+	Version string            `json:"version,omitempty"`
+	Variant string            `json:"variant,omitempty"`
+	Files   map[string]string `json:"files"`
+	// Components 发布清单中引用的组件版本（如 Java 运行时）
+	Components map[string]component.Ref `json:"components,omitempty"`
+}
+
+// VerifyResult 校验结果
+type VerifyResult struct {
+	Checked  int      `json:"checked"`
+	Failures []string `json:"failures"`
+}
+
+// OK 是否全部通过
+func (r *VerifyResult) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// VerifyError 安装结果校验未通过
+type VerifyError struct {
+	Result *VerifyResult
+}
+
+// Error 返回错误描述
+func (e *VerifyError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("安装结果校验未通过（共 %d 项）:\n", len(e.Result.Failures)))
+	for i, failure := range e.Result.Failures {
+		if i >= maxReportedProblems {
+			sb.WriteString(fmt.Sprintf("  ... 等共 %d 项问题\n", len(e.Result.Failures)))
+			break
+		}
+		sb.WriteString("  - " + failure + "\n")
+	}
+	return sb.String()
+}
+
+// Verifier 安装目录校验器
+type Verifier struct {
+	targetDir string
+}
+
+// NewVerifier 创建校验器
+func NewVerifier(targetDir string) *Verifier {
+	return &Verifier{targetDir: targetDir}
+}
+
+// Verify 校验文件哈希和入口文件
+func (v *Verifier) Verify(expected map[string]string, isJvmVersion bool) *VerifyResult {
+	result := &VerifyResult{Failures: []string{}}
+
+	paths := make([]string, 0, len(expected))
+	for relPath := range expected {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	for _, relPath := range paths {
+		fullPath := filepath.Join(v.targetDir, filepath.FromSlash(relPath))
+		// 正在运行的更新器由自更新流程处理，不参与校验
+		if utils.IsCurrentProcess(fullPath) {
+			continue
+		}
+
+		result.Checked++
+		actual, err := utils.HashFile(fullPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				result.Failures = append(result.Failures, fmt.Sprintf("文件缺失: %s", relPath))
+			} else {
+				result.Failures = append(result.Failures, fmt.Sprintf("读取失败: %s (%v)", relPath, err))
+			}
+			continue
+		}
+		if !strings.EqualFold(actual, expected[relPath]) {
+			result.Failures = append(result.Failures, fmt.Sprintf("校验值不一致: %s", relPath))
+		}
+	}
+
+	entryPoints := config.NativeEntryPoints
+	if isJvmVersion {
+		entryPoints = config.JVMEntryPoints
+	}
+	for _, pattern := range entryPoints {
+		result.Checked++
+		matches, err := filepath.Glob(filepath.Join(v.targetDir, filepath.FromSlash(pattern)))
+		if err != nil || len(matches) == 0 {
+			result.Failures = append(result.Failures, fmt.Sprintf("缺少入口文件: %s", pattern))
+		}
+	}
+
+	return result
+}
+
+// HealthCheck 以健康检查参数启动主程序
+func (v *Verifier) HealthCheck(programPath string, timeout time.Duration) error {
+	if programPath == "" {
+		programPath = filepath.Join(v.targetDir, config.ProgramName+".exe")
+	}
+	return utils.RunHealthCheck(programPath, []string{config.HealthCheckArg}, config.HealthCheckReadyLine, timeout)
+}
+
+// LoadInstallManifest 读取安装目录中的文件清单
+func LoadInstallManifest(targetDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(config.InstallManifestPath)))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析文件清单失败: %w", err)
+	}
+	return &manifest, nil
+}
+
+// loadPackageManifest 读取更新包中的文件清单，没有清单时返回 nil
+func (u *Updater) loadPackageManifest() (*Manifest, error) {
+	data, err := utils.ReadArchiveFile(u.packagePath, config.PackageManifestName)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析更新包文件清单失败: %w", err)
+	}
+	return &manifest, nil
+}
+
+// SaveInstallManifest 写入安装目录中的文件清单
+func SaveInstallManifest(targetDir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(targetDir, filepath.FromSlash(config.InstallManifestPath))
+	return utils.WriteFileAtomic(manifestPath, strings.NewReader(string(data)), 0644)
+}
+
+// expectedHashes 计算计划中文件的期望校验值
+// 更新包中带有文件清单时以清单为准，否则直接计算更新包中文件的校验值
+func (u *Updater) expectedHashes(plan *updatePlan) (map[string]string, error) {
+	// 保留和合并的文件可能与更新包不同，只校验直接覆盖的文件
+	wanted := make(map[string]bool)
+	for _, entry := range plan.entries {
+		if !entry.isDir && entry.action == rules.ActionInclude {
+			wanted[entry.relPath] = true
+		}
+	}
+
+	a, err := archive.Open(u.packagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, err
+	}
+	root := archive.FindRoot(entries)
+
+	hashes := make(map[string]string)
+	err = a.Walk(func(entry *archive.Entry, r io.Reader) error {
+		relPath := strings.TrimPrefix(entry.Name, root)
+		if entry.IsDir || !wanted[relPath] {
+			return nil
+		}
+
+		hash, err := utils.HashReader(r)
+		if err != nil {
+			return err
+		}
+		hashes[relPath] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if u.packageManifest != nil && len(u.packageManifest.Files) > 0 {
+		u.logDetail("使用更新包中的文件清单进行校验")
+		for relPath := range hashes {
+			if hash, ok := u.packageManifest.Files[relPath]; ok {
+				hashes[relPath] = hash
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// excludeSkippedFiles 从期望校验值中移除按文件占用策略跳过的文件，这些文件保持更新前的内容，
+// 不参与校验，也不写入文件清单和组件状态；跳过的文件记录到摘要中
+func (u *Updater) excludeSkippedFiles(expected map[string]string) {
+	for _, decision := range u.summary.LockDecisions {
+		if decision.Action != "skipped" {
+			continue
+		}
+		relPath, err := filepath.Rel(u.targetDir, decision.File)
+		if err != nil {
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+		delete(expected, relPath)
+		u.summary.SkippedFiles = append(u.summary.SkippedFiles, relPath)
+		u.logWarn(fmt.Sprintf("文件被占用，未更新: %s", relPath))
+	}
+}
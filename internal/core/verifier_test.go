@@ -0,0 +1,144 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"club.xiaojiawei/hs-script-update/internal/rules"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// hashString 计算字符串的 SHA-256 值
+func hashString(t *testing.T, s string) string {
+	t.Helper()
+	hash, err := utils.HashReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "hs-script.exe", "exe")
+	writeTestFile(t, dir, "lib/app.jar", "app")
+
+	cases := []struct {
+		name         string
+		expected     map[string]string
+		isJvm        bool
+		wantFailures []string
+	}{
+		{"全部一致", map[string]string{"hs-script.exe": hashString(t, "exe"), "lib/app.jar": hashString(t, "app")}, true, []string{}},
+		{"校验值不区分大小写", map[string]string{"hs-script.exe": strings.ToUpper(hashString(t, "exe"))}, false, []string{}},
+		{"内容不一致", map[string]string{"lib/app.jar": hashString(t, "new app")}, true, []string{"校验值不一致: lib/app.jar"}},
+		{"文件缺失", map[string]string{"lib/b.jar": hashString(t, "b")}, false, []string{"文件缺失: lib/b.jar"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := NewVerifier(dir).Verify(c.expected, c.isJvm)
+			if !reflect.DeepEqual(result.Failures, c.wantFailures) {
+				t.Errorf("Failures = %q，期望 %q", result.Failures, c.wantFailures)
+			}
+		})
+	}
+
+	// JVM 版缺少 lib/*.jar 时入口文件校验失败
+	result := NewVerifier(t.TempDir()).Verify(nil, true)
+	if want := []string{"缺少入口文件: hs-script.exe", "缺少入口文件: lib/*.jar"}; !reflect.DeepEqual(result.Failures, want) {
+		t.Errorf("Failures = %q，期望 %q", result.Failures, want)
+	}
+}
+
+func TestExpectedHashes(t *testing.T) {
+	packagePath := filepath.Join(t.TempDir(), "update.zip")
+	writeTestZip(t, packagePath, map[string]string{
+		"hs-script/hs-script.exe":  "exe",
+		"hs-script/lib/app.jar":    "app",
+		"hs-script/config/app.yml": "a: 1",
+	})
+	plan := &updatePlan{entries: []planEntry{
+		{relPath: "hs-script.exe", action: rules.ActionInclude},
+		{relPath: "lib/", isDir: true, action: rules.ActionInclude},
+		{relPath: "lib/app.jar", action: rules.ActionInclude},
+		// 保留和合并的文件不参与校验
+		{relPath: "config/app.yml", action: rules.ActionPreserve},
+	}}
+
+	cases := []struct {
+		name     string
+		manifest *Manifest
+		want     map[string]string
+	}{
+		{"计算更新包中的文件", nil, map[string]string{
+			"hs-script.exe": hashString(t, "exe"),
+			"lib/app.jar":   hashString(t, "app"),
+		}},
+		{"文件清单优先", &Manifest{Files: map[string]string{"lib/app.jar": "manifest", "other.jar": "other"}}, map[string]string{
+			"hs-script.exe": hashString(t, "exe"),
+			"lib/app.jar":   "manifest",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := NewUpdater(packagePath, t.TempDir(), false, 0, "")
+			u.packageManifest = c.manifest
+			got, err := u.expectedHashes(plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expectedHashes = %v，期望 %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInstallManifest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadInstallManifest(dir); err == nil {
+		t.Error("没有文件清单时应返回错误")
+	}
+
+	want := &Manifest{Version: "v4.2.0", Variant: "jvm", Files: map[string]string{"lib/app.jar": "abc"}}
+	if err := SaveInstallManifest(dir, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadInstallManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadInstallManifest = %+v，期望 %+v", got, want)
+	}
+}
+
+func TestExcludeSkippedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "hs-script.exe", "new exe")
+	// 被占用而跳过的文件保持更新前的内容
+	writeTestFile(t, dir, "lib/a.jar", "old jar")
+	exeHash, err := utils.HashFile(filepath.Join(dir, "hs-script.exe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{targetDir: dir, summary: &UpdateSummary{LockDecisions: []utils.LockDecision{
+		{File: filepath.Join(dir, "lib", "a.jar"), Action: "skipped"},
+		{File: filepath.Join(dir, "hs-script.exe"), Action: "killed"},
+	}}}
+	expected := map[string]string{"hs-script.exe": exeHash, "lib/a.jar": "new jar hash"}
+
+	u.excludeSkippedFiles(expected)
+	if want := map[string]string{"hs-script.exe": exeHash}; !reflect.DeepEqual(expected, want) {
+		t.Errorf("期望校验值 = %v，期望 %v", expected, want)
+	}
+	if want := []string{"lib/a.jar"}; !reflect.DeepEqual(u.summary.SkippedFiles, want) {
+		t.Errorf("跳过的文件 = %v，期望 %v", u.summary.SkippedFiles, want)
+	}
+	if result := NewVerifier(dir).Verify(expected, false); !result.OK() {
+		t.Errorf("跳过的文件不应导致校验失败: %v", result.Failures)
+	}
+}
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 
 	"club.xiaojiawei/hs-script-update/internal/model"
 	"club.xiaojiawei/hs-script-update/internal/repository"
@@ -19,16 +21,16 @@ func NewVersionChecker(repo repository.Repository) *VersionChecker {
 }
 
 // GetLatestVersion 获取最新版本信息
-func (vc *VersionChecker) GetLatestVersion(checkDev, isNative, interactive bool) (string, error) {
-	fmt.Println("获取最新版本信息...")
-	fmt.Printf("检查开发版: %v\n", checkDev)
+func (vc *VersionChecker) GetLatestVersion(ctx context.Context, checkDev, isNative, interactive bool) (string, error) {
+	slog.Info("获取最新版本信息...")
+	slog.Info(fmt.Sprintf("检查开发版: %v", checkDev))
 	if isNative {
-		fmt.Println("版本类型: Native")
+		slog.Info("版本类型: Native")
 	} else {
-		fmt.Println("版本类型: JVM")
+		slog.Info("版本类型: JVM")
 	}
 
-	latestRelease, err := vc.repo.GetLatestRelease(checkDev)
+	latestRelease, err := vc.repo.GetLatestRelease(ctx, checkDev)
 	if err != nil {
 		return "", fmt.Errorf("获取版本失败: %w", err)
 	}
@@ -39,30 +41,30 @@ func (vc *VersionChecker) GetLatestVersion(checkDev, isNative, interactive bool)
 
 	if interactive {
 		// 交互模式：显示到控制台
-		fmt.Println("\n========================================")
-		fmt.Println("最新版本信息")
-		fmt.Println("========================================")
-		fmt.Printf("版本号: %s\n", latestRelease.TagName)
+		slog.Info("========================================")
+		slog.Info("最新版本信息")
+		slog.Info("========================================")
+		slog.Info(fmt.Sprintf("版本号: %s", latestRelease.TagName))
 		if isNative {
-			fmt.Println("版本类型: Native")
+			slog.Info("版本类型: Native")
 		} else {
-			fmt.Println("版本类型: JVM")
+			slog.Info("版本类型: JVM")
 		}
 		if latestRelease.IsPreRelease {
-			fmt.Println("预发布: 是")
+			slog.Info("预发布: 是")
 		} else {
-			fmt.Println("预发布: 否")
+			slog.Info("预发布: 否")
 		}
 		if latestRelease.Name != "" {
-			fmt.Printf("名称: %s\n", latestRelease.Name)
+			slog.Info(fmt.Sprintf("名称: %s", latestRelease.Name))
 		}
 		if latestRelease.Body != "" {
-			fmt.Println("\n更新日志:")
-			fmt.Println(latestRelease.Body)
+			slog.Info("更新日志:")
+			slog.Info(latestRelease.Body)
 		}
-		fmt.Printf("\n下载地址: %s\n", repository.GetReleaseDownloadURL(vc.repo, latestRelease, isNative))
-		fmt.Printf("发布页面: %s\n", repository.GetReleasePageURL(vc.repo, latestRelease))
-		fmt.Println("========================================\n")
+		slog.Info(fmt.Sprintf("下载地址: %s", repository.GetReleaseDownloadURL(vc.repo, latestRelease, isNative)))
+		slog.Info(fmt.Sprintf("发布页面: %s", repository.GetReleasePageURL(vc.repo, latestRelease)))
+		slog.Info("========================================")
 		return "", nil
 	}
 
@@ -86,17 +88,17 @@ func (vc *VersionChecker) GetLatestVersion(checkDev, isNative, interactive bool)
 }
 
 // CheckVersion 检查版本更新
-func (vc *VersionChecker) CheckVersion(currentVersion string, checkDev, isNative, interactive bool) (string, error) {
-	fmt.Println("开始检查更新...")
-	fmt.Printf("当前版本: %s\n", currentVersion)
-	fmt.Printf("检查开发版: %v\n", checkDev)
+func (vc *VersionChecker) CheckVersion(ctx context.Context, currentVersion string, checkDev, isNative, interactive bool) (string, error) {
+	slog.Info("开始检查更新...")
+	slog.Info(fmt.Sprintf("当前版本: %s", currentVersion))
+	slog.Info(fmt.Sprintf("检查开发版: %v", checkDev))
 	if isNative {
-		fmt.Println("版本类型: Native")
+		slog.Info("版本类型: Native")
 	} else {
-		fmt.Println("版本类型: JVM")
+		slog.Info("版本类型: JVM")
 	}
 
-	latestRelease, err := vc.repo.GetLatestRelease(checkDev)
+	latestRelease, err := vc.repo.GetLatestRelease(ctx, checkDev)
 	if err != nil {
 		return "", fmt.Errorf("检查版本失败: %w", err)
 	}
@@ -109,29 +111,29 @@ func (vc *VersionChecker) CheckVersion(currentVersion string, checkDev, isNative
 
 	if interactive {
 		// 交互模式：显示到控制台
-		fmt.Println("\n========================================")
-		fmt.Println("版本检查结果")
-		fmt.Println("========================================")
-		fmt.Printf("当前版本: %s\n", current.TagName)
-		fmt.Printf("最新版本: %s\n", latestRelease.TagName)
+		slog.Info("========================================")
+		slog.Info("版本检查结果")
+		slog.Info("========================================")
+		slog.Info(fmt.Sprintf("当前版本: %s", current.TagName))
+		slog.Info(fmt.Sprintf("最新版本: %s", latestRelease.TagName))
 		if isNative {
-			fmt.Println("版本类型: Native")
+			slog.Info("版本类型: Native")
 		} else {
-			fmt.Println("版本类型: JVM")
+			slog.Info("版本类型: JVM")
 		}
 
 		if latestRelease.CompareTo(current) > 0 {
-			fmt.Println("状态: 有新版本可用")
+			slog.Info("状态: 有新版本可用")
 			if latestRelease.Body != "" {
-				fmt.Println("\n更新日志:")
-				fmt.Println(latestRelease.Body)
+				slog.Info("更新日志:")
+				slog.Info(latestRelease.Body)
 			}
-			fmt.Printf("\n下载地址: %s\n", repository.GetReleaseDownloadURL(vc.repo, latestRelease, isNative))
-			fmt.Printf("发布页面: %s\n", repository.GetReleasePageURL(vc.repo, latestRelease))
+			slog.Info(fmt.Sprintf("下载地址: %s", repository.GetReleaseDownloadURL(vc.repo, latestRelease, isNative)))
+			slog.Info(fmt.Sprintf("发布页面: %s", repository.GetReleasePageURL(vc.repo, latestRelease)))
 		} else {
-			fmt.Println("状态: 已是最新版本")
+			slog.Info("状态: 已是最新版本")
 		}
-		fmt.Println("========================================\n")
+		slog.Info("========================================")
 		return "", nil
 	}
 
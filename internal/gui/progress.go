@@ -1,12 +1,24 @@
 package gui
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/progress"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
 // ProgressCallback 进度回调接口
 type ProgressCallback interface {
 	// SetStatus 设置状态文本
 	SetStatus(status string)
 
-	// SetProgress 设置进度 (当前值, 最大值)
-	SetProgress(current, max int)
+	// SetProgress 设置进度（阶段、已处理字节数、总体进度、吞吐量和剩余时间）
+	SetProgress(event progress.Event)
 
 	// AppendDetail 追加详细信息
 	AppendDetail(detail string)
@@ -18,29 +30,85 @@ type ProgressCallback interface {
 	ShowSuccess(message string)
 }
 
+// consoleInterval 控制台模式下两次输出进度之间的最短间隔，切换阶段和完成时立即输出
+const consoleInterval = time.Second
+
 // ConsoleProgress 控制台进度输出（用于非GUI模式）
-type ConsoleProgress struct{}
+// 状态和详细信息已由日志输出到控制台，这里只输出进度行
+type ConsoleProgress struct {
+	mu        sync.Mutex
+	out       io.Writer
+	lastPhase progress.Phase
+	lastPrint time.Time
+}
 
 func NewConsoleProgress() *ConsoleProgress {
-	return &ConsoleProgress{}
+	return &ConsoleProgress{out: os.Stdout}
 }
 
 func (cp *ConsoleProgress) SetStatus(status string) {
-	// 控制台模式不需要实现
+	// 状态已由日志输出
 }
 
-func (cp *ConsoleProgress) SetProgress(current, max int) {
-	// 控制台模式不需要实现
+// SetProgress 输出进度行，如 "[ 45.2%] 写入文件 12.3 MB / 40.0 MB，5.1 MB/s，剩余 6 秒  lib/app.jar"
+func (cp *ConsoleProgress) SetProgress(event progress.Event) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	now := time.Now()
+	if event.Phase == cp.lastPhase && event.Percent < 100 && now.Sub(cp.lastPrint) < consoleInterval {
+		return
+	}
+	cp.lastPhase = event.Phase
+	cp.lastPrint = now
+
+	line := fmt.Sprintf("[%5.1f%%] %s", event.Percent, describeProgress(event))
+	if event.CurrentFile != "" {
+		line += "  " + event.CurrentFile
+	}
+	fmt.Fprintln(cp.out, line)
 }
 
 func (cp *ConsoleProgress) AppendDetail(detail string) {
-	// 控制台模式不需要实现
+	// 详细信息已由日志输出
 }
 
 func (cp *ConsoleProgress) ShowError(message string) {
-	// 控制台模式不需要实现
+	// 错误由调用方提示
 }
 
+// ShowSuccess 与未设置进度回调时一样弹出更新完成提示
 func (cp *ConsoleProgress) ShowSuccess(message string) {
-	// 控制台模式不需要实现
+	utils.ShowMessageBox(message, "更新完成")
+}
+
+// describeProgress 生成当前阶段的进度说明，如 "写入文件 12.3 MB / 40.0 MB，5.1 MB/s，剩余 6 秒"
+func describeProgress(event progress.Event) string {
+	var parts []string
+	switch {
+	case event.BytesTotal > 0:
+		parts = append(parts, fmt.Sprintf("%s / %s",
+			utils.FormatBytes(uint64(event.BytesDone)), utils.FormatBytes(uint64(event.BytesTotal))))
+	case event.BytesDone > 0:
+		parts = append(parts, utils.FormatBytes(uint64(event.BytesDone)))
+	}
+	if event.BytesPerSecond > 0 {
+		parts = append(parts, utils.FormatBytes(uint64(event.BytesPerSecond))+"/s")
+	}
+	if event.ETA > 0 {
+		parts = append(parts, "剩余 "+formatETA(event.ETA))
+	}
+	if len(parts) == 0 {
+		return event.Phase.Name()
+	}
+	return event.Phase.Name() + " " + strings.Join(parts, "，")
+}
+
+// formatETA 将剩余时间格式化为秒或分秒，不足 1 秒按 1 秒显示
+func formatETA(eta time.Duration) string {
+	seconds := int((eta + time.Second - 1) / time.Second)
+	if seconds < 60 {
+		return fmt.Sprintf("%d 秒", seconds)
+	}
+	return fmt.Sprintf("%d 分 %d 秒", seconds/60, seconds%60)
 }
@@ -5,27 +5,37 @@ import (
 	"sync"
 	"time"
 
+	"club.xiaojiawei/hs-script-update/internal/progress"
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
 )
 
+// progressScale 进度条的最大值，按千分比显示
+const progressScale = 1000
+
 // UpdaterWindow GUI更新窗口
 type UpdaterWindow struct {
-	mainWindow      *walk.MainWindow
-	progressBar     *walk.ProgressBar
-	statusLabel     *walk.Label
-	countdownLabel  *walk.Label
-	detailText      *walk.TextEdit
-	mu              sync.Mutex
-	maxProgress     int
-	currentProgress int
+	mainWindow     *walk.MainWindow
+	progressBar    *walk.ProgressBar
+	progressLabel  *walk.Label
+	fileLabel      *walk.Label
+	statusLabel    *walk.Label
+	countdownLabel *walk.Label
+	detailText     *walk.TextEdit
+	cancelButton   *walk.PushButton
+	mu             sync.Mutex
+	onCancel       func()
+	// running 更新正在进行，此时关闭窗口会先取消更新
+	running bool
+	// cancelable 更新还能取消（文件写入完成前）
+	cancelable bool
+	// closeRequested 更新过程中请求过关闭窗口，更新结束后关闭
+	closeRequested bool
 }
 
 // NewUpdaterWindow 创建更新窗口
 func NewUpdaterWindow() *UpdaterWindow {
-	return &UpdaterWindow{
-		maxProgress: 100,
-	}
+	return &UpdaterWindow{}
 }
 
 // Show 显示窗口
@@ -54,9 +64,20 @@ func (uw *UpdaterWindow) Show() error {
 			ProgressBar{
 				AssignTo: &uw.progressBar,
 				MinValue: 0,
-				MaxValue: 100,
+				MaxValue: progressScale,
 				Value:    0,
 			},
+			Label{
+				AssignTo: &uw.progressLabel,
+				Text:     "",
+				Font:     Font{PointSize: 9},
+			},
+			Label{
+				AssignTo:     &uw.fileLabel,
+				Text:         "",
+				Font:         Font{PointSize: 9},
+				EllipsisMode: EllipsisPath,
+			},
 			HSpacer{},
 			Label{
 				Text: "详细信息:",
@@ -68,12 +89,25 @@ func (uw *UpdaterWindow) Show() error {
 				VScroll:  true,
 				Font:     Font{Family: "Consolas", PointSize: 9},
 			},
+			Composite{
+				Layout: HBox{MarginsZero: true},
+				Children: []Widget{
+					HSpacer{},
+					PushButton{
+						AssignTo:  &uw.cancelButton,
+						Text:      "取消更新",
+						Enabled:   false,
+						OnClicked: uw.cancel,
+					},
+				},
+			},
 		},
 	}.Create()
 
 	if err != nil {
 		return fmt.Errorf("创建窗口失败: %w", err)
 	}
+	uw.mainWindow.Closing().Attach(uw.onClosing)
 
 	// 显示窗口
 	uw.mainWindow.Show()
@@ -88,6 +122,81 @@ func (uw *UpdaterWindow) Run() {
 	}
 }
 
+// SetOnCancel 设置取消更新的回调并启用取消按钮，更新过程中关闭窗口同样会取消更新
+func (uw *UpdaterWindow) SetOnCancel(cancel func()) {
+	uw.mu.Lock()
+	uw.onCancel = cancel
+	uw.running = true
+	uw.cancelable = true
+	uw.mu.Unlock()
+
+	uw.setCancelButton(true)
+}
+
+// SetCancelable 设置是否还能取消更新，文件写入完成后禁用取消按钮
+func (uw *UpdaterWindow) SetCancelable(cancelable bool) {
+	uw.mu.Lock()
+	uw.cancelable = cancelable
+	uw.mu.Unlock()
+
+	uw.setCancelButton(cancelable)
+}
+
+// Finish 更新结束，之后可以直接关闭窗口；更新过程中请求过关闭窗口时立即关闭
+func (uw *UpdaterWindow) Finish() {
+	uw.mu.Lock()
+	uw.running = false
+	uw.cancelable = false
+	closeRequested := uw.closeRequested
+	uw.mu.Unlock()
+
+	uw.setCancelButton(false)
+	if closeRequested {
+		uw.Close()
+	}
+}
+
+// setCancelButton 设置取消按钮是否可用
+func (uw *UpdaterWindow) setCancelButton(enabled bool) {
+	if uw.cancelButton != nil {
+		uw.cancelButton.Synchronize(func() {
+			uw.cancelButton.SetEnabled(enabled)
+		})
+	}
+}
+
+// cancel 取消更新，在 UI 线程中调用
+func (uw *UpdaterWindow) cancel() {
+	uw.mu.Lock()
+	onCancel := uw.onCancel
+	cancelable := uw.running && uw.cancelable
+	uw.cancelable = false
+	uw.mu.Unlock()
+
+	if !cancelable || onCancel == nil {
+		return
+	}
+	uw.cancelButton.SetEnabled(false)
+	uw.statusLabel.SetText("正在取消更新，恢复更新前的文件...")
+	onCancel()
+}
+
+// onClosing 更新过程中关闭窗口时先取消更新，等待恢复完成后再关闭；无法取消时等待更新完成
+func (uw *UpdaterWindow) onClosing(canceled *bool, reason walk.CloseReason) {
+	uw.mu.Lock()
+	running := uw.running
+	if running {
+		uw.closeRequested = true
+	}
+	uw.mu.Unlock()
+
+	if !running {
+		return
+	}
+	*canceled = true
+	uw.cancel()
+}
+
 // SetStatus 设置状态文本
 func (uw *UpdaterWindow) SetStatus(status string) {
 	uw.mu.Lock()
@@ -100,18 +209,16 @@ func (uw *UpdaterWindow) SetStatus(status string) {
 	}
 }
 
-// SetProgress 设置进度
-func (uw *UpdaterWindow) SetProgress(current, max int) {
+// SetProgress 设置进度，显示总体进度、当前阶段的吞吐量、剩余时间和正在处理的文件
+func (uw *UpdaterWindow) SetProgress(event progress.Event) {
 	uw.mu.Lock()
 	defer uw.mu.Unlock()
 
-	uw.currentProgress = current
-	uw.maxProgress = max
-
 	if uw.progressBar != nil {
 		uw.progressBar.Synchronize(func() {
-			uw.progressBar.SetRange(0, max)
-			uw.progressBar.SetValue(current)
+			uw.progressBar.SetValue(int(event.Percent * progressScale / 100))
+			uw.progressLabel.SetText(fmt.Sprintf("%.1f%%  %s", event.Percent, describeProgress(event)))
+			uw.fileLabel.SetText(event.CurrentFile)
 		})
 	}
 }
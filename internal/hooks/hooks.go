@@ -0,0 +1,250 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// Event 钩子触发阶段
+type Event string
+
+const (
+	// EventPreUpdate 写入文件之前
+	EventPreUpdate Event = "pre-update"
+	// EventPostCopy 文件复制完成、配置迁移之前
+	EventPostCopy Event = "post-copy"
+	// EventPostUpdate 更新完成、启动主程序之前
+	EventPostUpdate Event = "post-update"
+	// EventOnRollback 恢复备份之后
+	EventOnRollback Event = "on-rollback"
+)
+
+// Policy 钩子失败时的处理方式
+type Policy string
+
+const (
+	// PolicyFail 中止更新
+	PolicyFail Policy = "fail"
+	// PolicyContinue 记录警告后继续
+	PolicyContinue Policy = "continue"
+)
+
+// Action 内置动作
+type Action string
+
+const (
+	// ActionDelete 删除安装目录中的文件或目录
+	ActionDelete Action = "delete"
+	// ActionMkdir 创建目录
+	ActionMkdir Action = "mkdir"
+	// ActionMove 移动或重命名文件
+	ActionMove Action = "move"
+)
+
+// defaultTimeout 钩子默认超时时间（秒）
+const defaultTimeout = 60
+
+// Hook 更新包声明的钩子，command 和 action 二选一
+type Hook struct {
+	Name  string `json:"name,omitempty"`
+	Event Event  `json:"event"`
+	// Command 命令及参数
+	Command []string `json:"command,omitempty"`
+	// Action 内置动作，Path/To 为相对于安装目录的路径
+	Action Action `json:"action,omitempty"`
+	Path   string `json:"path,omitempty"`
+	To     string `json:"to,omitempty"`
+	// WorkDir 相对于安装目录的工作目录，默认为安装目录
+	WorkDir string `json:"workDir,omitempty"`
+	// Env 额外的环境变量
+	Env map[string]string `json:"env,omitempty"`
+	// Timeout 超时时间（秒）
+	Timeout int `json:"timeout,omitempty"`
+	// OnFailure 失败时的处理方式，默认为 fail
+	OnFailure Policy `json:"onFailure,omitempty"`
+}
+
+// File 钩子文件内容
+type File struct {
+	Hooks []Hook `json:"hooks"`
+}
+
+// Parse 解析钩子文件
+func Parse(data []byte) ([]Hook, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析钩子文件失败: %w", err)
+	}
+
+	for i := range file.Hooks {
+		if err := file.Hooks[i].validate(); err != nil {
+			return nil, fmt.Errorf("第 %d 个钩子无效: %w", i+1, err)
+		}
+	}
+	return file.Hooks, nil
+}
+
+// validate 检查钩子参数
+func (h *Hook) validate() error {
+	switch h.Event {
+	case EventPreUpdate, EventPostCopy, EventPostUpdate, EventOnRollback:
+	default:
+		return fmt.Errorf("未知的事件: %q", h.Event)
+	}
+
+	switch h.OnFailure {
+	case "":
+		h.OnFailure = PolicyFail
+	case PolicyFail, PolicyContinue:
+	default:
+		return fmt.Errorf("未知的失败策略: %q", h.OnFailure)
+	}
+
+	if (len(h.Command) == 0) == (h.Action == "") {
+		return fmt.Errorf("command 和 action 需要且只能指定一个")
+	}
+	switch h.Action {
+	case "":
+	case ActionDelete, ActionMkdir:
+		if h.Path == "" {
+			return fmt.Errorf("%s 动作需要 path", h.Action)
+		}
+	case ActionMove:
+		if h.Path == "" || h.To == "" {
+			return fmt.Errorf("move 动作需要 path 和 to")
+		}
+	default:
+		return fmt.Errorf("未知的内置动作: %q", h.Action)
+	}
+	return nil
+}
+
+// displayName 返回用于日志的钩子名称
+func (h *Hook) displayName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if h.Action != "" {
+		return fmt.Sprintf("%s %s", h.Action, h.Path)
+	}
+	return fmt.Sprintf("%v", h.Command)
+}
+
+// Runner 钩子执行器
+type Runner struct {
+	// TargetDir 安装目录
+	TargetDir string
+	// Hooks 更新包声明的所有钩子
+	Hooks []Hook
+	// Env 传递给命令的环境变量
+	Env []string
+	// Log 输出日志
+	Log func(message string)
+}
+
+// Run 按声明顺序执行指定阶段的钩子
+// 策略为 fail 的钩子失败时立即返回错误，策略为 continue 的钩子失败时只记录警告
+func (r *Runner) Run(ctx context.Context, event Event) error {
+	for i := range r.Hooks {
+		hook := &r.Hooks[i]
+		if hook.Event != event {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.log(fmt.Sprintf("执行钩子 [%s]: %s", event, hook.displayName()))
+		err := r.runHook(ctx, hook)
+		if err == nil {
+			continue
+		}
+		// 取消时不按策略继续执行
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if hook.OnFailure == PolicyContinue {
+			r.log(fmt.Sprintf("警告: 钩子 %s 失败，继续更新: %v", hook.displayName(), err))
+			continue
+		}
+		return fmt.Errorf("钩子 %s [%s] 失败: %w", hook.displayName(), event, err)
+	}
+	return nil
+}
+
+// runHook 执行单个钩子
+func (r *Runner) runHook(ctx context.Context, hook *Hook) error {
+	if hook.Action != "" {
+		return r.runAction(hook)
+	}
+
+	workDir := r.TargetDir
+	if hook.WorkDir != "" {
+		dir, err := archive.SafeJoin(r.TargetDir, hook.WorkDir)
+		if err != nil {
+			return err
+		}
+		workDir = dir
+	}
+
+	env := append([]string{}, r.Env...)
+	keys := make([]string, 0, len(hook.Env))
+	for key := range hook.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+hook.Env[key])
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return utils.RunCommand(ctx, hook.Command, workDir, env, time.Duration(timeout)*time.Second, func(line string) {
+		r.log("  " + line)
+	})
+}
+
+// runAction 执行内置动作，路径限制在安装目录内
+func (r *Runner) runAction(hook *Hook) error {
+	target, err := archive.SafeJoin(r.TargetDir, hook.Path)
+	if err != nil {
+		return err
+	}
+
+	switch hook.Action {
+	case ActionDelete:
+		return os.RemoveAll(target)
+	case ActionMkdir:
+		return os.MkdirAll(target, 0755)
+	case ActionMove:
+		dst, err := archive.SafeJoin(r.TargetDir, hook.To)
+		if err != nil {
+			return err
+		}
+		if !utils.Exists(target) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.Rename(target, dst)
+	}
+	return fmt.Errorf("未知的内置动作: %q", hook.Action)
+}
+
+// log 输出日志
+func (r *Runner) log(message string) {
+	if r.Log != nil {
+		r.Log(message)
+	}
+}
@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs []string
+	runner := &Runner{
+		TargetDir: dir,
+		Env:       []string{"HS_TEST_BASE=base"},
+		Hooks: []Hook{{
+			Event:   EventPostUpdate,
+			Name:    "print",
+			Command: []string{"sh", "-c", `echo "$HS_TEST_BASE $HS_TEST_HOOK"; pwd`},
+			WorkDir: "bin",
+			Env:     map[string]string{"HS_TEST_HOOK": "hook"},
+		}},
+		Log: func(message string) { logs = append(logs, message) },
+	}
+	if err := runner.Run(context.Background(), EventPostUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"执行钩子 [post-update]: print", "  base hook", "  " + filepath.Join(dir, "bin")}
+	if !reflect.DeepEqual(logs, want) {
+		t.Errorf("日志 = %q，期望 %q", logs, want)
+	}
+}
+
+func TestRunCommandFailure(t *testing.T) {
+	cases := []struct {
+		name    string
+		hook    Hook
+		wantErr bool
+	}{
+		{"fail 策略返回错误", Hook{Command: []string{"sh", "-c", "exit 3"}, OnFailure: PolicyFail}, true},
+		{"continue 策略继续", Hook{Command: []string{"sh", "-c", "exit 3"}, OnFailure: PolicyContinue}, false},
+		{"超时", Hook{Command: []string{"sleep", "5"}, Timeout: 1, OnFailure: PolicyFail}, true},
+		{"工作目录位于安装目录外", Hook{Command: []string{"true"}, WorkDir: "..", OnFailure: PolicyFail}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.hook.Event = EventPreUpdate
+			runner := &Runner{TargetDir: t.TempDir(), Hooks: []Hook{c.hook}}
+			if err := runner.Run(context.Background(), EventPreUpdate); (err != nil) != c.wantErr {
+				t.Errorf("Run 错误 = %v，期望出错: %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunCommandCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	runner := &Runner{TargetDir: t.TempDir(), Hooks: []Hook{
+		{Event: EventPreUpdate, Command: []string{"sleep", "5"}, OnFailure: PolicyContinue},
+		{Event: EventPreUpdate, Command: []string{"true"}},
+	}}
+	start := time.Now()
+	err := runner.Run(ctx, EventPreUpdate)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("取消时应忽略 continue 策略并返回 context.Canceled，实际: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("取消后应立即结束命令，耗时 %v", elapsed)
+	}
+}
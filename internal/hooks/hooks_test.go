@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	hooks, err := Parse([]byte(`{"hooks":[{"event":"post-copy","action":"mkdir","path":"data"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].OnFailure != PolicyFail {
+		t.Errorf("钩子 = %+v，默认策略应为 %s", hooks, PolicyFail)
+	}
+
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"无效的 JSON", `{`},
+		{"未知的事件", `{"hooks":[{"event":"pre-copy","command":["echo"]}]}`},
+		{"未知的失败策略", `{"hooks":[{"event":"pre-update","command":["echo"],"onFailure":"retry"}]}`},
+		{"缺少 command 和 action", `{"hooks":[{"event":"pre-update"}]}`},
+		{"同时指定 command 和 action", `{"hooks":[{"event":"pre-update","command":["echo"],"action":"mkdir","path":"a"}]}`},
+		{"未知的内置动作", `{"hooks":[{"event":"pre-update","action":"copy","path":"a"}]}`},
+		{"delete 缺少 path", `{"hooks":[{"event":"pre-update","action":"delete"}]}`},
+		{"move 缺少 to", `{"hooks":[{"event":"pre-update","action":"move","path":"a"}]}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Parse([]byte(c.data)); err == nil {
+				t.Errorf("Parse(%s) 应返回错误", c.data)
+			}
+		})
+	}
+}
+
+func TestRunActions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "old", "a.txt"))
+	writeFile(t, filepath.Join(dir, "cache", "b.txt"))
+
+	runner := &Runner{TargetDir: dir, Hooks: []Hook{
+		{Event: EventPostCopy, Action: ActionMkdir, Path: "data/logs"},
+		{Event: EventPostCopy, Action: ActionMove, Path: "old/a.txt", To: "new/a.txt"},
+		{Event: EventPostCopy, Action: ActionMove, Path: "missing.txt", To: "b.txt"},
+		{Event: EventPostCopy, Action: ActionDelete, Path: "cache"},
+		// 其他阶段的钩子不执行
+		{Event: EventPreUpdate, Action: ActionDelete, Path: "old"},
+	}}
+	if err := runner.Run(context.Background(), EventPostCopy); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"data/logs": true,
+		"new/a.txt": true,
+		"old/a.txt": false,
+		"old":       true,
+		"cache":     false,
+		"b.txt":     false,
+	}
+	for name, want := range cases {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s 存在 = %v，期望 %v", name, err == nil, want)
+		}
+	}
+}
+
+func TestRunActionOutsideTargetDir(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "install")
+	outside := filepath.Join(parent, "outside.txt")
+	writeFile(t, outside)
+
+	cases := []Hook{
+		{Action: ActionDelete, Path: "../outside.txt"},
+		{Action: ActionDelete, Path: "."},
+		{Action: ActionMkdir, Path: "../../a"},
+		{Action: ActionMove, Path: "../outside.txt", To: "a.txt"},
+		{Action: ActionMove, Path: "a.txt", To: "../b.txt"},
+	}
+	for _, hook := range cases {
+		hook.Event = EventPostUpdate
+		runner := &Runner{TargetDir: dir, Hooks: []Hook{hook}}
+		if err := runner.Run(context.Background(), EventPostUpdate); err == nil {
+			t.Errorf("%s %s -> %s 应返回错误", hook.Action, hook.Path, hook.To)
+		}
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("安装目录外的文件不应被修改: %v", err)
+	}
+}
+
+func TestRunPolicy(t *testing.T) {
+	dir := t.TempDir()
+	failing := Hook{Event: EventPostUpdate, Action: ActionDelete, Path: "../a"}
+
+	var logs []string
+	continueHook := failing
+	continueHook.OnFailure = PolicyContinue
+	runner := &Runner{
+		TargetDir: dir,
+		Hooks:     []Hook{continueHook, {Event: EventPostUpdate, Action: ActionMkdir, Path: "after"}},
+		Log:       func(message string) { logs = append(logs, message) },
+	}
+	if err := runner.Run(context.Background(), EventPostUpdate); err != nil {
+		t.Fatalf("continue 策略不应返回错误: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after")); err != nil {
+		t.Errorf("失败后应继续执行后续钩子: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Errorf("日志 = %v", logs)
+	}
+
+	failHook := failing
+	failHook.OnFailure = PolicyFail
+	runner = &Runner{
+		TargetDir: dir,
+		Hooks:     []Hook{failHook, {Event: EventPostUpdate, Action: ActionMkdir, Path: "skipped"}},
+	}
+	if err := runner.Run(context.Background(), EventPostUpdate); err == nil {
+		t.Error("fail 策略应返回错误")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skipped")); err == nil {
+		t.Error("失败后不应执行后续钩子")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := &Runner{TargetDir: dir, Hooks: []Hook{
+		{Event: EventPreUpdate, Action: ActionMkdir, Path: "a", OnFailure: PolicyContinue},
+	}}
+	if err := runner.Run(ctx, EventPreUpdate); !errors.Is(err, context.Canceled) {
+		t.Errorf("应返回 context.Canceled，实际: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); err == nil {
+		t.Error("取消后不应执行钩子")
+	}
+}
+
+// writeFile 创建空的测试文件及其目录
+func writeFile(t *testing.T, filePath string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
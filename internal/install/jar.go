@@ -0,0 +1,65 @@
+package install
+
+import (
+	"archive/zip"
+	"bufio"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+// jarManifestPath jar 中的清单文件
+const jarManifestPath = "META-INF/MANIFEST.MF"
+
+// jarVersionKeys 清单中表示版本的属性，按优先级排列
+var jarVersionKeys = []string{"Implementation-Version", "Bundle-Version", "Specification-Version"}
+
+// jarVersion 读取 lib 目录中主程序 jar 清单里的版本
+func jarVersion(targetDir string) string {
+	jars, _ := filepath.Glob(filepath.Join(targetDir, "lib", config.ProgramName+"*.jar"))
+	sort.Strings(jars)
+	for _, jar := range jars {
+		if version := readJarVersion(jar); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+// readJarVersion 读取 jar 清单中的版本属性
+func readJarVersion(jarPath string) string {
+	reader, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != jarManifestPath {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return ""
+		}
+		defer rc.Close()
+
+		attributes := make(map[string]string)
+		scanner := bufio.NewScanner(rc)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if ok {
+				attributes[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		for _, key := range jarVersionKeys {
+			if attributes[key] != "" {
+				return attributes[key]
+			}
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package install
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// fixedFileInfoSignature VS_FIXEDFILEINFO 结构的签名
+const fixedFileInfoSignature = 0xFEEF04BD
+
+// PEVersion 读取 exe 版本资源中的产品版本
+// 优先使用字符串表中的 ProductVersion（可能带有 -GA 等后缀），没有时使用 VS_FIXEDFILEINFO 中的数字版本
+func PEVersion(exePath string) (string, error) {
+	file, err := pe.Open(exePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	section := file.Section(".rsrc")
+	if section == nil {
+		return "", fmt.Errorf("没有资源段: %s", exePath)
+	}
+	data, err := section.Data()
+	if err != nil {
+		return "", err
+	}
+
+	if version := stringFileInfo(data, "ProductVersion"); version != "" {
+		return version, nil
+	}
+	if version := fixedFileInfo(data); version != "" {
+		return version, nil
+	}
+	return "", fmt.Errorf("没有版本资源: %s", exePath)
+}
+
+// stringFileInfo 在资源数据中查找字符串表的值
+func stringFileInfo(data []byte, key string) string {
+	pattern := encodeUTF16(key + "\x00")
+	index := bytes.Index(data, pattern)
+	if index < 0 {
+		return ""
+	}
+
+	// 值在键之后，按 4 字节对齐
+	offset := index + len(pattern)
+	offset = (offset + 3) &^ 3
+
+	var chars []uint16
+	for ; offset+1 < len(data); offset += 2 {
+		c := binary.LittleEndian.Uint16(data[offset:])
+		if c == 0 {
+			break
+		}
+		chars = append(chars, c)
+	}
+	return strings.TrimSpace(string(utf16.Decode(chars)))
+}
+
+// fixedFileInfo 在资源数据中查找 VS_FIXEDFILEINFO 的产品版本
+func fixedFileInfo(data []byte) string {
+	signature := make([]byte, 4)
+	binary.LittleEndian.PutUint32(signature, fixedFileInfoSignature)
+
+	for start := 0; ; {
+		index := bytes.Index(data[start:], signature)
+		if index < 0 {
+			return ""
+		}
+		offset := start + index
+		start = offset + 4
+		if offset%4 != 0 || offset+24 > len(data) {
+			continue
+		}
+
+		ms := binary.LittleEndian.Uint32(data[offset+16:])
+		ls := binary.LittleEndian.Uint32(data[offset+20:])
+		return fmt.Sprintf("%d.%d.%d", ms>>16, ms&0xFFFF, ls>>16)
+	}
+}
+
+// encodeUTF16 将字符串编码为 UTF-16LE 字节
+func encodeUTF16(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	result := make([]byte, len(chars)*2)
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(result[i*2:], c)
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+const (
+	// VariantJVM JVM 版
+	VariantJVM = "jvm"
+	// VariantNative Native 版
+	VariantNative = "native"
+)
+
+// 版本信息的来源
+const (
+	SourceStamp  = "stamp"
+	SourceJar    = "jar-manifest"
+	SourcePE     = "pe-version"
+	SourceLayout = "layout"
+)
+
+// Stamp 更新完成时写入安装目录的版本记录
+type Stamp struct {
+	Version   string    `json:"version"`
+	Variant   string    `json:"variant"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Info 探测到的安装信息
+type Info struct {
+	Version       string `json:"version,omitempty"`
+	Variant       string `json:"variant"`
+	VersionSource string `json:"versionSource,omitempty"`
+	VariantSource string `json:"variantSource"`
+}
+
+// IsJVM 是否为 JVM 版
+func (i *Info) IsJVM() bool {
+	return i.Variant == VariantJVM
+}
+
+// VariantOf 返回版本类型名称
+func VariantOf(isJvmVersion bool) string {
+	if isJvmVersion {
+		return VariantJVM
+	}
+	return VariantNative
+}
+
+// LoadStamp 读取版本记录，文件不存在时返回 nil
+func LoadStamp(targetDir string) (*Stamp, error) {
+	data, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(config.VersionStampPath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var stamp Stamp
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return nil, fmt.Errorf("解析版本记录失败: %w", err)
+	}
+	return &stamp, nil
+}
+
+// SaveStamp 写入版本记录
+func SaveStamp(targetDir string, stamp *Stamp) error {
+	data, err := json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return err
+	}
+	stampPath := filepath.Join(targetDir, filepath.FromSlash(config.VersionStampPath))
+	return utils.WriteFileAtomic(stampPath, strings.NewReader(string(data)), 0644)
+}
+
+// Probe 探测安装目录的版本和类型
+// 优先使用更新时写入的版本记录，没有时依次读取主程序 jar 的清单和 exe 的版本资源
+func Probe(targetDir string) (*Info, error) {
+	if !utils.IsDirectory(targetDir) {
+		return nil, fmt.Errorf("安装目录不存在: %s", targetDir)
+	}
+
+	info := &Info{}
+	stamp, err := LoadStamp(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	if stamp != nil {
+		info.Version = stamp.Version
+		if stamp.Version != "" {
+			info.VersionSource = SourceStamp
+		}
+		if stamp.Variant == VariantJVM || stamp.Variant == VariantNative {
+			info.Variant = stamp.Variant
+			info.VariantSource = SourceStamp
+		}
+	}
+
+	if info.Variant == "" {
+		info.Variant = VariantOf(utils.DetectJVMVersion(targetDir))
+		info.VariantSource = SourceLayout
+	}
+
+	if info.Version == "" && info.IsJVM() {
+		if version := jarVersion(targetDir); version != "" {
+			info.Version = version
+			info.VersionSource = SourceJar
+		}
+	}
+	if info.Version == "" {
+		if version, err := PEVersion(filepath.Join(targetDir, config.ProgramName+".exe")); err == nil && version != "" {
+			info.Version = version
+			info.VersionSource = SourcePE
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,211 @@
+package install
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+func TestProbe(t *testing.T) {
+	manifest := "Manifest-Version: 1.0\nImplementation-Version: 4.2.0-GA\n"
+
+	cases := []struct {
+		name  string
+		setup func(t *testing.T, dir string)
+		want  Info
+	}{
+		{
+			"空目录视为 Native 版",
+			func(t *testing.T, dir string) {},
+			Info{Variant: VariantNative, VariantSource: SourceLayout},
+		},
+		{
+			"lib 中有 jar 视为 JVM 版并读取清单版本",
+			func(t *testing.T, dir string) {
+				writeJar(t, filepath.Join(dir, "lib", config.ProgramName+"-4.2.0.jar"), manifest)
+				writeJar(t, filepath.Join(dir, "lib", "other.jar"), "Implementation-Version: 9.9.9\n")
+			},
+			Info{Version: "4.2.0-GA", Variant: VariantJVM, VersionSource: SourceJar, VariantSource: SourceLayout},
+		},
+		{
+			"清单没有版本属性",
+			func(t *testing.T, dir string) {
+				writeJar(t, filepath.Join(dir, "lib", config.ProgramName+".jar"), "Manifest-Version: 1.0\n")
+			},
+			Info{Variant: VariantJVM, VariantSource: SourceLayout},
+		},
+		{
+			"版本记录优先",
+			func(t *testing.T, dir string) {
+				writeJar(t, filepath.Join(dir, "lib", config.ProgramName+".jar"), manifest)
+				saveStamp(t, dir, &Stamp{Version: "v4.3.0", Variant: VariantNative})
+			},
+			Info{Version: "v4.3.0", Variant: VariantNative, VersionSource: SourceStamp, VariantSource: SourceStamp},
+		},
+		{
+			"版本记录缺少版本时读取清单",
+			func(t *testing.T, dir string) {
+				writeJar(t, filepath.Join(dir, "lib", config.ProgramName+".jar"), manifest)
+				saveStamp(t, dir, &Stamp{Variant: VariantJVM})
+			},
+			Info{Version: "4.2.0-GA", Variant: VariantJVM, VersionSource: SourceJar, VariantSource: SourceStamp},
+		},
+		{
+			"版本记录的类型无效时按目录结构判断",
+			func(t *testing.T, dir string) {
+				saveStamp(t, dir, &Stamp{Version: "v4.3.0", Variant: "unknown"})
+			},
+			Info{Version: "v4.3.0", Variant: VariantNative, VersionSource: SourceStamp, VariantSource: SourceLayout},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c.setup(t, dir)
+			info, err := Probe(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *info != c.want {
+				t.Errorf("Probe = %+v，期望 %+v", *info, c.want)
+			}
+		})
+	}
+}
+
+func TestProbeErrors(t *testing.T) {
+	if _, err := Probe(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("安装目录不存在时应返回错误")
+	}
+
+	dir := t.TempDir()
+	stampPath := filepath.Join(dir, filepath.FromSlash(config.VersionStampPath))
+	if err := os.MkdirAll(filepath.Dir(stampPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stampPath, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(dir); err == nil {
+		t.Error("版本记录无效时应返回错误")
+	}
+}
+
+func TestStamp(t *testing.T) {
+	dir := t.TempDir()
+	if stamp, err := LoadStamp(dir); err != nil || stamp != nil {
+		t.Fatalf("LoadStamp = %+v, %v，期望 nil", stamp, err)
+	}
+
+	want := &Stamp{Version: "v4.2.0", Variant: VariantJVM, UpdatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	saveStamp(t, dir, want)
+	got, err := LoadStamp(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != want.Version || got.Variant != want.Variant || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("LoadStamp = %+v，期望 %+v", got, want)
+	}
+}
+
+func TestVariantOf(t *testing.T) {
+	if VariantOf(true) != VariantJVM || VariantOf(false) != VariantNative {
+		t.Errorf("VariantOf(true) = %s，VariantOf(false) = %s", VariantOf(true), VariantOf(false))
+	}
+}
+
+func TestStringFileInfo(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"按 4 字节对齐", versionString("ProductVersion", "4.2.0-GA", 0), "4.2.0-GA"},
+		{"键之前有偏移", versionString("ProductVersion", "4.2.0", 2), "4.2.0"},
+		{"去除空白", versionString("ProductVersion", " 4.2.0 ", 0), "4.2.0"},
+		{"没有键", versionString("FileVersion", "4.2.0", 0), ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := stringFileInfo(c.data, "ProductVersion"); got != c.want {
+				t.Errorf("stringFileInfo = %q，期望 %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFixedFileInfo(t *testing.T) {
+	cases := []struct {
+		name   string
+		offset int
+		want   string
+	}{
+		{"对齐的签名", 8, "4.2.1"},
+		{"未对齐的签名", 6, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := make([]byte, c.offset+52)
+			binary.LittleEndian.PutUint32(data[c.offset:], fixedFileInfoSignature)
+			binary.LittleEndian.PutUint32(data[c.offset+16:], 4<<16|2)
+			binary.LittleEndian.PutUint32(data[c.offset+20:], 1<<16|7)
+			if got := fixedFileInfo(data); got != c.want {
+				t.Errorf("fixedFileInfo = %q，期望 %q", got, c.want)
+			}
+		})
+	}
+
+	if got := fixedFileInfo([]byte{0xBD, 0x04, 0xEF, 0xFE}); got != "" {
+		t.Errorf("数据不完整时 fixedFileInfo = %q", got)
+	}
+}
+
+// versionString 构造字符串表中的键值，prefix 为键之前的字节数
+func versionString(key, value string, prefix int) []byte {
+	data := make([]byte, prefix)
+	data = append(data, encodeUTF16(key+"\x00")...)
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	data = append(data, encodeUTF16(value+"\x00")...)
+	return data
+}
+
+// writeJar 创建只包含清单的 jar
+func writeJar(t *testing.T, jarPath, manifest string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(jarPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	w, err := zw.Create(jarManifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// saveStamp 写入版本记录
+func saveStamp(t *testing.T, dir string, stamp *Stamp) {
+	t.Helper()
+	if err := SaveStamp(dir, stamp); err != nil {
+		t.Fatal(err)
+	}
+}
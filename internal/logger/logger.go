@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Options 日志配置
+type Options struct {
+	// Level 输出级别
+	Level slog.Level
+	// File 日志文件路径，为空时只输出到控制台
+	File string
+	// Format 日志文件格式 (text/json)
+	Format string
+	// MaxSize 单个日志文件的最大字节数，超过后轮转
+	MaxSize int64
+	// MaxBackups 保留的历史日志文件数量
+	MaxBackups int
+}
+
+// Sink 接收日志记录的回调，如 GUI 的详细信息
+type Sink func(level slog.Level, message string)
+
+// handler 同时输出到控制台、日志文件和回调
+type handler struct {
+	state *state
+	attrs []slog.Attr
+	group string
+	// steps 按调用顺序记录的 WithAttrs 和 WithGroup，输出到日志文件时依次应用
+	steps []step
+}
+
+// step 一次 WithAttrs（group 为空）或 WithGroup 调用
+type step struct {
+	attrs []slog.Attr
+	group string
+}
+
+// state 所有 handler 共享的输出目标
+type state struct {
+	mu      sync.Mutex
+	level   slog.LevelVar
+	console io.Writer
+	file    slog.Handler
+	closer  io.Closer
+	sink    Sink
+}
+
+var shared = &state{console: os.Stdout}
+
+func init() {
+	slog.SetDefault(slog.New(&handler{state: shared}))
+}
+
+// Init 按配置初始化日志，重复调用时关闭之前打开的日志文件
+func Init(opts Options) error {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	shared.level.Set(opts.Level)
+	if shared.closer != nil {
+		shared.closer.Close()
+		shared.file, shared.closer = nil, nil
+	}
+	if opts.File == "" {
+		return nil
+	}
+
+	writer, err := newRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	handlerOpts := &slog.HandlerOptions{Level: &shared.level}
+	if strings.EqualFold(opts.Format, "json") {
+		shared.file = slog.NewJSONHandler(writer, handlerOpts)
+	} else {
+		shared.file = slog.NewTextHandler(writer, handlerOpts)
+	}
+	shared.closer = writer
+	return nil
+}
+
+// Close 关闭日志文件
+func Close() {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	if shared.closer != nil {
+		shared.closer.Close()
+		shared.file, shared.closer = nil, nil
+	}
+}
+
+// SetConsole 设置控制台输出，标准输出用于其他用途（如 --progress=jsonl）时改为标准错误
+func SetConsole(w io.Writer) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	shared.console = w
+}
+
+// SetSink 设置接收日志的回调，传入 nil 取消
+func SetSink(sink Sink) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	shared.sink = sink
+}
+
+// ParseLevel 解析日志级别 (debug/info/warn/error)
+func ParseLevel(text string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return level, fmt.Errorf("无效的日志级别: %s", text)
+	}
+	return level, nil
+}
+
+// Enabled 判断级别是否需要输出
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.state.level.Level()
+}
+
+// Handle 输出日志记录
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	message := Format(r.Level, r.Message)
+	var extra []string
+	for _, attr := range h.attrs {
+		extra = append(extra, fmt.Sprintf("%s=%v", attr.Key, attr.Value))
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		extra = append(extra, h.attrString(attr))
+		return true
+	})
+	if len(extra) > 0 {
+		message += " " + strings.Join(extra, " ")
+	}
+
+	// 回调和日志文件在锁外调用，回调中再次输出日志时不会死锁
+	h.state.mu.Lock()
+	fmt.Fprintln(h.state.console, message)
+	sink, fileHandler := h.state.sink, h.state.file
+	h.state.mu.Unlock()
+
+	if sink != nil {
+		sink(r.Level, message)
+	}
+	if fileHandler == nil {
+		return nil
+	}
+	for _, s := range h.steps {
+		if s.group != "" {
+			fileHandler = fileHandler.WithGroup(s.group)
+		} else {
+			fileHandler = fileHandler.WithAttrs(s.attrs)
+		}
+	}
+	return fileHandler.Handle(ctx, r.Clone())
+}
+
+// WithAttrs 返回附带属性的 handler，属性名按当前分组添加前缀，之后的分组不影响已附带的属性
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		merged = append(merged, slog.Attr{Key: h.groupKey(attr.Key), Value: attr.Value})
+	}
+	return &handler{state: h.state, attrs: merged, group: h.group, steps: h.appendStep(step{attrs: attrs})}
+}
+
+// WithGroup 返回带分组前缀的 handler
+func (h *handler) WithGroup(name string) slog.Handler {
+	group := name
+	if h.group != "" {
+		group = h.group + "." + name
+	}
+	return &handler{state: h.state, attrs: h.attrs, group: group, steps: h.appendStep(step{group: name})}
+}
+
+// appendStep 复制已有的步骤并追加一步，避免多个派生的 handler 共用底层数组
+func (h *handler) appendStep(s step) []step {
+	return append(append([]step{}, h.steps...), s)
+}
+
+// attrString 将属性格式化为 key=value
+func (h *handler) attrString(attr slog.Attr) string {
+	return fmt.Sprintf("%s=%v", h.groupKey(attr.Key), attr.Value)
+}
+
+// groupKey 为属性名添加当前分组前缀
+func (h *handler) groupKey(key string) string {
+	if h.group == "" {
+		return key
+	}
+	return h.group + "." + key
+}
+
+// Format 按级别为控制台和界面输出添加前缀
+func Format(level slog.Level, message string) string {
+	switch {
+	case level >= slog.LevelError:
+		return "错误: " + message
+	case level >= slog.LevelWarn:
+		return "警告: " + message
+	}
+	return message
+}
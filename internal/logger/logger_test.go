@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "logs", "update.log")
+	r, err := newRotatingFile(logPath, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		if _, err := fmt.Fprintf(r, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每行 7 字节，每写入一行轮转一次，只保留最近 2 个历史文件
+	cases := map[string]string{
+		logPath:        "line 4\n",
+		logPath + ".1": "line 3\n",
+		logPath + ".2": "line 2\n",
+	}
+	for filePath, want := range cases {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s 的内容 = %q，期望 %q", filepath.Base(filePath), data, want)
+		}
+	}
+	if _, err := os.Stat(logPath + ".3"); !os.IsNotExist(err) {
+		t.Errorf("超出保留数量的历史文件应被删除: %v", err)
+	}
+	if _, err := r.Write([]byte("x")); err == nil {
+		t.Error("关闭后写入应返回错误")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		text    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, c := range cases {
+		level, err := ParseLevel(c.text)
+		if (err != nil) != c.wantErr || (!c.wantErr && level != c.want) {
+			t.Errorf("ParseLevel(%q) = %v, %v", c.text, level, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug, "消息"},
+		{slog.LevelInfo, "消息"},
+		{slog.LevelWarn, "警告: 消息"},
+		{slog.LevelError, "错误: 消息"},
+	}
+	for _, c := range cases {
+		if got := Format(c.level, "消息"); got != c.want {
+			t.Errorf("Format(%v) = %q，期望 %q", c.level, got, c.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "update.log")
+	var console bytes.Buffer
+	var sunk []string
+	if err := Init(Options{Level: slog.LevelInfo, File: logPath, Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	SetConsole(&console)
+	SetSink(func(level slog.Level, message string) { sunk = append(sunk, message) })
+	t.Cleanup(func() {
+		SetSink(nil)
+		SetConsole(os.Stdout)
+		Init(Options{Level: slog.LevelInfo})
+	})
+
+	logger := slog.Default().With("file", "a.jar").WithGroup("copy")
+	logger.Debug("不输出")
+	logger.Warn("复制失败", "attempt", 2)
+	Close()
+
+	want := "警告: 复制失败 file=a.jar copy.attempt=2"
+	if got := strings.TrimSpace(console.String()); got != want {
+		t.Errorf("控制台输出 = %q，期望 %q", got, want)
+	}
+	if len(sunk) != 1 || sunk[0] != want {
+		t.Errorf("回调收到 %q，期望 %q", sunk, want)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &record); err != nil {
+		t.Fatalf("日志文件应为一行 JSON: %q", data)
+	}
+	group, _ := record["copy"].(map[string]any)
+	if record["msg"] != "复制失败" || record["file"] != "a.jar" || group["attempt"] != float64(2) {
+		t.Errorf("日志文件记录 = %v", record)
+	}
+}
@@ -0,0 +1,204 @@
+package merge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RenameKey 重命名配置文件中的键，保留原有的值
+// JSON 使用 . 分隔的路径表示嵌套键，INI 通过 section 指定节，properties 的 section 为空
+// 返回 true 表示文件内容发生了变化
+func RenameKey(filePath, section, from, to string) (bool, error) {
+	return editFile(filePath, func(format Format, data []byte) ([]byte, bool, error) {
+		if format == FormatJSON {
+			return editJSON(data, func(root *object) bool {
+				value, ok := getPath(root, from)
+				if !ok {
+					return false
+				}
+				if _, exists := getPath(root, to); exists {
+					// 新键已存在时只删除旧键，保留新键的值
+					return deletePath(root, from)
+				}
+				if renameInPlace(root, from, to) {
+					return true
+				}
+				setPath(root, to, value)
+				deletePath(root, from)
+				return true
+			})
+		}
+
+		doc := parseLines(data, format == FormatINI)
+		for _, entry := range doc.entries(section) {
+			if entry.key != from {
+				continue
+			}
+			if doc.hasKey(section, to) {
+				doc.lines = append(doc.lines[:entry.index], doc.lines[entry.index+1:]...)
+			} else {
+				doc.lines[entry.index] = renameLine(entry.line, from, to)
+			}
+			return joinLines(doc, data), true, nil
+		}
+		return data, false, nil
+	})
+}
+
+// RemoveKey 删除配置文件中的键
+// 返回 true 表示文件内容发生了变化
+func RemoveKey(filePath, section, key string) (bool, error) {
+	return editFile(filePath, func(format Format, data []byte) ([]byte, bool, error) {
+		if format == FormatJSON {
+			return editJSON(data, func(root *object) bool {
+				return deletePath(root, key)
+			})
+		}
+
+		doc := parseLines(data, format == FormatINI)
+		for _, entry := range doc.entries(section) {
+			if entry.key == key {
+				doc.lines = append(doc.lines[:entry.index], doc.lines[entry.index+1:]...)
+				return joinLines(doc, data), true, nil
+			}
+		}
+		return data, false, nil
+	})
+}
+
+// editFile 读取、修改并写回配置文件
+func editFile(filePath string, edit func(format Format, data []byte) ([]byte, bool, error)) (bool, error) {
+	format, err := DetectFormat(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	result, changed, err := edit(format, data)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := writeFile(filePath, result); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// editJSON 解析 JSON 对象，修改后重新输出
+func editJSON(data []byte, edit func(root *object) bool) ([]byte, bool, error) {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("解析配置失败: %w", err)
+	}
+	root, ok := value.(*object)
+	if !ok {
+		return data, false, nil
+	}
+	if !edit(root) {
+		return data, false, nil
+	}
+
+	result, err := encodeJSON(root)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// getPath 按 . 分隔的路径读取值
+func getPath(root *object, keyPath string) (interface{}, bool) {
+	parts := strings.Split(keyPath, ".")
+	current := root
+	for i, part := range parts {
+		value, ok := current.get(part)
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		if current, ok = value.(*object); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// setPath 按 . 分隔的路径写入值，中间对象不存在时自动创建
+func setPath(root *object, keyPath string, value interface{}) {
+	parts := strings.Split(keyPath, ".")
+	current := root
+	for _, part := range parts[:len(parts)-1] {
+		existing, _ := current.get(part)
+		child, ok := existing.(*object)
+		if !ok {
+			child = newObject()
+			current.set(part, child)
+		}
+		current = child
+	}
+	current.set(parts[len(parts)-1], value)
+}
+
+// renameInPlace 新旧键位于同一对象中时直接改名，保持键的位置
+func renameInPlace(root *object, from, to string) bool {
+	fromDir, fromKey := splitPath(from)
+	toDir, toKey := splitPath(to)
+	if fromDir != toDir {
+		return false
+	}
+	parent := root
+	if fromDir != "" {
+		value, ok := getPath(root, fromDir)
+		if parent, ok = value.(*object); !ok {
+			return false
+		}
+	}
+	return parent.rename(fromKey, toKey)
+}
+
+// splitPath 拆分出 . 分隔的路径中最后一级的键
+func splitPath(keyPath string) (string, string) {
+	if i := strings.LastIndex(keyPath, "."); i >= 0 {
+		return keyPath[:i], keyPath[i+1:]
+	}
+	return "", keyPath
+}
+
+// deletePath 按 . 分隔的路径删除值
+func deletePath(root *object, keyPath string) bool {
+	parts := strings.Split(keyPath, ".")
+	current := root
+	for _, part := range parts[:len(parts)-1] {
+		existing, _ := current.get(part)
+		child, ok := existing.(*object)
+		if !ok {
+			return false
+		}
+		current = child
+	}
+	return current.remove(parts[len(parts)-1])
+}
+
+// renameLine 替换键值行中的键，保留缩进、分隔符和值
+func renameLine(line, from, to string) string {
+	idx := strings.Index(line, from)
+	if idx < 0 {
+		return line
+	}
+	return line[:idx] + to + line[idx+len(from):]
+}
+
+// joinLines 按原文件的换行符输出文档
+func joinLines(doc *lineDoc, original []byte) []byte {
+	newline := detectNewline(original)
+	if len(doc.lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(doc.lines, newline) + newline)
+}
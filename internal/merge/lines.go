@@ -0,0 +1,169 @@
+package merge
+
+import (
+	"strings"
+)
+
+// lineEntry 配置文件中的键值行
+type lineEntry struct {
+	section string
+	key     string
+	line    string
+	index   int
+}
+
+// lineDoc 按行保存的 properties/INI 文档
+type lineDoc struct {
+	lines        []string
+	withSections bool
+}
+
+// parseLines 按行解析文档
+func parseLines(data []byte, withSections bool) *lineDoc {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	doc := &lineDoc{withSections: withSections}
+	if text != "" {
+		doc.lines = strings.Split(text, "\n")
+	}
+	return doc
+}
+
+// all 返回所有键值行
+func (d *lineDoc) all() []lineEntry {
+	var result []lineEntry
+	section := ""
+	for i, line := range d.lines {
+		if name, ok := d.sectionName(line); ok {
+			section = name
+			continue
+		}
+		if key, ok := parseKey(line); ok {
+			result = append(result, lineEntry{section: section, key: key, line: line, index: i})
+		}
+	}
+	return result
+}
+
+// entries 返回指定节中的键值行
+func (d *lineDoc) entries(section string) []lineEntry {
+	var result []lineEntry
+	for _, entry := range d.all() {
+		if entry.section == section {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// sectionOrder 返回节的出现顺序，全局节（""）排在最前
+func (d *lineDoc) sectionOrder() []string {
+	order := []string{""}
+	seen := map[string]bool{"": true}
+	for _, line := range d.lines {
+		if name, ok := d.sectionName(line); ok && !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+	return order
+}
+
+// hasKey 判断指定节中是否存在键
+func (d *lineDoc) hasKey(section, key string) bool {
+	for _, entry := range d.entries(section) {
+		if entry.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSection 判断节是否存在
+func (d *lineDoc) hasSection(section string) bool {
+	if section == "" {
+		return true
+	}
+	for _, line := range d.lines {
+		if name, ok := d.sectionName(line); ok && name == section {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionEnd 返回节中最后一个非空行之后的位置
+func (d *lineDoc) sectionEnd(section string) int {
+	current := ""
+	end := -1
+	inSection := section == ""
+	for i, line := range d.lines {
+		if name, ok := d.sectionName(line); ok {
+			current = name
+			inSection = current == section
+			if inSection {
+				end = i + 1
+			}
+			continue
+		}
+		if inSection && strings.TrimSpace(line) != "" {
+			end = i + 1
+		}
+	}
+	if end < 0 {
+		return 0
+	}
+	return end
+}
+
+// insert 在节的末尾插入行，节不存在时追加新节
+func (d *lineDoc) insert(section string, lines []string) {
+	if !d.hasSection(section) {
+		if len(d.lines) > 0 && strings.TrimSpace(d.lines[len(d.lines)-1]) != "" {
+			d.lines = append(d.lines, "")
+		}
+		d.lines = append(d.lines, "["+section+"]")
+		d.lines = append(d.lines, lines...)
+		return
+	}
+
+	end := d.sectionEnd(section)
+	result := make([]string, 0, len(d.lines)+len(lines))
+	result = append(result, d.lines[:end]...)
+	result = append(result, lines...)
+	result = append(result, d.lines[end:]...)
+	d.lines = result
+}
+
+// sectionName 解析节名
+func (d *lineDoc) sectionName(line string) (string, bool) {
+	if !d.withSections {
+		return "", false
+	}
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		return strings.TrimSpace(trimmed[1 : len(trimmed)-1]), true
+	}
+	return "", false
+}
+
+// parseKey 解析键值行中的键，注释和空行返回 false
+func parseKey(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") || strings.HasPrefix(trimmed, ";") {
+		return "", false
+	}
+	idx := strings.IndexAny(trimmed, "=:")
+	if idx <= 0 {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[:idx]), true
+}
+
+// detectNewline 检测文件使用的换行符
+func detectNewline(data []byte) string {
+	if strings.Contains(string(data), "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
@@ -0,0 +1,178 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format 配置文件格式
+type Format string
+
+const (
+	FormatJSON       Format = "json"
+	FormatProperties Format = "properties"
+	FormatINI        Format = "ini"
+)
+
+// DetectFormat 根据扩展名判断配置文件格式
+func DetectFormat(filePath string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".properties":
+		return FormatProperties, nil
+	case ".ini", ".cfg", ".conf":
+		return FormatINI, nil
+	}
+	return "", fmt.Errorf("不支持合并的配置文件格式: %s", filePath)
+}
+
+// MergeFile 将默认配置中新增的键合并到用户配置文件，保留用户已有的值
+// 返回 true 表示文件内容发生了变化
+func MergeFile(userPath string, defaults []byte) (bool, error) {
+	format, err := DetectFormat(userPath)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := os.ReadFile(userPath)
+	if err != nil {
+		return false, err
+	}
+
+	merged, changed, err := Merge(format, user, defaults)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	if err := writeFile(userPath, merged); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Merge 合并配置内容，返回合并后的内容及是否发生变化
+func Merge(format Format, user, defaults []byte) ([]byte, bool, error) {
+	switch format {
+	case FormatJSON:
+		return mergeJSON(user, defaults)
+	case FormatProperties:
+		return mergeINI(user, defaults, false)
+	case FormatINI:
+		return mergeINI(user, defaults, true)
+	}
+	return nil, false, fmt.Errorf("不支持合并的配置文件格式: %s", format)
+}
+
+// mergeJSON 递归地为 JSON 对象补充缺失的键
+func mergeJSON(user, defaults []byte) ([]byte, bool, error) {
+	userValue, err := decodeJSON(user)
+	if err != nil {
+		return nil, false, fmt.Errorf("解析用户配置失败: %w", err)
+	}
+	defaultValue, err := decodeJSON(defaults)
+	if err != nil {
+		return nil, false, fmt.Errorf("解析默认配置失败: %w", err)
+	}
+
+	userObject, ok1 := userValue.(*object)
+	defaultObject, ok2 := defaultValue.(*object)
+	if !ok1 || !ok2 {
+		// 非对象类型的配置无法按键合并，保留用户配置
+		return user, false, nil
+	}
+
+	if !mergeObject(userObject, defaultObject) {
+		return user, false, nil
+	}
+
+	data, err := encodeJSON(userObject)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// mergeObject 为 dst 补充 src 中缺失的键，返回是否发生变化
+// dst 中已有的键保持原顺序，缺失的键按 src 中的顺序追加在末尾
+func mergeObject(dst, src *object) bool {
+	changed := false
+	for _, key := range src.keys {
+		srcValue := src.values[key]
+		dstValue, ok := dst.get(key)
+		if !ok {
+			dst.set(key, srcValue)
+			changed = true
+			continue
+		}
+
+		dstChild, ok1 := dstValue.(*object)
+		srcChild, ok2 := srcValue.(*object)
+		if ok1 && ok2 && mergeObject(dstChild, srcChild) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// decodeJSON 解析 JSON，对象保持键顺序，数字保持原样
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeValue(decoder)
+}
+
+// encodeJSON 以缩进格式输出 JSON，不转义 HTML 字符
+func encodeJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeINI 为 properties/INI 文件补充缺失的键，保留原有的注释和顺序
+func mergeINI(user, defaults []byte, withSections bool) ([]byte, bool, error) {
+	userDoc := parseLines(user, withSections)
+	defaultDoc := parseLines(defaults, withSections)
+
+	changed := false
+	for _, section := range defaultDoc.sectionOrder() {
+		var missing []string
+		for _, entry := range defaultDoc.entries(section) {
+			if !userDoc.hasKey(section, entry.key) {
+				missing = append(missing, entry.line)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		userDoc.insert(section, missing)
+		changed = true
+	}
+
+	if !changed {
+		return user, false, nil
+	}
+	return joinLines(userDoc, user), true, nil
+}
+
+// writeFile 先写入临时文件再替换，避免配置文件处于半写入状态
+func writeFile(filePath string, data []byte) error {
+	tmpPath := filePath + ".merge-tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name        string
+		format      Format
+		user        string
+		defaults    string
+		want        string
+		wantChanged bool
+	}{
+		{
+			"JSON 保留用户的值和键顺序，缺失的键追加在末尾",
+			FormatJSON,
+			`{"z": 1, "a": {"y": "user"}, "m": [1]}`,
+			`{"a": {"x": 0, "y": "default"}, "b": 2, "z": 0}`,
+			"{\n  \"z\": 1,\n  \"a\": {\n    \"y\": \"user\",\n    \"x\": 0\n  },\n  \"m\": [\n    1\n  ],\n  \"b\": 2\n}\n",
+			true,
+		},
+		{
+			"JSON 数字和 HTML 字符保持原样",
+			FormatJSON,
+			`{"big": 12345678901234567890, "url": "a?b=1&c=<d>"}`,
+			`{"f": 1.50}`,
+			"{\n  \"big\": 12345678901234567890,\n  \"url\": \"a?b=1&c=<d>\",\n  \"f\": 1.50\n}\n",
+			true,
+		},
+		{
+			"JSON 没有缺失的键时不修改",
+			FormatJSON,
+			`{"a":1}`,
+			`{"a":2}`,
+			`{"a":1}`,
+			false,
+		},
+		{
+			"JSON 类型不同时保留用户配置",
+			FormatJSON,
+			`{"a": 1}`,
+			`{"a": {"b": 2}}`,
+			`{"a": 1}`,
+			false,
+		},
+		{
+			"properties 保留注释并追加缺失的键",
+			FormatProperties,
+			"# 注释\na=1\n",
+			"a=0\nb: 2\n",
+			"# 注释\na=1\nb: 2\n",
+			true,
+		},
+		{
+			"properties 保留 CRLF 换行符",
+			FormatProperties,
+			"a=1\r\n",
+			"b=2\n",
+			"a=1\r\nb=2\r\n",
+			true,
+		},
+		{
+			"INI 在节的末尾插入缺失的键",
+			FormatINI,
+			"[main]\na=1\n\n[other]\nc=3\n",
+			"[main]\na=0\nb=2\n[other]\nc=0\n",
+			"[main]\na=1\nb=2\n\n[other]\nc=3\n",
+			true,
+		},
+		{
+			"INI 追加缺失的节",
+			FormatINI,
+			"[main]\na=1\n",
+			"[main]\na=0\n[new]\nd=4\n",
+			"[main]\na=1\n\n[new]\nd=4\n",
+			true,
+		},
+		{
+			"INI 同名键位于不同节",
+			FormatINI,
+			"[main]\na=1\n",
+			"[other]\na=2\n",
+			"[main]\na=1\n\n[other]\na=2\n",
+			true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, changed, err := Merge(c.format, []byte(c.user), []byte(c.defaults))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != c.wantChanged {
+				t.Errorf("changed = %v，期望 %v", changed, c.wantChanged)
+			}
+			if string(got) != c.want {
+				t.Errorf("合并结果 = %q，期望 %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMergeInvalidJSON(t *testing.T) {
+	if _, _, err := Merge(FormatJSON, []byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("用户配置无效时应返回错误")
+	}
+	if _, _, err := Merge(FormatJSON, []byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Error("默认配置无效时应返回错误")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]Format{
+		"app.json":       FormatJSON,
+		"APP.JSON":       FormatJSON,
+		"app.properties": FormatProperties,
+		"app.ini":        FormatINI,
+		"app.cfg":        FormatINI,
+		"app.conf":       FormatINI,
+	}
+	for name, want := range cases {
+		if got, err := DetectFormat(name); err != nil || got != want {
+			t.Errorf("DetectFormat(%s) = %s, %v，期望 %s", name, got, err, want)
+		}
+	}
+	if _, err := DetectFormat("app.yml"); err == nil {
+		t.Error("DetectFormat(app.yml) 应返回错误")
+	}
+}
+
+func TestRenameKey(t *testing.T) {
+	cases := []struct {
+		name        string
+		file        string
+		content     string
+		section     string
+		from, to    string
+		want        string
+		wantChanged bool
+	}{
+		{
+			"JSON 同一对象中改名保持位置",
+			"app.json",
+			`{"a": 1, "old": 2, "c": 3}`,
+			"", "old", "new",
+			"{\n  \"a\": 1,\n  \"new\": 2,\n  \"c\": 3\n}\n",
+			true,
+		},
+		{
+			"JSON 嵌套键改名保持位置",
+			"app.json",
+			`{"a": {"old": 1, "b": 2}}`,
+			"", "a.old", "a.new",
+			"{\n  \"a\": {\n    \"new\": 1,\n    \"b\": 2\n  }\n}\n",
+			true,
+		},
+		{
+			"JSON 移动到其他对象",
+			"app.json",
+			`{"old": 1, "b": 2}`,
+			"", "old", "x.y",
+			"{\n  \"b\": 2,\n  \"x\": {\n    \"y\": 1\n  }\n}\n",
+			true,
+		},
+		{
+			"JSON 新键已存在时只删除旧键",
+			"app.json",
+			`{"old": 1, "new": 2}`,
+			"", "old", "new",
+			"{\n  \"new\": 2\n}\n",
+			true,
+		},
+		{
+			"JSON 旧键不存在时不修改",
+			"app.json",
+			`{"a": 1}`,
+			"", "old", "new",
+			`{"a": 1}`,
+			false,
+		},
+		{
+			"properties 保留分隔符和值",
+			"app.properties",
+			"# 注释\n  old : 1\nb=2\n",
+			"", "old", "new",
+			"# 注释\n  new : 1\nb=2\n",
+			true,
+		},
+		{
+			"INI 只修改指定节",
+			"app.ini",
+			"[a]\nold=1\n[b]\nold=2\n",
+			"b", "old", "new",
+			"[a]\nold=1\n[b]\nnew=2\n",
+			true,
+		},
+		{
+			"INI 新键已存在时删除旧键",
+			"app.ini",
+			"[a]\nold=1\nnew=2\n",
+			"a", "old", "new",
+			"[a]\nnew=2\n",
+			true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filePath := writeTestFile(t, c.file, c.content)
+			changed, err := RenameKey(filePath, c.section, c.from, c.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != c.wantChanged {
+				t.Errorf("changed = %v，期望 %v", changed, c.wantChanged)
+			}
+			if got := readTestFile(t, filePath); got != c.want {
+				t.Errorf("文件内容 = %q，期望 %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRemoveKey(t *testing.T) {
+	cases := []struct {
+		name        string
+		file        string
+		content     string
+		section     string
+		key         string
+		want        string
+		wantChanged bool
+	}{
+		{
+			"JSON 删除键并保持其他键的顺序",
+			"app.json",
+			`{"c": 1, "b": 2, "a": 3}`,
+			"", "b",
+			"{\n  \"c\": 1,\n  \"a\": 3\n}\n",
+			true,
+		},
+		{
+			"JSON 删除嵌套键",
+			"app.json",
+			`{"a": {"b": 1, "c": 2}}`,
+			"", "a.b",
+			"{\n  \"a\": {\n    \"c\": 2\n  }\n}\n",
+			true,
+		},
+		{
+			"JSON 路径中间不是对象",
+			"app.json",
+			`{"a": 1}`,
+			"", "a.b",
+			`{"a": 1}`,
+			false,
+		},
+		{
+			"properties 删除键",
+			"app.properties",
+			"a=1\r\nb=2\r\n",
+			"", "a",
+			"b=2\r\n",
+			true,
+		},
+		{
+			"INI 只删除指定节中的键",
+			"app.ini",
+			"[a]\nk=1\n[b]\nk=2\n",
+			"a", "k",
+			"[a]\n[b]\nk=2\n",
+			true,
+		},
+		{
+			"INI 键不存在时不修改",
+			"app.ini",
+			"[a]\nk=1\n",
+			"b", "k",
+			"[a]\nk=1\n",
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filePath := writeTestFile(t, c.file, c.content)
+			changed, err := RemoveKey(filePath, c.section, c.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != c.wantChanged {
+				t.Errorf("changed = %v，期望 %v", changed, c.wantChanged)
+			}
+			if got := readTestFile(t, filePath); got != c.want {
+				t.Errorf("文件内容 = %q，期望 %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	filePath := writeTestFile(t, "app.json", `{"a": 1}`)
+	changed, err := MergeFile(filePath, []byte(`{"b": 2}`))
+	if err != nil || !changed {
+		t.Fatalf("MergeFile = %v, %v", changed, err)
+	}
+	if got, want := readTestFile(t, filePath), "{\n  \"a\": 1,\n  \"b\": 2\n}\n"; got != want {
+		t.Errorf("文件内容 = %q，期望 %q", got, want)
+	}
+	if _, err := os.Stat(filePath + ".merge-tmp"); !os.IsNotExist(err) {
+		t.Errorf("不应残留临时文件: %v", err)
+	}
+}
+
+// writeTestFile 在临时目录中创建配置文件
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+// readTestFile 读取配置文件
+func readTestFile(t *testing.T, filePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,152 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// object 保持键顺序的 JSON 对象，合并和修改后按原顺序输出，新增的键追加在末尾
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newObject 创建空对象
+func newObject() *object {
+	return &object{values: make(map[string]interface{})}
+}
+
+// get 读取键的值
+func (o *object) get(key string) (interface{}, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// set 写入键的值，已存在的键保持原位置
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// remove 删除键，返回键是否存在
+func (o *object) remove(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// rename 将键改名并保持位置，新键已存在或旧键不存在时返回 false
+func (o *object) rename(from, to string) bool {
+	value, ok := o.values[from]
+	if !ok {
+		return false
+	}
+	if _, exists := o.values[to]; exists {
+		return false
+	}
+	for i, k := range o.keys {
+		if k == from {
+			o.keys[i] = to
+			break
+		}
+	}
+	delete(o.values, from)
+	o.values[to] = value
+	return true
+}
+
+// MarshalJSON 按键顺序输出对象
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyData, err := marshalValue(key)
+		if err != nil {
+			return nil, err
+		}
+		valueData, err := marshalValue(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		buf.Write(valueData)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalValue 输出单个值，与 encodeJSON 一样不转义 HTML 字符
+func marshalValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// decodeValue 按出现顺序读取 JSON 值，对象解析为 *object，数字保持原样
+func decodeValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := newObject()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("无效的键: %v", keyToken)
+			}
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(key, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+	return nil, fmt.Errorf("无效的 JSON: %v", delim)
+}
@@ -0,0 +1,261 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/merge"
+	"club.xiaojiawei/hs-script-update/internal/model"
+	"club.xiaojiawei/hs-script-update/internal/utils"
+)
+
+// StepType 迁移步骤类型
+type StepType string
+
+const (
+	// StepMerge 将更新包中同路径的默认配置合并到用户配置
+	StepMerge StepType = "merge"
+	// StepRename 重命名键
+	StepRename StepType = "rename"
+	// StepRemove 删除键
+	StepRemove StepType = "remove"
+	// StepScript 执行脚本
+	StepScript StepType = "script"
+)
+
+// defaultScriptTimeout 脚本默认超时时间（秒）
+const defaultScriptTimeout = 60
+
+// Step 迁移步骤
+type Step struct {
+	Type StepType `json:"type"`
+	// File 配置文件相对于安装目录的路径
+	File string `json:"file,omitempty"`
+	// Section INI 文件中的节
+	Section string `json:"section,omitempty"`
+	// Key 要删除的键（JSON 使用 . 分隔的路径）
+	Key string `json:"key,omitempty"`
+	// From、To 重命名前后的键
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Command 脚本命令及参数，在安装目录中执行
+	Command []string `json:"command,omitempty"`
+	// Timeout 脚本超时时间（秒）
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Migration 一组迁移步骤
+// 当旧版本 >= From（为空时不限制）且旧版本 < To <= 新版本时执行
+type Migration struct {
+	From        string `json:"from,omitempty"`
+	To          string `json:"to"`
+	Description string `json:"description,omitempty"`
+	Steps       []Step `json:"steps"`
+}
+
+// File 迁移文件内容
+type File struct {
+	Migrations []Migration `json:"migrations"`
+}
+
+// Parse 解析迁移文件
+func Parse(data []byte) ([]Migration, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析迁移文件失败: %w", err)
+	}
+
+	for i, migration := range file.Migrations {
+		if migration.To == "" {
+			return nil, fmt.Errorf("第 %d 个迁移缺少 to 版本", i+1)
+		}
+		for j, step := range migration.Steps {
+			if err := step.validate(); err != nil {
+				return nil, fmt.Errorf("迁移 %s 的第 %d 个步骤无效: %w", migration.To, j+1, err)
+			}
+		}
+	}
+	return file.Migrations, nil
+}
+
+// validate 检查步骤参数
+func (s *Step) validate() error {
+	if s.File != "" && !filepath.IsLocal(filepath.FromSlash(s.File)) {
+		return fmt.Errorf("file 必须是安装目录中的相对路径: %s", s.File)
+	}
+	switch s.Type {
+	case StepMerge:
+		if s.File == "" {
+			return fmt.Errorf("缺少 file")
+		}
+	case StepRename:
+		if s.File == "" || s.From == "" || s.To == "" {
+			return fmt.Errorf("需要 file、from 和 to")
+		}
+	case StepRemove:
+		if s.File == "" || s.Key == "" {
+			return fmt.Errorf("需要 file 和 key")
+		}
+	case StepScript:
+		if len(s.Command) == 0 {
+			return fmt.Errorf("缺少 command")
+		}
+	default:
+		return fmt.Errorf("未知的步骤类型: %q", s.Type)
+	}
+	return nil
+}
+
+// Select 选出需要执行的迁移，并按 to 版本升序排列
+// 旧版本未知时只执行可以重复执行的合并步骤
+func Select(migrations []Migration, oldVersion, newVersion string) []Migration {
+	var selected []Migration
+	for _, migration := range migrations {
+		if newVersion != "" && model.CompareVersion(newVersion, migration.To) < 0 {
+			continue
+		}
+
+		if oldVersion == "" {
+			var steps []Step
+			for _, step := range migration.Steps {
+				if step.Type == StepMerge {
+					steps = append(steps, step)
+				}
+			}
+			if len(steps) > 0 {
+				migration.Steps = steps
+				selected = append(selected, migration)
+			}
+			continue
+		}
+
+		if model.CompareVersion(oldVersion, migration.To) >= 0 {
+			continue
+		}
+		if migration.From != "" && model.CompareVersion(oldVersion, migration.From) < 0 {
+			continue
+		}
+		selected = append(selected, migration)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return model.CompareVersion(selected[i].To, selected[j].To) < 0
+	})
+	return selected
+}
+
+// Runner 迁移执行器
+type Runner struct {
+	// TargetDir 安装目录
+	TargetDir string
+	// PackageFile 读取更新包中的文件，文件不存在时返回 nil
+	PackageFile func(relPath string) ([]byte, error)
+	// Env 传递给脚本的环境变量
+	Env []string
+	// Log 输出日志
+	Log func(message string)
+}
+
+// Run 依次执行迁移，遇到错误立即停止
+func (r *Runner) Run(migrations []Migration) error {
+	for _, migration := range migrations {
+		r.log(fmt.Sprintf("执行配置迁移 -> %s %s", migration.To, migration.Description))
+		for i, step := range migration.Steps {
+			if err := r.runStep(step); err != nil {
+				return fmt.Errorf("迁移 %s 的第 %d 个步骤 (%s) 失败: %w", migration.To, i+1, step.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runStep 执行单个步骤，配置文件路径限制在安装目录内
+func (r *Runner) runStep(step Step) error {
+	var filePath string
+	if step.File != "" {
+		var err error
+		if filePath, err = archive.SafeJoin(r.TargetDir, step.File); err != nil {
+			return err
+		}
+	}
+
+	switch step.Type {
+	case StepMerge:
+		defaults, err := r.PackageFile(step.File)
+		if err != nil {
+			return err
+		}
+		if defaults == nil {
+			return fmt.Errorf("更新包中不存在默认配置: %s", step.File)
+		}
+		if !utils.Exists(filePath) {
+			r.log(fmt.Sprintf("  创建配置: %s", step.File))
+			return utils.WriteFileAtomic(filePath, bytes.NewReader(defaults), 0644)
+		}
+		changed, err := merge.MergeFile(filePath, defaults)
+		r.logChange(changed, fmt.Sprintf("  合并配置: %s", step.File))
+		return err
+
+	case StepRename:
+		if !utils.Exists(filePath) {
+			return nil
+		}
+		changed, err := merge.RenameKey(filePath, step.Section, step.From, step.To)
+		r.logChange(changed, fmt.Sprintf("  重命名键: %s %s -> %s", step.File, step.From, step.To))
+		return err
+
+	case StepRemove:
+		if !utils.Exists(filePath) {
+			return nil
+		}
+		changed, err := merge.RemoveKey(filePath, step.Section, step.Key)
+		r.logChange(changed, fmt.Sprintf("  删除键: %s %s", step.File, step.Key))
+		return err
+
+	case StepScript:
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = defaultScriptTimeout
+		}
+		r.log(fmt.Sprintf("  执行脚本: %v", step.Command))
+		return utils.RunCommand(context.Background(), step.Command, r.TargetDir, r.Env, time.Duration(timeout)*time.Second, func(line string) {
+			r.log("    " + line)
+		})
+	}
+	return fmt.Errorf("未知的步骤类型: %q", step.Type)
+}
+
+// Files 返回迁移涉及的配置文件
+func Files(migrations []Migration) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, migration := range migrations {
+		for _, step := range migration.Steps {
+			if step.File != "" && !seen[step.File] {
+				seen[step.File] = true
+				files = append(files, step.File)
+			}
+		}
+	}
+	return files
+}
+
+// log 输出日志
+func (r *Runner) log(message string) {
+	if r.Log != nil {
+		r.Log(message)
+	}
+}
+
+// logChange 文件发生变化时输出日志
+func (r *Runner) logChange(changed bool, message string) {
+	if changed {
+		r.log(message)
+	}
+}
@@ -0,0 +1,194 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	migrations, err := Parse([]byte(`{"migrations":[{"to":"v1.2.0","steps":[{"type":"merge","file":"config/app.json"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Steps[0].File != "config/app.json" {
+		t.Errorf("迁移 = %+v", migrations)
+	}
+
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"无效的 JSON", `{`},
+		{"缺少 to", `{"migrations":[{"steps":[]}]}`},
+		{"未知的步骤类型", `{"migrations":[{"to":"v1","steps":[{"type":"copy","file":"a.json"}]}]}`},
+		{"merge 缺少 file", `{"migrations":[{"to":"v1","steps":[{"type":"merge"}]}]}`},
+		{"rename 缺少 to", `{"migrations":[{"to":"v1","steps":[{"type":"rename","file":"a.json","from":"a"}]}]}`},
+		{"remove 缺少 key", `{"migrations":[{"to":"v1","steps":[{"type":"remove","file":"a.json"}]}]}`},
+		{"script 缺少 command", `{"migrations":[{"to":"v1","steps":[{"type":"script"}]}]}`},
+		{"file 为上级目录", `{"migrations":[{"to":"v1","steps":[{"type":"merge","file":"../a.json"}]}]}`},
+		{"file 为绝对路径", `{"migrations":[{"to":"v1","steps":[{"type":"merge","file":"/etc/a.json"}]}]}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Parse([]byte(c.data)); err == nil {
+				t.Errorf("Parse(%s) 应返回错误", c.data)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	migrations := []Migration{
+		{To: "v1.3.0", Steps: []Step{{Type: StepRename, File: "a.json", From: "a", To: "b"}}},
+		{To: "v1.1.0", Steps: []Step{{Type: StepMerge, File: "a.json"}}},
+		{From: "v1.1.0", To: "v1.2.0", Steps: []Step{{Type: StepRemove, File: "a.json", Key: "c"}}},
+		{To: "v2.0.0", Steps: []Step{{Type: StepMerge, File: "b.json"}}},
+	}
+
+	cases := []struct {
+		name       string
+		oldVersion string
+		newVersion string
+		want       []string
+	}{
+		{"按 to 版本升序", "v1.0.0", "v1.3.0", []string{"v1.1.0", "v1.3.0"}},
+		{"满足 from 时执行", "v1.1.0", "v1.3.0", []string{"v1.2.0", "v1.3.0"}},
+		{"跳过已执行的迁移", "v1.2.0", "v1.3.0", []string{"v1.3.0"}},
+		{"跳过高于新版本的迁移", "v1.0.0", "v1.1.0", []string{"v1.1.0"}},
+		{"新版本未知时不限制", "v1.2.0", "", []string{"v1.3.0", "v2.0.0"}},
+		{"旧版本未知时只执行合并步骤", "", "v2.0.0", []string{"v1.1.0", "v2.0.0"}},
+		{"已是最新版本", "v2.0.0", "v2.0.0", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for _, migration := range Select(migrations, c.oldVersion, c.newVersion) {
+				got = append(got, migration.To)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Select(%q, %q) = %v，期望 %v", c.oldVersion, c.newVersion, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSelectUnknownOldVersionFiltersSteps(t *testing.T) {
+	migrations := []Migration{{To: "v1.1.0", Steps: []Step{
+		{Type: StepRename, File: "a.json", From: "a", To: "b"},
+		{Type: StepMerge, File: "a.json"},
+		{Type: StepScript, Command: []string{"echo"}},
+	}}}
+
+	selected := Select(migrations, "", "v1.1.0")
+	if len(selected) != 1 || len(selected[0].Steps) != 1 || selected[0].Steps[0].Type != StepMerge {
+		t.Errorf("迁移 = %+v", selected)
+	}
+	if len(migrations[0].Steps) != 3 {
+		t.Errorf("不应修改原迁移的步骤: %+v", migrations[0].Steps)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config", "app.json"), `{"name": "user", "old": 1, "temp": true}`)
+	writeFile(t, filepath.Join(dir, "config", "app.properties"), "a=1\n")
+
+	defaults := map[string]string{
+		"config/app.json":       `{"name": "default", "added": 2}`,
+		"config/app.properties": "a=0\nb=2\n",
+		"config/new.ini":        "[main]\nc=3\n",
+	}
+	runner := &Runner{
+		TargetDir: dir,
+		PackageFile: func(relPath string) ([]byte, error) {
+			if data, ok := defaults[relPath]; ok {
+				return []byte(data), nil
+			}
+			return nil, nil
+		},
+	}
+
+	err := runner.Run([]Migration{{To: "v1.1.0", Steps: []Step{
+		{Type: StepMerge, File: "config/app.json"},
+		{Type: StepRename, File: "config/app.json", From: "old", To: "renamed"},
+		{Type: StepRemove, File: "config/app.json", Key: "temp"},
+		{Type: StepMerge, File: "config/app.properties"},
+		{Type: StepMerge, File: "config/new.ini"},
+		// 文件不存在时跳过
+		{Type: StepRename, File: "config/missing.json", From: "a", To: "b"},
+		{Type: StepRemove, File: "config/missing.json", Key: "a"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"config/app.json":       "{\n  \"name\": \"user\",\n  \"renamed\": 1,\n  \"added\": 2\n}\n",
+		"config/app.properties": "a=1\nb=2\n",
+		"config/new.ini":        "[main]\nc=3\n",
+	}
+	for file, want := range cases {
+		if got := readFile(t, filepath.Join(dir, file)); got != want {
+			t.Errorf("%s 的内容 = %q，期望 %q", file, got, want)
+		}
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	dir := t.TempDir()
+	runner := &Runner{
+		TargetDir: dir,
+		PackageFile: func(relPath string) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	cases := []struct {
+		name string
+		step Step
+	}{
+		{"默认配置不存在", Step{Type: StepMerge, File: "config/app.json"}},
+		{"路径位于安装目录外", Step{Type: StepMerge, File: "../app.json"}},
+		{"删除安装目录外的键", Step{Type: StepRemove, File: "../../app.json", Key: "a"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := runner.Run([]Migration{{To: "v1.1.0", Steps: []Step{c.step}}}); err == nil {
+				t.Error("应返回错误")
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	files := Files([]Migration{
+		{Steps: []Step{{File: "a.json"}, {Command: []string{"echo"}}}},
+		{Steps: []Step{{File: "b.ini"}, {File: "a.json"}}},
+	})
+	if want := []string{"a.json", "b.ini"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v，期望 %v", files, want)
+	}
+}
+
+// writeFile 创建测试文件及其目录
+func writeFile(t *testing.T, filePath, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile 读取测试文件
+func readFile(t *testing.T, filePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
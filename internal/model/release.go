@@ -2,25 +2,66 @@ package model
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/config"
 )
 
 // Release 版本发布信息
 type Release struct {
-	TagName      string `json:"tag_name"`
-	IsPreRelease bool   `json:"prerelease"`
-	Name         string `json:"name,omitempty"`
-	Body         string `json:"body,omitempty"`
+	TagName      string  `json:"tag_name"`
+	IsPreRelease bool    `json:"prerelease"`
+	Name         string  `json:"name,omitempty"`
+	Body         string  `json:"body,omitempty"`
+	Assets       []Asset `json:"assets,omitempty"`
+}
+
+// Asset 发布附件
+type Asset struct {
+	Name string `json:"name"`
 }
 
-// FileName 返回文件名
+// FileName 返回更新包文件名
+// 发布附件中有该版本支持格式的更新包时使用附件的文件名（优先使用默认扩展名），否则使用默认扩展名
 func (r *Release) FileName(isNative bool) string {
+	prefix, ext := fmt.Sprintf("hs-script_%s", r.TagName), config.JVMPackageExt
 	if isNative {
-		return fmt.Sprintf("hs-script-native_%s.zip", r.TagName)
+		prefix, ext = fmt.Sprintf("hs-script-native_%s", r.TagName), config.NativePackageExt
+	}
+
+	var found string
+	for _, asset := range r.Assets {
+		rest, ok := strings.CutPrefix(asset.Name, prefix)
+		if !ok || !strings.HasPrefix(rest, ".") {
+			continue
+		}
+		if strings.EqualFold(rest, ext) {
+			return asset.Name
+		}
+		if _, err := archive.DetectFormatByName(rest); err == nil && found == "" {
+			found = asset.Name
+		}
+	}
+	if found != "" {
+		return found
+	}
+	return prefix + ext
+}
+
+// packageVersionPattern 从更新包文件名中提取版本号，如 hs-script_v4.13.0-GA.zip
+var packageVersionPattern = regexp.MustCompile(`_(v?\d+(?:\.\d+)*(?:-[A-Za-z0-9]+)?)\.(?:zip|tar|tgz|tzst)`)
+
+// VersionFromFileName 从更新包文件名中提取版本号，无法识别时返回空字符串
+func VersionFromFileName(fileName string) string {
+	match := packageVersionPattern.FindStringSubmatch(fileName)
+	if match == nil {
+		return ""
 	}
-	return fmt.Sprintf("hs-script_%s.zip", r.TagName)
+	return match[1]
 }
 
 // CompareTo 比较版本大小
@@ -39,7 +80,7 @@ func CompareVersion(version1, version2 string) int {
 	match2 := regex.FindString(version2)
 
 	if match1 == "" || match2 == "" {
-		fmt.Printf("版本号有误，version1：%s，version2：%s\n", version1, version2)
+		slog.Warn(fmt.Sprintf("版本号有误，version1：%s，version2：%s", version1, version2))
 		return 0
 	}
 
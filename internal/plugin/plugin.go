@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+	"club.xiaojiawei/hs-script-update/internal/model"
+)
+
+// Descriptor 插件描述文件内容
+type Descriptor struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Requires 需要的主程序版本范围，如 ">=4.13.0 <5.0.0"，为空表示不限制
+	Requires string `json:"requires,omitempty"`
+	// Repository 插件发布仓库，为空时不支持独立更新
+	Repository *Source `json:"repository,omitempty"`
+}
+
+// Source 插件发布仓库
+type Source struct {
+	// Type 仓库源 (github/gitee)
+	Type    string `json:"type"`
+	User    string `json:"user"`
+	Project string `json:"project"`
+	// Asset 发布附件文件名，支持 {id}、{version} 占位符，默认 {id}_{version}.zip
+	Asset string `json:"asset,omitempty"`
+	// Descriptor 仓库中描述文件的路径，默认为 plugin.json
+	Descriptor string `json:"descriptor,omitempty"`
+}
+
+// AssetName 返回指定版本的发布附件文件名
+func (s *Source) AssetName(id, version string) string {
+	asset := s.Asset
+	if asset == "" {
+		asset = "{id}_{version}.zip"
+	}
+	return strings.NewReplacer("{id}", id, "{version}", version).Replace(asset)
+}
+
+// DescriptorPath 返回仓库中描述文件的路径
+func (s *Source) DescriptorPath() string {
+	if s.Descriptor == "" {
+		return config.PluginDescriptorName
+	}
+	return s.Descriptor
+}
+
+// Plugin 插件目录
+type Plugin struct {
+	// DirName 插件目录名
+	DirName string
+	// Path 插件目录的完整路径
+	Path string
+	// Descriptor 插件描述，读取失败时为 nil
+	Descriptor *Descriptor
+	// Err 读取描述文件的错误，缺少描述文件时为 nil
+	Err error
+}
+
+// ID 返回插件 ID，没有描述文件时使用目录名
+func (p *Plugin) ID() string {
+	if p.Descriptor != nil && p.Descriptor.ID != "" {
+		return p.Descriptor.ID
+	}
+	return p.DirName
+}
+
+// LoadDescriptor 读取插件目录中的描述文件，文件不存在时返回 nil
+func LoadDescriptor(pluginPath string) (*Descriptor, error) {
+	data, err := os.ReadFile(filepath.Join(pluginPath, config.PluginDescriptorName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ParseDescriptor(data)
+}
+
+// ParseDescriptor 解析插件描述
+func ParseDescriptor(data []byte) (*Descriptor, error) {
+	var descriptor Descriptor
+	if err := json.Unmarshal(data, &descriptor); err != nil {
+		return nil, fmt.Errorf("解析插件描述失败: %w", err)
+	}
+	if descriptor.ID == "" {
+		return nil, fmt.Errorf("插件描述缺少 id")
+	}
+	if _, err := ParseRange(descriptor.Requires); err != nil {
+		return nil, err
+	}
+	if source := descriptor.Repository; source != nil && (source.Type == "" || source.User == "" || source.Project == "") {
+		return nil, fmt.Errorf("插件 %s 的 repository 需要 type、user 和 project", descriptor.ID)
+	}
+	return &descriptor, nil
+}
+
+// Scan 扫描插件目录下的所有插件，按目录名排序
+func Scan(pluginDir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var plugins []*Plugin
+	for _, entry := range entries {
+		// 以 . 开头的是更新插件时使用的临时目录
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		pluginPath := filepath.Join(pluginDir, entry.Name())
+		descriptor, err := LoadDescriptor(pluginPath)
+		plugins = append(plugins, &Plugin{
+			DirName:    entry.Name(),
+			Path:       pluginPath,
+			Descriptor: descriptor,
+			Err:        err,
+		})
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].DirName < plugins[j].DirName
+	})
+	return plugins, nil
+}
+
+// Constraint 单个版本约束
+type Constraint struct {
+	Op      string
+	Version string
+}
+
+// Range 版本范围，所有约束都满足时匹配
+type Range []Constraint
+
+// ParseRange 解析版本范围，约束之间用空格或逗号分隔
+// 支持 >=、>、<=、<、= 运算符，省略运算符时视为 =
+func ParseRange(text string) (Range, error) {
+	var result Range
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for _, field := range fields {
+		op := "="
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				field = strings.TrimPrefix(field, candidate)
+				break
+			}
+		}
+		if field == "" || !strings.ContainsAny(field, "0123456789") {
+			return nil, fmt.Errorf("无效的版本范围: %q", text)
+		}
+		result = append(result, Constraint{Op: op, Version: field})
+	}
+	return result, nil
+}
+
+// Contains 判断版本是否在范围内
+func (r Range) Contains(version string) bool {
+	for _, constraint := range r {
+		cmp := model.CompareVersion(version, constraint.Version)
+		var ok bool
+		switch constraint.Op {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Quarantine 将插件移动到禁用目录，并写入禁用原因
+// 禁用目录中已存在同名插件时追加时间戳，返回移动后的路径
+func Quarantine(p *Plugin, disabledDir, reason string) (string, error) {
+	if err := os.MkdirAll(disabledDir, 0755); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(disabledDir, p.DirName)
+	if _, err := os.Stat(dst); err == nil {
+		dst = fmt.Sprintf("%s-%s", dst, time.Now().Format("20060102-150405"))
+	}
+	if err := os.Rename(p.Path, dst); err != nil {
+		return "", fmt.Errorf("移动插件 %s 失败: %w", p.DirName, err)
+	}
+
+	note := fmt.Sprintf("禁用时间: %s\n原路径: %s\n原因: %s\n确认兼容后可将此目录移回插件目录（并删除本文件）\n",
+		time.Now().Format("2006-01-02 15:04:05"), p.Path, reason)
+	if err := os.WriteFile(filepath.Join(dst, config.PluginDisabledNoteName), []byte(note), 0644); err != nil {
+		return dst, fmt.Errorf("写入禁用说明失败: %w", err)
+	}
+	return dst, nil
+}
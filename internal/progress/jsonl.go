@@ -0,0 +1,192 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JSON 行事件类型
+const (
+	// TypePhaseStart 进入新阶段
+	TypePhaseStart = "phase-start"
+	// TypePhaseEnd 阶段结束
+	TypePhaseEnd = "phase-end"
+	// TypeProgress 进度
+	TypeProgress = "progress"
+	// TypeFile 阶段中的一个文件处理完成
+	TypeFile = "file"
+	// TypeStatus 状态文本
+	TypeStatus = "status"
+	// TypeLog 日志，与输出到控制台的内容相同
+	TypeLog = "log"
+	// TypeWarning 警告
+	TypeWarning = "warning"
+	// TypePromptRequired 更新器弹窗询问用户，等待用户操作
+	TypePromptRequired = "prompt-required"
+	// TypeSuccess 更新成功
+	TypeSuccess = "success"
+	// TypeError 更新失败，code 为错误码
+	TypeError = "error"
+	// TypeSummary 更新结束后的结果摘要（--json）
+	TypeSummary = "summary"
+)
+
+// record 一行 JSON 事件
+type record struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Phase Phase     `json:"phase,omitempty"`
+	*progressRecord
+	// Total 阶段需要处理的字节数，仅用于 phase-start
+	Total    int64  `json:"total,omitempty"`
+	File     string `json:"file,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Question string `json:"question,omitempty"`
+	Summary  any    `json:"summary,omitempty"`
+}
+
+// progressRecord 进度事件的字段，为 0 时也输出
+type progressRecord struct {
+	BytesDone      int64   `json:"bytesDone"`
+	BytesTotal     int64   `json:"bytesTotal"`
+	Percent        float64 `json:"percent"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+	ETA            int64   `json:"etaMs"`
+}
+
+// JSONLWriter 将更新过程以每行一个 JSON 对象的形式输出，供嵌入更新器的宿主程序自行显示界面
+// 实现 core.ProgressCallback 和 core.EventProgress
+type JSONLWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+	stdout bool
+}
+
+// NewJSONLWriter 创建 JSON 行输出：target 为空时输出到标准输出，为数字时输出到该文件描述符
+// （Windows 下为继承的句柄），否则写入该文件
+func NewJSONLWriter(target string) (*JSONLWriter, error) {
+	if target == "" {
+		return &JSONLWriter{out: os.Stdout, stdout: true}, nil
+	}
+	if fd, err := strconv.Atoi(target); err == nil {
+		if fd == 1 {
+			return &JSONLWriter{out: os.Stdout, stdout: true}, nil
+		}
+		if fd < 0 {
+			return nil, fmt.Errorf("无效的文件描述符: %s", target)
+		}
+		file := os.NewFile(uintptr(fd), "fd"+target)
+		if file == nil {
+			return nil, fmt.Errorf("无效的文件描述符: %s", target)
+		}
+		return &JSONLWriter{out: file, closer: file}, nil
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return nil, fmt.Errorf("创建进度输出文件失败: %w", err)
+	}
+	return &JSONLWriter{out: file, closer: file}, nil
+}
+
+// Stdout 是否输出到标准输出
+func (w *JSONLWriter) Stdout() bool {
+	return w.stdout
+}
+
+// Close 关闭输出的文件或文件描述符
+func (w *JSONLWriter) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// write 输出一行事件，写入失败时忽略，不影响更新
+func (w *JSONLWriter) write(r record) {
+	r.Time = time.Now()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.out.Write(append(data, '\n'))
+}
+
+// SetStatus 输出状态文本
+func (w *JSONLWriter) SetStatus(status string) {
+	w.write(record{Type: TypeStatus, Message: status})
+}
+
+// SetProgress 输出进度，各字段为 0 时也输出
+func (w *JSONLWriter) SetProgress(event Event) {
+	w.write(record{
+		Type:  TypeProgress,
+		Phase: event.Phase,
+		File:  event.CurrentFile,
+		progressRecord: &progressRecord{
+			BytesDone:      event.BytesDone,
+			BytesTotal:     event.BytesTotal,
+			Percent:        event.Percent,
+			BytesPerSecond: event.BytesPerSecond,
+			ETA:            event.ETA.Milliseconds(),
+		},
+	})
+}
+
+// AppendDetail 输出日志，警告还会单独通过 ShowWarning 输出
+func (w *JSONLWriter) AppendDetail(detail string) {
+	w.write(record{Type: TypeLog, Message: detail})
+}
+
+// ShowError 不输出事件：更新器在返回错误前总会调用 Failed，由其输出带错误码的 error 事件，
+// 这里输出会使宿主程序收到两次失败
+func (w *JSONLWriter) ShowError(message string) {}
+
+// ShowSuccess 输出更新成功
+func (w *JSONLWriter) ShowSuccess(message string) {
+	w.write(record{Type: TypeSuccess, Message: message})
+}
+
+// PhaseStarted 输出阶段开始，total 为该阶段需要处理的字节数
+func (w *JSONLWriter) PhaseStarted(phase Phase, total int64) {
+	w.write(record{Type: TypePhaseStart, Phase: phase, Total: total})
+}
+
+// PhaseFinished 输出阶段结束
+func (w *JSONLWriter) PhaseFinished(phase Phase) {
+	w.write(record{Type: TypePhaseEnd, Phase: phase})
+}
+
+// FileDone 输出阶段中的一个文件处理完成
+func (w *JSONLWriter) FileDone(phase Phase, file string) {
+	w.write(record{Type: TypeFile, Phase: phase, File: file})
+}
+
+// ShowWarning 输出警告
+func (w *JSONLWriter) ShowWarning(message string) {
+	w.write(record{Type: TypeWarning, Message: message})
+}
+
+// PromptRequired 输出更新器即将弹窗询问用户，宿主程序可提示用户切换到弹窗
+func (w *JSONLWriter) PromptRequired(file, question string) {
+	w.write(record{Type: TypePromptRequired, File: file, Question: question})
+}
+
+// Failed 输出更新失败及错误码
+func (w *JSONLWriter) Failed(code, message string) {
+	w.write(record{Type: TypeError, Code: code, Message: message})
+}
+
+// Summary 输出结果摘要，使 --json 与 JSON 行输出同时使用时不破坏输出格式
+func (w *JSONLWriter) Summary(summary any) {
+	w.write(record{Type: TypeSummary, Summary: summary})
+}
@@ -0,0 +1,101 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONLWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	w, err := NewJSONLWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.PhaseStarted(PhaseCopy, 100)
+	w.SetProgress(Event{Phase: PhaseCopy, BytesTotal: 100, Percent: 5, ETA: 1500 * time.Millisecond})
+	w.FileDone(PhaseCopy, "lib/app.jar")
+	w.PromptRequired("lib/app.jar", "是否重试？")
+	w.Failed("canceled", "更新已取消")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	wantTypes := []string{TypePhaseStart, TypeProgress, TypeFile, TypePromptRequired, TypeError}
+	if len(lines) != len(wantTypes) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if lines[i]["type"] != want {
+			t.Errorf("line %d type = %v, want %s", i, lines[i]["type"], want)
+		}
+	}
+	// 进度字段为 0 时也输出
+	if done, ok := lines[1]["bytesDone"]; !ok || done != 0.0 {
+		t.Errorf("bytesDone = %v, want 0", done)
+	}
+	if lines[1]["etaMs"] != 1500.0 {
+		t.Errorf("etaMs = %v, want 1500", lines[1]["etaMs"])
+	}
+	if lines[4]["code"] != "canceled" {
+		t.Errorf("code = %v, want canceled", lines[4]["code"])
+	}
+}
+
+func TestJSONLWriterSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	w, err := NewJSONLWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.AppendDetail("警告: 删除备份失败")
+	w.ShowError("更新失败")
+	w.Summary(map[string]any{"success": true})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	// ShowError 不输出，失败由 Failed 输出
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0]["type"] != TypeLog || lines[0]["message"] != "警告: 删除备份失败" {
+		t.Errorf("log line = %v", lines[0])
+	}
+	summary, ok := lines[1]["summary"].(map[string]any)
+	if lines[1]["type"] != TypeSummary || !ok || summary["success"] != true {
+		t.Errorf("summary line = %v", lines[1])
+	}
+}
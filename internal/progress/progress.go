@@ -0,0 +1,293 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase 更新阶段
+type Phase string
+
+const (
+	// PhasePrepare 等待主程序退出、检查更新包和生成更新计划
+	PhasePrepare Phase = "prepare"
+	// PhaseDownload 下载更新包中不包含的组件
+	PhaseDownload Phase = "download"
+	// PhaseBackup 备份将被覆盖的文件
+	PhaseBackup Phase = "backup"
+	// PhaseExtract 完整解压更新包到临时目录
+	PhaseExtract Phase = "extract"
+	// PhaseCopy 写入安装目录
+	PhaseCopy Phase = "copy"
+	// PhaseFinalize 配置迁移、校验安装结果和启动主程序
+	PhaseFinalize Phase = "finalize"
+)
+
+// phaseNames 各阶段显示的名称
+var phaseNames = map[Phase]string{
+	PhasePrepare:  "准备更新",
+	PhaseDownload: "下载组件",
+	PhaseBackup:   "备份文件",
+	PhaseExtract:  "解压更新包",
+	PhaseCopy:     "写入文件",
+	PhaseFinalize: "完成更新",
+}
+
+// Name 返回阶段显示的名称
+func (p Phase) Name() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return string(p)
+}
+
+// 准备和收尾阶段固定占总体进度的开头和结尾，其余部分按权重分给计划中的阶段
+const (
+	prepareShare  = 5
+	finalizeShare = 5
+)
+
+// weights 各阶段的权重
+var weights = map[Phase]float64{
+	PhaseDownload: 25,
+	PhaseBackup:   10,
+	PhaseExtract:  25,
+	PhaseCopy:     40,
+}
+
+// ReportInterval 两次进度事件之间的最短间隔，切换阶段和完成时立即报告
+const ReportInterval = 100 * time.Millisecond
+
+// Event 进度事件
+type Event struct {
+	// Phase 当前阶段
+	Phase Phase
+	// BytesDone 当前阶段已处理的字节数
+	BytesDone int64
+	// BytesTotal 当前阶段需要处理的字节数，未知时为 0
+	BytesTotal int64
+	// CurrentFile 正在处理的文件
+	CurrentFile string
+	// Percent 按阶段权重折算的总体进度 (0-100)
+	Percent float64
+	// BytesPerSecond 当前阶段的平均吞吐量
+	BytesPerSecond float64
+	// ETA 当前阶段的预计剩余时间，无法估计时为 0
+	ETA time.Duration
+}
+
+// Observer 接收阶段开始、结束和文件处理完成的通知，不受 ReportInterval 限制
+type Observer interface {
+	// PhaseStarted 进入新阶段，total 为该阶段需要处理的字节数，未知时为 0
+	PhaseStarted(phase Phase, total int64)
+	// PhaseFinished 阶段结束，失败或取消时不调用
+	PhaseFinished(phase Phase)
+	// FileDone 阶段中的一个文件处理完成
+	FileDone(phase Phase, file string)
+}
+
+// span 阶段在总体进度中的区间
+type span struct {
+	start, size float64
+}
+
+// Tracker 按各阶段实际处理的字节数计算总体进度、吞吐量和剩余时间
+type Tracker struct {
+	mu       sync.Mutex
+	report   func(Event)
+	observer Observer
+	spans    map[Phase]span
+	// active 当前阶段已开始且未结束，用于在切换阶段时通知上一阶段结束
+	active  bool
+	phase   Phase
+	started time.Time
+	done    int64
+	total   int64
+	// fraction 没有字节数的阶段按步骤报告的完成比例
+	fraction   float64
+	file       string
+	percent    float64
+	lastReport time.Time
+	now        func() time.Time
+}
+
+// NewTracker 创建进度跟踪器，report 接收进度事件
+func NewTracker(report func(Event)) *Tracker {
+	t := &Tracker{report: report, phase: PhasePrepare, now: time.Now}
+	t.Plan(PhaseBackup, PhaseCopy)
+	return t
+}
+
+// SetObserver 设置接收阶段和文件通知的观察者
+func (t *Tracker) SetObserver(observer Observer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.observer = observer
+}
+
+// Plan 设置本次更新会经过的阶段（准备和收尾阶段除外），未列出的阶段不占进度
+func (t *Tracker) Plan(phases ...Phase) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spans = map[Phase]span{
+		PhasePrepare:  {start: 0, size: prepareShare},
+		PhaseFinalize: {start: 100 - finalizeShare, size: finalizeShare},
+	}
+	var sum float64
+	for _, phase := range phases {
+		sum += weights[phase]
+	}
+	start := float64(prepareShare)
+	for _, phase := range phases {
+		if sum == 0 {
+			break
+		}
+		size := (100 - prepareShare - finalizeShare) * weights[phase] / sum
+		t.spans[phase] = span{start: start, size: size}
+		start += size
+	}
+}
+
+// Start 进入新阶段，total 为该阶段需要处理的字节数，未知时为 0
+func (t *Tracker) Start(phase Phase, total int64) {
+	t.finishPhase()
+
+	t.mu.Lock()
+	t.active = true
+	t.phase = phase
+	t.started = t.now()
+	t.done = 0
+	t.total = total
+	t.fraction = 0
+	observer := t.observer
+	event := t.event()
+	t.mu.Unlock()
+
+	if observer != nil {
+		observer.PhaseStarted(phase, total)
+	}
+	t.emit(event)
+}
+
+// Add 增加当前阶段已处理的字节数，可直接作为 utils.ProgressFunc 使用
+// 文件发生变化时视为上一个文件已处理完成
+func (t *Tracker) Add(file string, n int64) {
+	t.setFile(file)
+	t.mu.Lock()
+	t.done += n
+	t.mu.Unlock()
+
+	t.throttled()
+}
+
+// Set 设置当前阶段已处理和需要处理的字节数，用于下载等总量在开始后才知道的阶段
+func (t *Tracker) Set(file string, done, total int64) {
+	t.setFile(file)
+	t.mu.Lock()
+	t.done = done
+	t.total = total
+	t.mu.Unlock()
+
+	t.throttled()
+}
+
+// setFile 切换正在处理的文件，通知上一个文件已处理完成
+func (t *Tracker) setFile(file string) {
+	t.mu.Lock()
+	previous, phase, observer := t.file, t.phase, t.observer
+	t.file = file
+	t.mu.Unlock()
+
+	if observer != nil && previous != "" && previous != file {
+		observer.FileDone(phase, previous)
+	}
+}
+
+// finishPhase 通知最后一个文件和当前阶段已完成
+func (t *Tracker) finishPhase() {
+	t.setFile("")
+
+	t.mu.Lock()
+	active, phase, observer := t.active, t.phase, t.observer
+	t.active = false
+	t.mu.Unlock()
+
+	if observer != nil && active {
+		observer.PhaseFinished(phase)
+	}
+}
+
+// Step 设置没有字节数的阶段的完成比例 (0-1)
+func (t *Tracker) Step(fraction float64) {
+	t.mu.Lock()
+	t.fraction = fraction
+	event := t.event()
+	t.mu.Unlock()
+
+	t.emit(event)
+}
+
+// Complete 更新完成，总体进度为 100%
+func (t *Tracker) Complete() {
+	t.finishPhase()
+
+	t.mu.Lock()
+	t.phase = PhaseFinalize
+	t.fraction = 1
+	t.total = 0
+	t.percent = 100
+	event := t.event()
+	t.mu.Unlock()
+
+	t.emit(event)
+}
+
+// throttled 距上次报告超过 ReportInterval 或当前阶段已完成时报告进度
+func (t *Tracker) throttled() {
+	t.mu.Lock()
+	finished := t.total > 0 && t.done >= t.total
+	if !finished && t.now().Sub(t.lastReport) < ReportInterval {
+		t.mu.Unlock()
+		return
+	}
+	event := t.event()
+	t.mu.Unlock()
+
+	t.emit(event)
+}
+
+// event 生成当前的进度事件，调用方需持有锁
+func (t *Tracker) event() Event {
+	fraction := t.fraction
+	if t.total > 0 {
+		fraction = min(float64(t.done)/float64(t.total), 1)
+	}
+	// 总体进度不回退，未计划的阶段保持当前进度
+	if s, ok := t.spans[t.phase]; ok {
+		t.percent = max(t.percent, s.start+s.size*fraction)
+	}
+
+	event := Event{
+		Phase:       t.phase,
+		BytesDone:   t.done,
+		BytesTotal:  t.total,
+		CurrentFile: t.file,
+		Percent:     t.percent,
+	}
+	if elapsed := t.now().Sub(t.started); t.done > 0 && elapsed > 0 {
+		event.BytesPerSecond = float64(t.done) / elapsed.Seconds()
+		if t.total > t.done {
+			event.ETA = time.Duration(float64(t.total-t.done) / event.BytesPerSecond * float64(time.Second))
+		}
+	}
+	t.lastReport = t.now()
+	return event
+}
+
+// emit 在不持有锁时调用 report
+func (t *Tracker) emit(event Event) {
+	if t.report != nil {
+		t.report(event)
+	}
+}
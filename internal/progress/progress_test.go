@@ -0,0 +1,125 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestTracker 创建使用可控时钟的跟踪器，返回收到的事件
+func newTestTracker() (*Tracker, *[]Event, *time.Time) {
+	var events []Event
+	now := time.Unix(0, 0)
+	t := NewTracker(func(e Event) { events = append(events, e) })
+	t.now = func() time.Time { return now }
+	return t, &events, &now
+}
+
+func TestTrackerWeightsPhases(t *testing.T) {
+	tracker, events, now := newTestTracker()
+	tracker.Plan(PhaseBackup, PhaseCopy)
+
+	tracker.Start(PhaseBackup, 100)
+	tracker.Add("a", 100)
+	// 备份占中间部分的 10/50
+	if got := (*events)[len(*events)-1].Percent; got != 5+18 {
+		t.Fatalf("percent after backup = %v, want 23", got)
+	}
+
+	tracker.Start(PhaseCopy, 1000)
+	*now = now.Add(2 * time.Second)
+	tracker.Add("lib/app.jar", 500)
+	last := (*events)[len(*events)-1]
+	if last.Percent != 23+36 {
+		t.Errorf("percent = %v, want 59", last.Percent)
+	}
+	if last.BytesPerSecond != 250 {
+		t.Errorf("bytes per second = %v, want 250", last.BytesPerSecond)
+	}
+	if last.ETA != 2*time.Second {
+		t.Errorf("eta = %v, want 2s", last.ETA)
+	}
+	if last.CurrentFile != "lib/app.jar" {
+		t.Errorf("current file = %q", last.CurrentFile)
+	}
+
+	tracker.Complete()
+	if got := (*events)[len(*events)-1].Percent; got != 100 {
+		t.Errorf("percent after complete = %v, want 100", got)
+	}
+}
+
+func TestTrackerThrottles(t *testing.T) {
+	tracker, events, now := newTestTracker()
+	tracker.Start(PhaseCopy, 1000)
+	count := len(*events)
+
+	*now = now.Add(ReportInterval)
+	tracker.Add("a", 10)
+	tracker.Add("b", 10)
+	if got := len(*events) - count; got != 1 {
+		t.Fatalf("reported %d events within interval, want 1", got)
+	}
+	// 阶段完成时立即报告
+	tracker.Add("c", 980)
+	if got := len(*events) - count; got != 2 {
+		t.Errorf("reported %d events, want 2", got)
+	}
+}
+
+func TestTrackerNeverGoesBack(t *testing.T) {
+	tracker, events, _ := newTestTracker()
+	tracker.Plan(PhaseBackup, PhaseCopy)
+	tracker.Start(PhaseCopy, 10)
+	tracker.Add("a", 10)
+	before := (*events)[len(*events)-1].Percent
+
+	// 未计划的阶段保持当前进度
+	tracker.Start(PhaseExtract, 10)
+	if got := (*events)[len(*events)-1].Percent; got != before {
+		t.Errorf("percent = %v, want %v", got, before)
+	}
+}
+
+// recordingObserver 记录收到的阶段和文件通知
+type recordingObserver struct {
+	calls []string
+}
+
+func (o *recordingObserver) PhaseStarted(phase Phase, total int64) {
+	o.calls = append(o.calls, "start:"+string(phase))
+}
+
+func (o *recordingObserver) PhaseFinished(phase Phase) {
+	o.calls = append(o.calls, "end:"+string(phase))
+}
+
+func (o *recordingObserver) FileDone(phase Phase, file string) {
+	o.calls = append(o.calls, "file:"+string(phase)+":"+file)
+}
+
+func TestTrackerObserver(t *testing.T) {
+	tracker, _, _ := newTestTracker()
+	observer := &recordingObserver{}
+	tracker.SetObserver(observer)
+
+	tracker.Start(PhasePrepare, 0)
+	tracker.Start(PhaseCopy, 30)
+	tracker.Add("a", 5)
+	tracker.Add("a", 5)
+	tracker.Add("b", 10)
+	tracker.Add("c", 10)
+	tracker.Complete()
+
+	want := []string{
+		"start:prepare", "end:prepare",
+		"start:copy", "file:copy:a", "file:copy:b", "file:copy:c", "end:copy",
+	}
+	if len(observer.calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", observer.calls, want)
+	}
+	for i := range want {
+		if observer.calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q", i, observer.calls[i], want[i])
+		}
+	}
+}
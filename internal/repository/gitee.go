@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -10,17 +11,25 @@ import (
 )
 
 // GiteeRepository Gitee 仓库
-type GiteeRepository struct{}
+type GiteeRepository struct {
+	userName    string
+	projectName string
+}
 
 // NewGiteeRepository 创建 Gitee 仓库实例
 func NewGiteeRepository() *GiteeRepository {
-	return &GiteeRepository{}
+	return NewGiteeProjectRepository("zergqueen", config.ProjectName)
+}
+
+// NewGiteeProjectRepository 创建指定项目的 Gitee 仓库实例
+func NewGiteeProjectRepository(userName, projectName string) *GiteeRepository {
+	return &GiteeRepository{userName: userName, projectName: projectName}
 }
 
 // GetLatestRelease 获取最新版本信息
-func (g *GiteeRepository) GetLatestRelease(isPreview bool) (*model.Release, error) {
+func (g *GiteeRepository) GetLatestRelease(ctx context.Context, isPreview bool) (*model.Release, error) {
 	url := g.GetLatestReleaseURL(isPreview)
-	response, err := utils.Get(url)
+	response, err := utils.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("获取最新版本失败: %w", err)
 	}
@@ -63,11 +72,11 @@ func (g *GiteeRepository) GetLatestReleaseURL(isPreview bool) string {
 	if isPreview {
 		// 预览版：获取 latest（包括预发布版本）
 		return fmt.Sprintf("https://%s/api/v5/repos/%s/%s/releases/latest",
-			g.GetDomain(), g.GetUserName(), config.ProjectName)
+			g.GetDomain(), g.GetUserName(), g.GetProjectName())
 	}
 	// 正式版：获取所有 releases，自己过滤
 	return fmt.Sprintf("https://%s/api/v5/repos/%s/%s/releases",
-		g.GetDomain(), g.GetUserName(), config.ProjectName)
+		g.GetDomain(), g.GetUserName(), g.GetProjectName())
 }
 
 // GetDomain 获取域名
@@ -77,5 +86,32 @@ func (g *GiteeRepository) GetDomain() string {
 
 // GetUserName 获取用户名
 func (g *GiteeRepository) GetUserName() string {
-	return "zergqueen"
+	return g.userName
+}
+
+// GetProjectName 获取项目名
+func (g *GiteeRepository) GetProjectName() string {
+	return g.projectName
+}
+
+// GetReleases 获取版本列表（包括预发布版本）
+func (g *GiteeRepository) GetReleases(ctx context.Context) ([]model.Release, error) {
+	url := fmt.Sprintf("https://%s/api/v5/repos/%s/%s/releases",
+		g.GetDomain(), g.GetUserName(), g.GetProjectName())
+	response, err := utils.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("获取版本列表失败: %w", err)
+	}
+
+	var releases []model.Release
+	if err := json.Unmarshal([]byte(response), &releases); err != nil {
+		return nil, fmt.Errorf("解析版本列表失败: %w", err)
+	}
+	return releases, nil
+}
+
+// GetRawFileURL 获取仓库中指定版本文件的原始内容 URL
+func (g *GiteeRepository) GetRawFileURL(ref, filePath string) string {
+	return fmt.Sprintf("https://%s/%s/%s/raw/%s/%s",
+		g.GetDomain(), g.GetUserName(), g.GetProjectName(), ref, filePath)
 }
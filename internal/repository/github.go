@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -10,17 +11,25 @@ import (
 )
 
 // GitHubRepository GitHub 仓库
-type GitHubRepository struct{}
+type GitHubRepository struct {
+	userName    string
+	projectName string
+}
 
 // NewGitHubRepository 创建 GitHub 仓库实例
 func NewGitHubRepository() *GitHubRepository {
-	return &GitHubRepository{}
+	return NewGitHubProjectRepository("xjw580", config.ProjectName)
+}
+
+// NewGitHubProjectRepository 创建指定项目的 GitHub 仓库实例
+func NewGitHubProjectRepository(userName, projectName string) *GitHubRepository {
+	return &GitHubRepository{userName: userName, projectName: projectName}
 }
 
 // GetLatestRelease 获取最新版本信息
-func (g *GitHubRepository) GetLatestRelease(isPreview bool) (*model.Release, error) {
+func (g *GitHubRepository) GetLatestRelease(ctx context.Context, isPreview bool) (*model.Release, error) {
 	url := g.GetLatestReleaseURL(isPreview)
-	response, err := utils.Get(url)
+	response, err := utils.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("获取最新版本失败: %w", err)
 	}
@@ -50,11 +59,11 @@ func (g *GitHubRepository) GetLatestReleaseURL(isPreview bool) string {
 	if isPreview {
 		// 预览版：获取所有 releases
 		return fmt.Sprintf("https://api.%s/repos/%s/%s/releases",
-			g.GetDomain(), g.GetUserName(), config.ProjectName)
+			g.GetDomain(), g.GetUserName(), g.GetProjectName())
 	}
 	// 正式版：获取 latest（GitHub 的 latest 默认是非预发布版本）
 	return fmt.Sprintf("https://api.%s/repos/%s/%s/releases/latest",
-		g.GetDomain(), g.GetUserName(), config.ProjectName)
+		g.GetDomain(), g.GetUserName(), g.GetProjectName())
 }
 
 // GetDomain 获取域名
@@ -64,5 +73,32 @@ func (g *GitHubRepository) GetDomain() string {
 
 // GetUserName 获取用户名
 func (g *GitHubRepository) GetUserName() string {
-	return "xjw580" // 根据实际 GitHub 用户名修改
+	return g.userName
+}
+
+// GetProjectName 获取项目名
+func (g *GitHubRepository) GetProjectName() string {
+	return g.projectName
+}
+
+// GetReleases 获取版本列表（包括预发布版本）
+func (g *GitHubRepository) GetReleases(ctx context.Context) ([]model.Release, error) {
+	url := fmt.Sprintf("https://api.%s/repos/%s/%s/releases",
+		g.GetDomain(), g.GetUserName(), g.GetProjectName())
+	response, err := utils.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("获取版本列表失败: %w", err)
+	}
+
+	var releases []model.Release
+	if err := json.Unmarshal([]byte(response), &releases); err != nil {
+		return nil, fmt.Errorf("解析版本列表失败: %w", err)
+	}
+	return releases, nil
+}
+
+// GetRawFileURL 获取仓库中指定版本文件的原始内容 URL
+func (g *GitHubRepository) GetRawFileURL(ref, filePath string) string {
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s",
+		g.GetUserName(), g.GetProjectName(), ref, filePath)
 }
@@ -1,16 +1,16 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 
-	"club.xiaojiawei/hs-script-update/internal/config"
 	"club.xiaojiawei/hs-script-update/internal/model"
 )
 
 // Repository 版本仓库接口
 type Repository interface {
 	// GetLatestRelease 获取最新版本信息
-	GetLatestRelease(isPreview bool) (*model.Release, error)
+	GetLatestRelease(ctx context.Context, isPreview bool) (*model.Release, error)
 
 	// GetLatestReleaseURL 获取最新版本的 API URL
 	GetLatestReleaseURL(isPreview bool) string
@@ -20,16 +20,41 @@ type Repository interface {
 
 	// GetUserName 获取用户名
 	GetUserName() string
+
+	// GetProjectName 获取项目名
+	GetProjectName() string
+
+	// GetReleases 获取版本列表（包括预发布版本）
+	GetReleases(ctx context.Context) ([]model.Release, error)
+
+	// GetRawFileURL 获取仓库中指定版本文件的原始内容 URL
+	GetRawFileURL(ref, filePath string) string
+}
+
+// NewProjectRepository 根据仓库源创建指定项目的仓库实例
+func NewProjectRepository(source, userName, projectName string) (Repository, error) {
+	switch source {
+	case "github":
+		return NewGitHubProjectRepository(userName, projectName), nil
+	case "gitee":
+		return NewGiteeProjectRepository(userName, projectName), nil
+	}
+	return nil, fmt.Errorf("未知的仓库源: %s", source)
 }
 
 // GetReleaseDownloadURL 获取版本下载URL
 func GetReleaseDownloadURL(repo Repository, release *model.Release, isNative bool) string {
+	return GetAssetDownloadURL(repo, release, release.FileName(isNative))
+}
+
+// GetAssetDownloadURL 获取版本中指定附件的下载URL
+func GetAssetDownloadURL(repo Repository, release *model.Release, fileName string) string {
 	return fmt.Sprintf("https://%s/%s/%s/releases/download/%s/%s",
 		repo.GetDomain(),
 		repo.GetUserName(),
-		config.ProjectName,
+		repo.GetProjectName(),
 		release.TagName,
-		release.FileName(isNative))
+		fileName)
 }
 
 // GetReleasePageURL 获取版本发布页面URL
@@ -37,6 +62,6 @@ func GetReleasePageURL(repo Repository, release *model.Release) string {
 	return fmt.Sprintf("https://%s/%s/%s/releases/tag/%s",
 		repo.GetDomain(),
 		repo.GetUserName(),
-		config.ProjectName,
+		repo.GetProjectName(),
 		release.TagName)
 }
@@ -0,0 +1,174 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/config"
+)
+
+// Action 规则动作
+type Action string
+
+const (
+	// ActionInclude 从更新包复制并覆盖安装目录中的文件
+	ActionInclude Action = "include"
+	// ActionExclude 不从更新包复制
+	ActionExclude Action = "exclude"
+	// ActionPreserve 保留安装目录中的文件，仅在文件不存在时从更新包复制
+	ActionPreserve Action = "preserve"
+	// ActionMerge 合并配置文件：保留用户的值，补充更新包中新增的默认值
+	ActionMerge Action = "merge"
+)
+
+// Rule 单条更新规则
+type Rule struct {
+	// Pattern 相对于安装目录的路径，使用 / 分隔，支持 *、? 和 **
+	// 规则同时作用于匹配的目录下的所有内容
+	Pattern string `json:"pattern"`
+	Action  Action `json:"action"`
+}
+
+// File 更新规则文件内容
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// RuleSet 按顺序匹配的规则集合，第一条匹配的规则生效，没有规则匹配时默认复制
+type RuleSet struct {
+	rules []Rule
+}
+
+// NewRuleSet 按顺序合并多组规则
+func NewRuleSet(groups ...[]Rule) *RuleSet {
+	rs := &RuleSet{}
+	for _, group := range groups {
+		rs.rules = append(rs.rules, group...)
+	}
+	return rs
+}
+
+// Rules 返回全部规则
+func (rs *RuleSet) Rules() []Rule {
+	return rs.rules
+}
+
+// Match 返回相对路径对应的动作
+func (rs *RuleSet) Match(relPath string) Action {
+	if rs == nil {
+		return ActionInclude
+	}
+
+	relPath = strings.Trim(strings.ReplaceAll(relPath, "\\", "/"), "/")
+	for _, rule := range rs.rules {
+		if matchPathOrParent(rule.Pattern, relPath) {
+			return rule.Action
+		}
+	}
+	return ActionInclude
+}
+
+// Default 根据版本类型生成内置规则，与 config 中的保留目录和插件列表一致
+func Default(isJvmVersion bool) []Rule {
+	// 更新包中的元数据文件不复制到安装目录
+	defaults := []Rule{
+		{Pattern: config.UpdateRulesName, Action: ActionExclude},
+		{Pattern: config.PackageManifestName, Action: ActionExclude},
+		{Pattern: config.MigrationsName, Action: ActionExclude},
+		{Pattern: config.HooksName, Action: ActionExclude},
+	}
+
+	preserveDirs := config.NativePreserveDirs
+	if isJvmVersion {
+		preserveDirs = config.JVMPreserveDirs
+	}
+	for _, dir := range preserveDirs {
+		defaults = append(defaults, Rule{Pattern: dir, Action: ActionPreserve})
+	}
+
+	if isJvmVersion {
+		for _, plugin := range config.JVMUpdatePluginDirs {
+			defaults = append(defaults, Rule{Pattern: "plugin/" + plugin, Action: ActionInclude})
+		}
+		// 其他插件由用户自行管理
+		defaults = append(defaults, Rule{Pattern: "plugin/*", Action: ActionExclude})
+	}
+	return defaults
+}
+
+// Parse 解析规则文件内容
+func Parse(data []byte) ([]Rule, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析更新规则失败: %w", err)
+	}
+
+	for i, rule := range file.Rules {
+		switch rule.Action {
+		case ActionInclude, ActionExclude, ActionPreserve, ActionMerge:
+		default:
+			return nil, fmt.Errorf("第 %d 条规则的动作无效: %q", i+1, rule.Action)
+		}
+		pattern := strings.Trim(strings.ReplaceAll(rule.Pattern, "\\", "/"), "/")
+		if pattern == "" {
+			return nil, fmt.Errorf("第 %d 条规则的路径为空", i+1)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("第 %d 条规则的路径无效: %q", i+1, rule.Pattern)
+		}
+		file.Rules[i].Pattern = pattern
+	}
+	return file.Rules, nil
+}
+
+// Load 读取规则文件，文件不存在时返回空规则
+func Load(filePath string) ([]Rule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// matchPathOrParent 判断规则是否匹配路径本身或其任一上级目录
+func matchPathOrParent(pattern, relPath string) bool {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(relPath, "/")
+	for i := len(pathParts); i > 0; i-- {
+		if matchParts(patternParts, pathParts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParts 逐级匹配路径，** 匹配任意层级（包括零层）
+func matchParts(patternParts, pathParts []string) bool {
+	if len(patternParts) == 0 {
+		return len(pathParts) == 0
+	}
+
+	if patternParts[0] == "**" {
+		for i := 0; i <= len(pathParts); i++ {
+			if matchParts(patternParts[1:], pathParts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(pathParts) == 0 {
+		return false
+	}
+	matched, err := path.Match(patternParts[0], pathParts[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchParts(patternParts[1:], pathParts[1:])
+}
@@ -0,0 +1,93 @@
+package rules
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules []Rule
+		path  string
+		want  Action
+	}{
+		{"没有规则时复制", nil, "lib/app.jar", ActionInclude},
+		{"匹配文件本身", []Rule{{Pattern: "config/app.yml", Action: ActionMerge}}, "config/app.yml", ActionMerge},
+		{"匹配上级目录", []Rule{{Pattern: "data", Action: ActionPreserve}}, "data/db/save.json", ActionPreserve},
+		{"目录名只是前缀时不匹配", []Rule{{Pattern: "data", Action: ActionPreserve}}, "database-drivers/h2.jar", ActionInclude},
+		{"通配符只匹配一级", []Rule{{Pattern: "plugin/*", Action: ActionExclude}}, "plugin/a/b.jar", ActionExclude},
+		{"通配符不跨越目录", []Rule{{Pattern: "*.jar", Action: ActionExclude}}, "lib/app.jar", ActionInclude},
+		{"** 匹配零层目录", []Rule{{Pattern: "**/*.log", Action: ActionExclude}}, "app.log", ActionExclude},
+		{"** 匹配多层目录", []Rule{{Pattern: "**/*.log", Action: ActionExclude}}, "logs/2024/app.log", ActionExclude},
+		{"** 位于中间", []Rule{{Pattern: "lib/**/native", Action: ActionPreserve}}, "lib/x/y/native/a.so", ActionPreserve},
+		{"? 匹配单个字符", []Rule{{Pattern: "app-?.jar", Action: ActionExclude}}, "app-1.jar", ActionExclude},
+		{"反斜杠视为分隔符", []Rule{{Pattern: "data", Action: ActionPreserve}}, `data\save.json`, ActionPreserve},
+		{
+			"第一条匹配的规则生效",
+			[]Rule{
+				{Pattern: "plugin/base", Action: ActionInclude},
+				{Pattern: "plugin/*", Action: ActionExclude},
+			},
+			"plugin/base/base.jar", ActionInclude,
+		},
+		{
+			"顺序决定结果",
+			[]Rule{
+				{Pattern: "plugin/*", Action: ActionExclude},
+				{Pattern: "plugin/base", Action: ActionInclude},
+			},
+			"plugin/base/base.jar", ActionExclude,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := NewRuleSet(c.rules).Match(c.path); got != c.want {
+				t.Errorf("Match(%q) = %s，期望 %s", c.path, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatchNilRuleSet(t *testing.T) {
+	var rs *RuleSet
+	if got := rs.Match("data/save.json"); got != ActionInclude {
+		t.Errorf("Match = %s，期望 %s", got, ActionInclude)
+	}
+}
+
+func TestDefaultOrder(t *testing.T) {
+	rs := NewRuleSet(Default(true))
+	cases := map[string]Action{
+		"update-rules.json":                       ActionExclude,
+		"config/app.yml":                          ActionPreserve,
+		"data/save.json":                          ActionPreserve,
+		"database-drivers/h2.jar":                 ActionInclude,
+		"plugin/hs-script-base-card-plugin/a.jar": ActionInclude,
+		"plugin/third-party/a.jar":                ActionExclude,
+		"lib/app.jar":                             ActionInclude,
+	}
+	for path, want := range cases {
+		if got := rs.Match(path); got != want {
+			t.Errorf("Match(%q) = %s，期望 %s", path, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse([]byte(`{"rules":[{"pattern":"\\config\\","action":"preserve"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Pattern != "config" {
+		t.Errorf("规则 = %+v", rules)
+	}
+
+	for _, data := range []string{
+		`{"rules":[{"pattern":"a","action":"copy"}]}`,
+		`{"rules":[{"pattern":"/","action":"include"}]}`,
+		`{"rules":[{"pattern":"[","action":"include"}]}`,
+		`{`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%s) 应返回错误", data)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"club.xiaojiawei/hs-script-update/internal/archive"
+	"club.xiaojiawei/hs-script-update/internal/rules"
+)
+
+// tempFileSuffix 流式写入时使用的临时文件后缀
+const tempFileSuffix = ".tmp-update"
+
+// ExtractArchive 解压更新包到指定目录，支持 zip、tar、tar.gz、tar.zst 格式，ctx 取消时在当前文件处中止
+// onProgress 不为空时在写入过程中报告已解压的字节数，每个文件写入完成后至少调用一次
+func ExtractArchive(ctx context.Context, archivePath, destDir string, onProgress ProgressFunc) error {
+	slog.Info(fmt.Sprintf("开始解压: %s -> %s", archivePath, destDir))
+
+	a, err := archive.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// 先检查全部条目，未通过安全检查时不写入任何文件
+	if _, err := a.Entries(); err != nil {
+		return err
+	}
+
+	err = a.Walk(func(entry *archive.Entry, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 构造目标路径
+		fpath, err := archive.SafeJoin(destDir, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir {
+			return CreateDirectory(fpath)
+		}
+
+		// 创建文件的父目录
+		if err := CreateDirectory(filepath.Dir(fpath)); err != nil {
+			return err
+		}
+
+		// 创建文件
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, entry.Mode)
+		if err != nil {
+			return err
+		}
+		r = contextReader{ctx: ctx, r: r}
+		var counter *progressReader
+		if onProgress != nil {
+			counter = &progressReader{r: r, relPath: entry.Name, onProgress: onProgress}
+			r = counter
+		}
+		_, err = io.Copy(outFile, r)
+		outFile.Close()
+		if err == nil && counter != nil {
+			onProgress(entry.Name, max(entry.Size-counter.n, 0))
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("解压完成")
+	return nil
+}
+
+// ApplyArchive 流式应用更新包：直接将压缩包中的条目按更新规则写入目标位置，不做完整的临时解压
+// 每个文件先写入同目录下的临时文件，再重命名覆盖目标文件；onProgress 不为空时在写入过程中报告已处理的字节数，
+// 每个文件处理完后再调用一次，保留或合并等未完整读取的文件按条目大小补齐
+// ctx 取消时在当前文件处中止，已写入的文件由调用方恢复
+func ApplyArchive(ctx context.Context, archivePath, targetDir string, ruleSet *rules.RuleSet, onProgress ProgressFunc) error {
+	slog.Info(fmt.Sprintf("开始流式更新: %s -> %s", archivePath, targetDir))
+
+	a, err := archive.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// 先检查全部条目，未通过安全检查时不写入任何文件
+	entries, err := a.Entries()
+	if err != nil {
+		return err
+	}
+	root := archive.FindRoot(entries)
+
+	err = a.Walk(func(entry *archive.Entry, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		relPath := strings.TrimPrefix(entry.Name, root)
+		if relPath == "" || relPath == strings.TrimSuffix(root, "/") {
+			return nil
+		}
+
+		r = contextReader{ctx: ctx, r: r}
+		var counter *progressReader
+		if onProgress != nil {
+			counter = &progressReader{r: r, relPath: relPath, onProgress: onProgress}
+			r = counter
+		}
+
+		// 构造目标路径
+		fpath, err := archive.SafeJoin(targetDir, relPath)
+		if err != nil {
+			return err
+		}
+
+		action := ruleSet.Match(relPath)
+		if entry.IsDir {
+			if action == rules.ActionExclude {
+				return nil
+			}
+			return CreateDirectory(fpath)
+		}
+
+		err = ApplyRule(action, relPath, fpath,
+			func() ([]byte, error) {
+				return io.ReadAll(r)
+			},
+			func() error {
+				slog.Debug(fmt.Sprintf("写入文件: %s -> %s", entry.Name, fpath))
+				return writeFileAtomic(ctx, fpath, r, entry.Mode)
+			})
+		if err != nil {
+			return fmt.Errorf("写入文件失败 %s: %w", relPath, err)
+		}
+		if counter != nil && action != rules.ActionExclude {
+			onProgress(relPath, max(entry.Size-counter.n, 0))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("流式更新完成")
+	return nil
+}
+
+// WriteFileAtomic 先写入临时文件再重命名为目标文件，避免目标文件处于半写入状态
+func WriteFileAtomic(dst string, r io.Reader, mode os.FileMode) error {
+	return writeFileAtomic(context.Background(), dst, r, mode)
+}
+
+// writeFileAtomic 原子写入文件，目标文件被占用时按文件占用策略处理，ctx 取消时停止等待
+func writeFileAtomic(ctx context.Context, dst string, r io.Reader, mode os.FileMode) error {
+	// 检查目标文件是否是当前正在运行的进程
+	if IsCurrentProcess(dst) {
+		slog.Debug(fmt.Sprintf("跳过更新器文件: %s (正在运行中)", dst))
+		return nil
+	}
+
+	if err := CreateDirectory(filepath.Dir(dst)); err != nil {
+		return err
+	}
+
+	tmpPath := dst + tempFileSuffix
+	tmpFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tmpFile, r)
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		Delete(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		// 检查是否是文件被占用的错误
+		if !isFileInUseError(err) || !Exists(dst) {
+			Delete(tmpPath)
+			return err
+		}
+
+		// 按文件占用策略处理
+		err = resolveLockedFile(ctx, dst, func() error {
+			return os.Rename(tmpPath, dst)
+		})
+		if errors.Is(err, errSkipLockedFile) {
+			slog.Info(fmt.Sprintf("跳过文件: %s", dst))
+			return Delete(tmpPath)
+		}
+		if err != nil {
+			Delete(tmpPath)
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractSelfUpdateFromArchive 在更新包中查找与当前更新器同名的文件，并将其解压为待替换的新版本更新器
+// 返回 true 表示已准备好新版本更新器，需要调用 ReplaceSelf 完成替换
+func ExtractSelfUpdateFromArchive(archivePath, targetDir string) (bool, error) {
+	updaterName, ok := currentUpdaterName()
+	if !ok {
+		return false, nil // 无法获取当前进程，跳过自更新
+	}
+
+	a, err := archive.Open(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer a.Close()
+
+	found := false
+	err = a.Walk(func(entry *archive.Entry, r io.Reader) error {
+		if found || entry.IsDir || path.Base(entry.Name) != updaterName {
+			return nil
+		}
+		found = true
+
+		slog.Info("检测到更新器本身需要更新...")
+		newUpdaterPath := filepath.Join(targetDir, updaterName) + UpdaterNewSuffix
+
+		// 删除上次残留的新版本更新器（如果存在）
+		if Exists(newUpdaterPath) {
+			if err := Delete(newUpdaterPath); err != nil {
+				return fmt.Errorf("删除残留的新版本更新器失败: %w", err)
+			}
+		}
+
+		slog.Info(fmt.Sprintf("解压新版本更新器到: %s", newUpdaterPath))
+		if err := WriteFileAtomic(newUpdaterPath, r, entry.Mode); err != nil {
+			return fmt.Errorf("解压新更新器失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil // 没有找到更新器时跳过
+}
+
+// ReadArchiveFile 读取更新包根目录下的文件，文件不存在时返回 nil
+func ReadArchiveFile(archivePath, relPath string) ([]byte, error) {
+	a, err := archive.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, err
+	}
+	root := archive.FindRoot(entries)
+
+	var data []byte
+	err = a.Walk(func(entry *archive.Entry, r io.Reader) error {
+		if data != nil || entry.IsDir || strings.TrimPrefix(entry.Name, root) != relPath {
+			return nil
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		data = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
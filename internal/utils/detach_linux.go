@@ -0,0 +1,10 @@
+//go:build linux
+
+package utils
+
+import "syscall"
+
+// detachedProcessAttr 在新的会话中启动，更新器退出或终端关闭后不受影响
+func detachedProcessAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package utils
+
+import "syscall"
+
+// Windows 进程创建标志
+const (
+	detachedProcess       = 0x00000008
+	createNewProcessGroup = 0x00000200
+)
+
+// detachedProcessAttr 不继承更新器的控制台，更新器退出后不受影响
+func detachedProcessAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | createNewProcessGroup}
+}
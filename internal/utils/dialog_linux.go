@@ -0,0 +1,25 @@
+//go:build linux
+
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// ShowMessageBox 显示信息提示（Linux 下没有消息框，写入日志）
+func ShowMessageBox(message, title string) {
+	slog.Info(fmt.Sprintf("[%s] %s", title, message))
+}
+
+// ShowErrorBox 显示错误提示（Linux 下没有消息框，写入日志）
+func ShowErrorBox(message, title string) {
+	slog.Error(fmt.Sprintf("[%s] %s", title, message))
+}
+
+// AskUserWithTimeout 询问用户（Linux 下无人值守运行，直接按超时处理，按“否”处理）
+func AskUserWithTimeout(question string, timeoutMinutes int) bool {
+	slog.Info(question)
+	slog.Info("无法显示对话框，按“否”处理。")
+	return false
+}
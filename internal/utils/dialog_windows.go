@@ -0,0 +1,109 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Windows MessageBox 常量
+const (
+	MB_OK              = 0x00000000
+	MB_YESNO           = 0x00000004
+	MB_ICONQUESTION    = 0x00000020
+	MB_ICONINFORMATION = 0x00000040
+	MB_ICONERROR       = 0x00000010
+	MB_SETFOREGROUND   = 0x00010000
+	IDYES              = 6
+	IDNO               = 7
+	WM_CLOSE           = 0x0010
+)
+
+// ShowMessageBox 显示信息提示框
+func ShowMessageBox(message, title string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+
+	messageBox.Call(
+		0, // hwnd
+		uintptr(unsafe.Pointer(messagePtr)),
+		uintptr(unsafe.Pointer(titlePtr)),
+		uintptr(MB_OK|MB_ICONINFORMATION|MB_SETFOREGROUND),
+	)
+}
+
+// ShowErrorBox 显示错误提示框
+func ShowErrorBox(message, title string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+
+	messageBox.Call(
+		0, // hwnd
+		uintptr(unsafe.Pointer(messagePtr)),
+		uintptr(unsafe.Pointer(titlePtr)),
+		uintptr(MB_OK|MB_ICONERROR|MB_SETFOREGROUND),
+	)
+}
+
+// AskUserWithTimeout 询问用户，带超时（使用 MessageBox），超时未回应按“否”处理
+func AskUserWithTimeout(question string, timeoutMinutes int) bool {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	findWindow := user32.NewProc("FindWindowW")
+	sendMessage := user32.NewProc("SendMessageW")
+
+	// 构建提示文本
+	message := fmt.Sprintf("%s\n\n如果 %d 分钟内没有回应，将按“否”处理。", question, timeoutMinutes)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	titlePtr, _ := syscall.UTF16PtrFromString("HS-Script 更新器")
+
+	// 创建一个通道来接收用户选择
+	responseChan := make(chan int, 1)
+
+	// 在新的 goroutine 中显示 MessageBox
+	go func() {
+		ret, _, _ := messageBox.Call(
+			0, // hwnd
+			uintptr(unsafe.Pointer(messagePtr)),
+			uintptr(unsafe.Pointer(titlePtr)),
+			uintptr(MB_YESNO|MB_ICONQUESTION|MB_SETFOREGROUND),
+		)
+		responseChan <- int(ret)
+	}()
+
+	// 等待用户响应或超时
+	timeout := time.Duration(timeoutMinutes) * time.Minute
+	select {
+	case response := <-responseChan:
+		// 收到用户响应
+		slog.Info(fmt.Sprintf("用户选择: %s", map[int]string{IDYES: "是", IDNO: "否"}[response]))
+		return response == IDYES
+	case <-time.After(timeout):
+		// 超时，关闭 MessageBox
+		slog.Info(fmt.Sprintf("超时（%d 分钟），按“否”处理。", timeoutMinutes))
+
+		// 查找 MessageBox 窗口并关闭
+		titleSearchPtr, _ := syscall.UTF16PtrFromString("HS-Script 更新器")
+		hwnd, _, _ := findWindow.Call(
+			0, // lpClassName
+			uintptr(unsafe.Pointer(titleSearchPtr)),
+		)
+
+		if hwnd != 0 {
+			// 发送关闭消息
+			sendMessage.Call(hwnd, WM_CLOSE, 0, 0)
+		}
+
+		return false // 没有回应时不执行操作
+	}
+}
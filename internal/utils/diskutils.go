@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NearestExistingDir 返回路径自身或最近的已存在的上级目录
+func NearestExistingDir(path string) string {
+	dir := filepath.Clean(path)
+	for !IsDirectory(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+	return dir
+}
+
+// CheckWritable 通过创建并删除探测文件检查目录是否可写
+func CheckWritable(dir string) error {
+	probe, err := os.CreateTemp(dir, ".hs-update-probe-*")
+	if err != nil {
+		return err
+	}
+	probePath := probe.Name()
+	probe.Close()
+	return os.Remove(probePath)
+}
+
+// CheckFileWritable 检查已存在的文件是否可以被覆盖（如只读属性）
+func CheckFileWritable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf("文件为只读: %s", path)
+	}
+	return nil
+}
+
+// FormatBytes 将字节数格式化为易读的字符串
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// VolumeOf 返回路径所在文件系统的挂载点，同一文件系统中的路径返回相同的值
+// 路径不存在时按最近的已存在的上级目录判断，无法识别时返回绝对路径本身
+func VolumeOf(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	dir := NearestExistingDir(absPath)
+	var stat syscall.Stat_t
+	if err := syscall.Stat(dir, &stat); err != nil {
+		return absPath
+	}
+
+	// 向上查找，直到上级目录属于其他设备
+	for {
+		parent := filepath.Dir(dir)
+		var parentStat syscall.Stat_t
+		if parent == dir || syscall.Stat(parent, &parentStat) != nil || parentStat.Dev != stat.Dev {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// GetFreeSpace 获取路径所在文件系统对当前用户可用的空间（字节）
+func GetFreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("获取磁盘空间失败: %s, %w", path, err)
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package utils
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestVolumeOf(t *testing.T) {
+	dir := t.TempDir()
+	volume := VolumeOf(dir)
+	cases := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "a", "b", "c.txt"),
+		filepath.Dir(dir),
+	}
+	for _, path := range cases {
+		if got := VolumeOf(path); got != volume {
+			t.Errorf("VolumeOf(%s) = %s，与 %s 位于同一文件系统，期望 %s", path, got, dir, volume)
+		}
+	}
+
+	var root, proc syscall.Stat_t
+	if syscall.Stat("/", &root) != nil || syscall.Stat("/proc", &proc) != nil || root.Dev == proc.Dev {
+		t.Skip("/proc 未单独挂载")
+	}
+	if got := VolumeOf("/proc/self"); got != "/proc" {
+		t.Errorf("VolumeOf(/proc/self) = %s，期望 /proc", got)
+	}
+	if got := VolumeOf("/"); got != "/" {
+		t.Errorf("VolumeOf(/) = %s，期望 /", got)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// VolumeOf 返回路径所在的卷（如 C:），无法识别时返回绝对路径本身
+func VolumeOf(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	if volume := filepath.VolumeName(absPath); volume != "" {
+		return volume
+	}
+	return absPath
+}
+
+// GetFreeSpace 获取路径所在磁盘对当前用户可用的空间（字节）
+func GetFreeSpace(path string) (uint64, error) {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
+	ret, _, callErr := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)),
+	)
+	if ret == 0 {
+		return 0, fmt.Errorf("获取磁盘空间失败: %s, %w", path, callErr)
+	}
+	return freeBytesAvailable, nil
+}
@@ -1,15 +1,20 @@
 package utils
 
 import (
-	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
-	"syscall"
-	"unsafe"
+
+	"club.xiaojiawei/hs-script-update/internal/merge"
+	"club.xiaojiawei/hs-script-update/internal/rules"
 )
 
 // Exists 检查文件或目录是否存在
@@ -53,9 +58,14 @@ func ListDirectory(path string) ([]string, error) {
 
 // CopyFile 复制文件
 func CopyFile(src, dst string) error {
+	return copyFile(context.Background(), src, dst)
+}
+
+// copyFile 复制文件，目标文件被占用时按文件占用策略处理，ctx 取消时停止等待
+func copyFile(ctx context.Context, src, dst string) error {
 	// 检查目标文件是否是当前正在运行的进程
-	if isCurrentProcess(dst) {
-		fmt.Printf("跳过更新器文件: %s (正在运行中)\n", dst)
+	if IsCurrentProcess(dst) {
+		slog.Debug(fmt.Sprintf("跳过更新器文件: %s (正在运行中)", dst))
 		return nil
 	}
 
@@ -74,24 +84,19 @@ func CopyFile(src, dst string) error {
 	// 尝试创建目标文件
 	destFile, err := os.Create(dst)
 	if err != nil {
-		// 检查是否是文件被占用的错误
+		// 检查是否是文件被占用的错误，按文件占用策略处理
 		if isFileInUseError(err) && Exists(dst) {
-			fmt.Printf("检测到文件被占用: %s\n", dst)
-			// 处理文件占用
-			canProceed, handleErr := HandleLockedFile(dst)
-			if handleErr != nil {
-				return fmt.Errorf("处理文件占用失败: %w", handleErr)
-			}
-			if !canProceed {
-				// 用户选择跳过此文件
-				fmt.Printf("跳过文件: %s\n", dst)
+			err = resolveLockedFile(ctx, dst, func() error {
+				var createErr error
+				destFile, createErr = os.Create(dst)
+				return createErr
+			})
+			if errors.Is(err, errSkipLockedFile) {
+				slog.Info(fmt.Sprintf("跳过文件: %s", dst))
 				return nil
 			}
-
-			// 重试创建文件
-			destFile, err = os.Create(dst)
 			if err != nil {
-				return fmt.Errorf("处理占用后仍无法创建文件: %w", err)
+				return err
 			}
 		} else {
 			return err
@@ -103,6 +108,39 @@ func CopyFile(src, dst string) error {
 	return err
 }
 
+// contextReader 每次读取前检查 ctx 是否已取消，用于中止大文件的复制
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// ProgressFunc 进度回调，n 为新处理的字节数，relPath 为正在处理的文件
+type ProgressFunc func(relPath string, n int64)
+
+// progressReader 读取时通过 onProgress 报告读取的字节数
+type progressReader struct {
+	r          io.Reader
+	relPath    string
+	onProgress ProgressFunc
+	n          int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.onProgress(r.relPath, int64(n))
+	}
+	return n, err
+}
+
 // isFileInUseError 检查错误是否是文件被占用的错误
 func isFileInUseError(err error) bool {
 	if err == nil {
@@ -116,8 +154,8 @@ func isFileInUseError(err error) bool {
 		strings.Contains(errMsg, "access is denied")
 }
 
-// isCurrentProcess 检查文件是否是当前正在运行的进程
-func isCurrentProcess(filePath string) bool {
+// IsCurrentProcess 检查文件是否是当前正在运行的进程
+func IsCurrentProcess(filePath string) bool {
 	// 获取当前可执行文件路径
 	currentExe, err := os.Executable()
 	if err != nil {
@@ -140,8 +178,14 @@ func isCurrentProcess(filePath string) bool {
 	return filepath.Clean(currentExe) == filepath.Clean(targetPath)
 }
 
-// CopyDirectory 递归复制目录
-func CopyDirectory(src, dst string, excludeDirs, includePluginDirs []string) error {
+// CopyDirectory 按更新规则递归复制目录，ruleSet 为 nil 时复制全部内容，ctx 取消时在文件之间中止
+// onProgress 不为空时在处理完每个未被排除的文件后报告该文件的大小
+func CopyDirectory(ctx context.Context, src, dst string, ruleSet *rules.RuleSet, onProgress ProgressFunc) error {
+	return copyDirectory(ctx, src, dst, "", ruleSet, onProgress)
+}
+
+// copyDirectory 递归复制目录，relDir 为相对于更新根目录的路径
+func copyDirectory(ctx context.Context, src, dst, relDir string, ruleSet *rules.RuleSet, onProgress ProgressFunc) error {
 	if !Exists(dst) {
 		if err := CreateDirectory(dst); err != nil {
 			return err
@@ -154,126 +198,158 @@ func CopyDirectory(src, dst string, excludeDirs, includePluginDirs []string) err
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		srcPath := filepath.Join(src, entry)
 		dstPath := filepath.Join(dst, entry)
+		relPath := path.Join(relDir, entry)
 
 		if IsDirectory(srcPath) {
-			// 检查是否需要排除
-			if contains(excludeDirs, entry) {
-				fmt.Printf("跳过目录: %s\n", srcPath)
-				continue
-			}
-
-			// 特殊处理 plugin 目录
-			if entry == "plugin" && len(includePluginDirs) > 0 {
-				if err := copyPluginDirectory(srcPath, dstPath, includePluginDirs); err != nil {
-					return err
-				}
-			} else {
-				if err := CopyDirectory(srcPath, dstPath, excludeDirs, includePluginDirs); err != nil {
+			// 目录本身被排除时不创建，其中的文件仍按各自的规则处理
+			if ruleSet.Match(relPath) == rules.ActionExclude {
+				if err := copyDirectoryContents(ctx, srcPath, dstPath, relPath, ruleSet, onProgress); err != nil {
 					return err
 				}
+				continue
 			}
-		} else {
-			fmt.Printf("复制文件: %s -> %s\n", srcPath, dstPath)
-			if err := CopyFile(srcPath, dstPath); err != nil {
+			if err := copyDirectory(ctx, srcPath, dstPath, relPath, ruleSet, onProgress); err != nil {
 				return err
 			}
+			continue
 		}
-	}
 
-	return nil
-}
-
-// copyPluginDirectory 复制 plugin 目录，要更新的插件
-func copyPluginDirectory(src, dst string, includePluginDirs []string) error {
-	if !Exists(dst) {
-		if err := CreateDirectory(dst); err != nil {
+		action := ruleSet.Match(relPath)
+		err := ApplyRule(action, relPath, dstPath,
+			func() ([]byte, error) {
+				return os.ReadFile(srcPath)
+			},
+			func() error {
+				slog.Debug(fmt.Sprintf("复制文件: %s -> %s", srcPath, dstPath))
+				return copyFile(ctx, srcPath, dstPath)
+			})
+		if err != nil {
 			return err
 		}
+		if onProgress != nil && action != rules.ActionExclude {
+			onProgress(relPath, fileSize(srcPath))
+		}
 	}
 
+	return nil
+}
+
+// copyDirectoryContents 处理被排除目录中的内容，只有被其他规则选中的文件才会创建目录
+func copyDirectoryContents(ctx context.Context, src, dst, relDir string, ruleSet *rules.RuleSet, onProgress ProgressFunc) error {
 	entries, err := ListDirectory(src)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
-		if contains(includePluginDirs, entry) {
-			fmt.Printf("需要更新的插件: %s\n", filepath.Join(src, entry))
-			srcPath := filepath.Join(src, entry)
-			dstPath := filepath.Join(dst, entry)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		srcPath := filepath.Join(src, entry)
+		dstPath := filepath.Join(dst, entry)
+		relPath := path.Join(relDir, entry)
 
-			if IsDirectory(srcPath) {
-				if err := CopyDirectory(srcPath, dstPath, nil, nil); err != nil {
-					return err
-				}
+		if IsDirectory(srcPath) {
+			if ruleSet.Match(relPath) == rules.ActionExclude {
+				err = copyDirectoryContents(ctx, srcPath, dstPath, relPath, ruleSet, onProgress)
 			} else {
-				fmt.Printf("复制文件: %s -> %s\n", srcPath, dstPath)
-				if err := CopyFile(srcPath, dstPath); err != nil {
-					return err
-				}
+				err = copyDirectory(ctx, srcPath, dstPath, relPath, ruleSet, onProgress)
 			}
+			if err != nil {
+				return err
+			}
+			continue
 		}
-	}
 
+		action := ruleSet.Match(relPath)
+		if action == rules.ActionExclude {
+			continue
+		}
+		err := ApplyRule(action, relPath, dstPath,
+			func() ([]byte, error) {
+				return os.ReadFile(srcPath)
+			},
+			func() error {
+				slog.Debug(fmt.Sprintf("复制文件: %s -> %s", srcPath, dstPath))
+				return copyFile(ctx, srcPath, dstPath)
+			})
+		if err != nil {
+			return err
+		}
+		if onProgress != nil {
+			onProgress(relPath, fileSize(srcPath))
+		}
+	}
 	return nil
 }
 
-// Unzip 解压 ZIP 文件到指定目录
-func Unzip(zipFilePath, destDir string) error {
-	fmt.Printf("开始解压: %s -> %s\n", zipFilePath, destDir)
-
-	r, err := zip.OpenReader(zipFilePath)
+// fileSize 返回文件大小，无法读取时返回 0
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
 	if err != nil {
-		return err
+		return 0
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		// 构造目标路径
-		fpath := filepath.Join(destDir, f.Name)
+	return info.Size()
+}
 
-		// 检查路径是否安全（防止 zip slip 攻击）
-		if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("非法的文件路径: %s", fpath)
+// ApplyRule 按规则动作处理单个文件
+// defaults 读取更新包中的文件内容（用于合并配置），write 将更新包中的文件写入目标位置
+func ApplyRule(action rules.Action, relPath, dst string, defaults func() ([]byte, error), write func() error) error {
+	switch action {
+	case rules.ActionExclude:
+		slog.Debug(fmt.Sprintf("跳过: %s", relPath))
+		return nil
+	case rules.ActionPreserve:
+		if Exists(dst) {
+			slog.Debug(fmt.Sprintf("保留: %s", relPath))
+			return nil
 		}
-
-		if f.FileInfo().IsDir() {
-			// 创建目录
-			if err := CreateDirectory(fpath); err != nil {
-				return err
-			}
-		} else {
-			// 创建文件的父目录
-			if err := CreateDirectory(filepath.Dir(fpath)); err != nil {
-				return err
-			}
-
-			// 创建文件
-			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	case rules.ActionMerge:
+		if Exists(dst) {
+			data, err := defaults()
 			if err != nil {
 				return err
 			}
-
-			rc, err := f.Open()
+			changed, err := merge.MergeFile(dst, data)
 			if err != nil {
-				outFile.Close()
-				return err
+				// 合并失败时保留用户的配置，不中断更新
+				slog.Warn(fmt.Sprintf("合并配置失败，保留原文件: %s (%v)", relPath, err))
+				return nil
 			}
-
-			_, err = io.Copy(outFile, rc)
-			outFile.Close()
-			rc.Close()
-
-			if err != nil {
-				return err
+			if changed {
+				slog.Debug(fmt.Sprintf("合并配置: %s", relPath))
+			} else {
+				slog.Debug(fmt.Sprintf("保留: %s", relPath))
 			}
+			return nil
 		}
 	}
+	return write()
+}
+
+// HashFile 计算文件的 SHA-256 值（十六进制）
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-	fmt.Println("解压完成")
-	return nil
+	return HashReader(file)
+}
+
+// HashReader 计算数据流的 SHA-256 值（十六进制）
+func HashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 // FindFile 递归查找文件
@@ -340,109 +416,22 @@ func FindExtractedDirectory(tempExtractDir string) string {
 	return tempExtractDir
 }
 
-// getShortPath 获取 Windows 短路径（8.3格式）
-func getShortPath(longPath string) string {
-	// 转换为绝对路径
-	absPath, err := filepath.Abs(longPath)
-	if err != nil {
-		return longPath
-	}
-
-	// 调用 Windows API 获取短路径
-	kernel32 := syscall.NewLazyDLL("kernel32.dll")
-	getShortPathName := kernel32.NewProc("GetShortPathNameW")
-
-	// 转换为 UTF-16
-	longPathUTF16, err := syscall.UTF16PtrFromString(absPath)
-	if err != nil {
-		return longPath
-	}
-
-	// 第一次调用获取所需缓冲区大小
-	bufSize, _, _ := getShortPathName.Call(
-		uintptr(unsafe.Pointer(longPathUTF16)),
-		0,
-		0,
-	)
-
-	if bufSize == 0 {
-		return longPath
-	}
-
-	// 分配缓冲区
-	shortPathBuf := make([]uint16, bufSize)
-
-	// 第二次调用获取短路径
-	ret, _, _ := getShortPathName.Call(
-		uintptr(unsafe.Pointer(longPathUTF16)),
-		uintptr(unsafe.Pointer(&shortPathBuf[0])),
-		uintptr(bufSize),
-	)
-
-	if ret == 0 {
-		return longPath
-	}
-
-	// 转换回字符串
-	return syscall.UTF16ToString(shortPathBuf)
-}
-
-// HandleSelfUpdate 处理更新器自我更新
-func HandleSelfUpdate(tempExtractDir, targetDir string) error {
+// currentUpdaterName 获取当前更新器的文件名
+func currentUpdaterName() (string, bool) {
 	// 获取当前可执行文件的名称
 	currentExe, err := os.Executable()
 	if err != nil {
-		return nil // 无法获取当前进程，跳过自更新
+		return "", false
 	}
 
 	// 解析符号链接
 	currentExe, err = filepath.EvalSymlinks(currentExe)
 	if err != nil {
-		return nil
+		return "", false
 	}
 
 	// 提取文件名
-	updaterName := filepath.Base(currentExe)
-
-	// 在解压的文件中查找同名的更新器
-	newUpdaterPath, err := FindFile(tempExtractDir, updaterName)
-	if err != nil || newUpdaterPath == "" {
-		return nil // 没有找到更新器，跳过
-	}
-
-	fmt.Println("\n检测到更新器本身需要更新...")
-	currentUpdaterPath := filepath.Join(targetDir, updaterName)
-	backupUpdaterPath := currentUpdaterPath + ".bak"
-
-	// 1. 删除旧的备份（如果存在）
-	if Exists(backupUpdaterPath) {
-		if err := Delete(backupUpdaterPath); err != nil {
-			return fmt.Errorf("删除旧备份失败: %w", err)
-		}
-	}
-
-	// 2. 将新的 updater 复制为备份文件
-	fmt.Printf("复制新版本更新器到: %s\n", backupUpdaterPath)
-	// 直接使用底层复制，因为 CopyFile 会跳过当前进程
-	if err := copyFileDirect(newUpdaterPath, backupUpdaterPath); err != nil {
-		return fmt.Errorf("复制新更新器失败: %w", err)
-	}
-
-	fmt.Println("更新器将在退出后自动更新")
-	fmt.Println("请等待程序完全退出...")
-
-	shortCurrentPath := getShortPath(currentUpdaterPath)
-	shortBackupPath := getShortPath(backupUpdaterPath)
-
-	cmdStr := fmt.Sprintf("ping -n 3 127.0.0.1 > nul && del /f /q %s && ren %s %s",
-		shortCurrentPath, shortBackupPath, updaterName)
-
-	cmd := exec.Command("cmd", "/c", cmdStr)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("启动更新命令失败: %w", err)
-	}
-
-	return nil
+	return filepath.Base(currentExe), true
 }
 
 // copyFileDirect 直接复制文件（不检查是否是当前进程）
@@ -468,13 +457,3 @@ func copyFileDirect(src, dst string) error {
 	_, err = io.Copy(destFile, sourceFile)
 	return err
 }
-
-// contains 检查切片中是否包含指定元素
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if strings.HasPrefix(item, s) {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,10 @@
+//go:build linux
+
+package utils
+
+import "os"
+
+// deleteOnReboot 删除移开的文件，Linux 下已打开的文件删除后仍可被占用进程继续使用
+func deleteOnReboot(path string) error {
+	return os.Remove(path)
+}
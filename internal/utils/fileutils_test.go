@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyDirectoryCanceled(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := CopyDirectory(ctx, src, dst, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回 context.Canceled，实际: %v", err)
+	}
+	if Exists(filepath.Join(dst, "a.txt")) {
+		t.Error("取消后不应复制文件")
+	}
+
+	if err := CopyDirectory(context.Background(), src, dst, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !Exists(filepath.Join(dst, "a.txt")) {
+		t.Error("应复制文件")
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := contextReader{ctx: ctx, r: zeroReader{}}
+	cancel()
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后读取应返回 context.Canceled，实际: %v", err)
+	}
+}
+
+// zeroReader 每次读取 0 字节的读取器
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	return 0, nil
+}
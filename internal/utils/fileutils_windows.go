@@ -0,0 +1,31 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// moveFileDelayUntilReboot MoveFileEx 标志：重启后再执行
+const moveFileDelayUntilReboot = 0x4
+
+// deleteOnReboot 先尝试直接删除移开的文件，失败时通过 MoveFileEx 安排在重启后删除（需要管理员权限）
+func deleteOnReboot(path string) error {
+	if err := os.Remove(path); err == nil {
+		return nil
+	}
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	moveFileEx := syscall.NewLazyDLL("kernel32.dll").NewProc("MoveFileExW")
+	if ret, _, callErr := moveFileEx.Call(uintptr(unsafe.Pointer(pathPtr)), 0, moveFileDelayUntilReboot); ret == 0 {
+		return callErr
+	}
+	slog.Info(fmt.Sprintf("旧文件将在重启后删除: %s", path))
+	return nil
+}
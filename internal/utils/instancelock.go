@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInstanceLocked 已有其他进程持有锁
+var ErrInstanceLocked = errors.New("已有更新正在进行")
+
+// lockWriteGrace 锁文件内容为空或无法解析时，视为持有者正在写入的时长，超过后视为残留
+const lockWriteGrace = 5 * time.Second
+
+// LockOwner 锁文件中记录的持有者信息
+type LockOwner struct {
+	PID       int       `json:"pid"`
+	Path      string    `json:"path"`
+	StartedAt time.Time `json:"startedAt"`
+	// ProcessStart 持有者进程的启动时间，用于识别 PID 被其他进程复用的情况，无法读取时为空
+	ProcessStart time.Time `json:"processStart"`
+}
+
+// processStartTolerance 比较进程启动时间时允许的误差
+const processStartTolerance = time.Second
+
+// InstanceLock 基于锁文件的单实例锁，持有者进程退出后遗留的锁文件视为失效
+type InstanceLock struct {
+	path string
+}
+
+// AcquireInstanceLock 获取锁文件，lockPath 已被其他正在运行的进程持有时：
+// wait 为 false 返回 ErrInstanceLocked，为 true 时等待持有者释放锁或退出，开始等待时调用 onWait，ctx 取消时停止等待
+func AcquireInstanceLock(ctx context.Context, lockPath, path string, wait bool, onWait func(owner LockOwner)) (*InstanceLock, error) {
+	if err := CreateDirectory(filepath.Dir(lockPath)); err != nil {
+		return nil, err
+	}
+
+	waiting := false
+	for {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			owner := LockOwner{PID: os.Getpid(), Path: path, StartedAt: time.Now()}
+			if started, err := Processes.StartTime(owner.PID); err == nil {
+				owner.ProcessStart = started
+			}
+			data, _ := json.Marshal(owner)
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("写入锁文件失败: %w", err)
+			}
+			return &InstanceLock{path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建锁文件失败: %w", err)
+		}
+
+		owner, err := readLockOwner(lockPath)
+		if err != nil {
+			// 持有者可能刚创建文件还未写入内容
+			if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) < lockWriteGrace {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			slog.Warn(fmt.Sprintf("锁文件无效，已删除: %s (%v)", lockPath, err))
+			removeStaleLock(lockPath, 0)
+			continue
+		}
+		if !owner.alive() {
+			slog.Warn(fmt.Sprintf("持有锁的进程已退出 (PID: %d)，删除残留的锁文件: %s", owner.PID, lockPath))
+			removeStaleLock(lockPath, owner.PID)
+			continue
+		}
+
+		if !wait {
+			return nil, fmt.Errorf("%w (PID: %d, 开始于 %s)", ErrInstanceLocked, owner.PID, owner.StartedAt.Format("2006-01-02 15:04:05"))
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !waiting {
+			waiting = true
+			if onWait != nil {
+				onWait(*owner)
+			}
+		}
+		// 持有者可能在退出前释放锁，因此每秒重新检查一次
+		if err := Processes.WaitForExit(owner.PID, time.Second); err != nil && !errors.Is(err, ErrWaitTimeout) {
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// alive 持有者进程是否仍在运行，PID 已被启动时间不同的其他进程复用时视为已退出
+func (o LockOwner) alive() bool {
+	if !Processes.IsRunning(o.PID) {
+		return false
+	}
+	if o.ProcessStart.IsZero() {
+		return true
+	}
+	started, err := Processes.StartTime(o.PID)
+	if err != nil {
+		return true
+	}
+	diff := started.Sub(o.ProcessStart)
+	return diff < processStartTolerance && diff > -processStartTolerance
+}
+
+// Release 释放锁，只删除仍由当前进程持有的锁文件
+func (l *InstanceLock) Release() error {
+	owner, err := readLockOwner(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if owner.PID != os.Getpid() {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// readLockOwner 读取锁文件中的持有者信息
+func readLockOwner(lockPath string) (*LockOwner, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	var owner LockOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, err
+	}
+	if owner.PID <= 0 {
+		return nil, fmt.Errorf("锁文件中的 PID 无效: %d", owner.PID)
+	}
+	return &owner, nil
+}
+
+// removeStaleLock 删除残留的锁文件，删除前再次确认持有者没有变化，避免删除其他进程刚获取的锁
+func removeStaleLock(lockPath string, pid int) {
+	if owner, err := readLockOwner(lockPath); err == nil && owner.PID != pid {
+		return
+	}
+	if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+		slog.Warn(fmt.Sprintf("删除残留的锁文件失败: %v", err))
+	}
+}
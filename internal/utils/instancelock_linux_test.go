@@ -0,0 +1,111 @@
+//go:build linux
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLockOwner 写入指定持有者的锁文件
+func writeLockOwner(t *testing.T, lockPath string, pid int) {
+	t.Helper()
+	data, _ := json.Marshal(LockOwner{PID: pid, StartedAt: time.Now()})
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireInstanceLock(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "update.lock")
+	lock, err := AcquireInstanceLock(context.Background(), lockPath, "/opt/hs", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := readLockOwner(lockPath)
+	if err != nil || owner.PID != os.Getpid() || owner.Path != "/opt/hs" {
+		t.Fatalf("锁文件内容不正确: %+v, %v", owner, err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(lockPath) {
+		t.Error("释放后应删除锁文件")
+	}
+}
+
+func TestAcquireInstanceLockHeld(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "update.lock")
+	cmd := startSleep(t)
+	writeLockOwner(t, lockPath, cmd.Process.Pid)
+
+	if _, err := AcquireInstanceLock(context.Background(), lockPath, "", false, nil); !errors.Is(err, ErrInstanceLocked) {
+		t.Fatalf("锁被持有时应返回 ErrInstanceLocked，实际: %v", err)
+	}
+
+	// 等待持有者退出后获取锁
+	waited := false
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	lock, err := AcquireInstanceLock(context.Background(), lockPath, "", true, func(owner LockOwner) {
+		waited = owner.PID == cmd.Process.Pid
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+	if !waited {
+		t.Error("应调用 onWait 回调")
+	}
+}
+
+func TestAcquireInstanceLockStale(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "update.lock")
+	cmd := startSleep(t)
+	pid := cmd.Process.Pid
+	cmd.Process.Kill()
+	cmd.Wait()
+	writeLockOwner(t, lockPath, pid)
+
+	lock, err := AcquireInstanceLock(context.Background(), lockPath, "", false, nil)
+	if err != nil {
+		t.Fatalf("持有者已退出时应获取到锁: %v", err)
+	}
+	lock.Release()
+}
+
+func TestReleaseOtherOwner(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "update.lock")
+	lock := &InstanceLock{path: lockPath}
+	writeLockOwner(t, lockPath, os.Getpid()+1)
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if !Exists(lockPath) {
+		t.Error("不应删除其他进程持有的锁文件")
+	}
+}
+
+func TestAcquireInstanceLockReusedPID(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "update.lock")
+	cmd := startSleep(t)
+	// 锁文件中记录的启动时间与当前使用该 PID 的进程不同，说明 PID 已被复用
+	data, _ := json.Marshal(LockOwner{PID: cmd.Process.Pid, StartedAt: time.Now(), ProcessStart: time.Unix(1, 0)})
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := AcquireInstanceLock(context.Background(), lockPath, "", false, nil)
+	if err != nil {
+		t.Fatalf("PID 被复用时应获取到锁: %v", err)
+	}
+	lock.Release()
+}
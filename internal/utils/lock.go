@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 文件占用时的处理方式
+const (
+	// LockPrompt 弹窗询问用户（默认）
+	LockPrompt = "prompt"
+	// LockKill 直接结束安装目录中的占用进程
+	LockKill = "kill"
+	// LockSkip 跳过被占用的文件
+	LockSkip = "skip"
+	// LockRetry 按退避间隔重试
+	LockRetry = "retry"
+	// LockFail 中止更新
+	LockFail = "fail"
+	// LockMoveAside 将被占用的文件重命名移开，重启后删除
+	LockMoveAside = "move-aside"
+)
+
+// maxLockRetryInterval 重试的最大间隔
+const maxLockRetryInterval = time.Minute
+
+// errSkipLockedFile 按策略跳过被占用的文件
+var errSkipLockedFile = errors.New("跳过被占用的文件")
+
+// LockPolicy 文件占用策略
+type LockPolicy struct {
+	Mode     string
+	Retries  int
+	Interval time.Duration
+}
+
+// String 返回策略的命令行形式
+func (p LockPolicy) String() string {
+	if p.Mode == LockRetry {
+		return fmt.Sprintf("%s:%d,%v", p.Mode, p.Retries, p.Interval)
+	}
+	return p.Mode
+}
+
+// ParseLockPolicy 解析 prompt、kill、skip、fail、move-aside 或 retry:<n>,<interval>
+// interval 可以是秒数或 Go 时长格式（如 500ms、2s）
+func ParseLockPolicy(value string) (LockPolicy, error) {
+	mode, args, _ := strings.Cut(strings.TrimSpace(value), ":")
+	switch mode {
+	case LockPrompt, LockKill, LockSkip, LockFail, LockMoveAside:
+		if args != "" {
+			return LockPolicy{}, fmt.Errorf("文件占用策略 %s 不需要参数: %q", mode, value)
+		}
+		return LockPolicy{Mode: mode}, nil
+	case LockRetry:
+		retries, interval, ok := strings.Cut(args, ",")
+		if !ok {
+			return LockPolicy{}, fmt.Errorf("重试策略格式应为 retry:<次数>,<间隔>: %q", value)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(retries))
+		if err != nil || n <= 0 {
+			return LockPolicy{}, fmt.Errorf("重试次数无效: %q", retries)
+		}
+		d, err := parseInterval(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return LockPolicy{}, fmt.Errorf("重试间隔无效: %q", interval)
+		}
+		return LockPolicy{Mode: LockRetry, Retries: n, Interval: d}, nil
+	}
+	return LockPolicy{}, fmt.Errorf("未知的文件占用策略: %q", value)
+}
+
+// parseInterval 解析秒数或 Go 时长
+func parseInterval(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+// OnLocked 当前使用的文件占用策略
+var OnLocked = LockPolicy{Mode: LockPrompt}
+
+// LockDecision 一次文件占用的处理记录
+type LockDecision struct {
+	File     string `json:"file"`
+	Policy   string `json:"policy"`
+	Action   string `json:"action"`
+	Attempts int    `json:"attempts,omitempty"`
+	PIDs     []int  `json:"pids,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LockRecorder 记录文件占用的处理结果，为空时只写日志
+var LockRecorder func(decision LockDecision)
+
+// recordLockDecision 记录处理结果
+func recordLockDecision(decision LockDecision) {
+	decision.Policy = OnLocked.String()
+	message := fmt.Sprintf("文件占用处理: %s -> %s", decision.File, decision.Action)
+	if decision.Error != "" {
+		message += fmt.Sprintf(" (%s)", decision.Error)
+	}
+	slog.Info(message)
+	if LockRecorder != nil {
+		LockRecorder(decision)
+	}
+}
+
+// resolveLockedFile 按文件占用策略处理被占用的文件，write 重新执行被占用的写入操作
+// 返回 nil 表示已写入，返回 errSkipLockedFile 表示按策略跳过该文件，ctx 取消时停止等待并返回取消错误
+func resolveLockedFile(ctx context.Context, dst string, write func() error) error {
+	slog.Info(fmt.Sprintf("检测到文件被占用: %s", dst))
+	decision := LockDecision{File: dst}
+	fail := func(action string, err error) error {
+		decision.Action = action
+		decision.Error = err.Error()
+		recordLockDecision(decision)
+		return err
+	}
+
+	switch OnLocked.Mode {
+	case LockSkip:
+		decision.Action = "skipped"
+		recordLockDecision(decision)
+		return errSkipLockedFile
+
+	case LockFail:
+		return fail("failed", fmt.Errorf("文件被占用: %s", dst))
+
+	case LockRetry:
+		interval := OnLocked.Interval
+		for attempt := 1; attempt <= OnLocked.Retries; attempt++ {
+			slog.Info(fmt.Sprintf("%v 后重试 (%d/%d): %s", interval, attempt, OnLocked.Retries, dst))
+			select {
+			case <-ctx.Done():
+				return fail("canceled", ctx.Err())
+			case <-time.After(interval):
+			}
+			decision.Attempts = attempt
+			err := write()
+			if err == nil {
+				decision.Action = "retried"
+				recordLockDecision(decision)
+				return nil
+			}
+			if !isFileInUseError(err) {
+				return fail("failed", err)
+			}
+			interval = min(interval*2, maxLockRetryInterval)
+		}
+		return fail("failed", fmt.Errorf("重试 %d 次后文件仍被占用: %s", OnLocked.Retries, dst))
+
+	case LockKill:
+		processes, err := FindProcessesUsingFile(dst)
+		if err != nil {
+			return fail("failed", fmt.Errorf("查找占用进程失败: %w", err))
+		}
+		for _, proc := range processes {
+			if proc.PID == os.Getpid() {
+				continue
+			}
+			if InstallDir == "" || proc.Path == "" || !IsWithinDir(proc.Path, InstallDir) {
+				slog.Info("  不结束安装目录外的占用进程: " + describeProcess(proc))
+				continue
+			}
+			if err := KillProcess(proc.PID); err != nil {
+				slog.Warn(err.Error())
+				continue
+			}
+			Processes.WaitForExit(proc.PID, 5*time.Second)
+			decision.PIDs = append(decision.PIDs, proc.PID)
+		}
+		if len(decision.PIDs) == 0 {
+			return fail("failed", fmt.Errorf("没有可以结束的占用进程: %s", dst))
+		}
+		if err := write(); err != nil {
+			return fail("failed", fmt.Errorf("结束占用进程后仍无法写入文件: %w", err))
+		}
+		decision.Action = "killed"
+		recordLockDecision(decision)
+		return nil
+
+	case LockMoveAside:
+		aside := fmt.Sprintf("%s.locked-%d", dst, time.Now().UnixNano())
+		if err := os.Rename(dst, aside); err != nil {
+			return fail("failed", fmt.Errorf("移开被占用的文件失败: %w", err))
+		}
+		if err := write(); err != nil {
+			os.Rename(aside, dst)
+			return fail("failed", fmt.Errorf("移开被占用的文件后仍无法写入: %w", err))
+		}
+		if err := deleteOnReboot(aside); err != nil {
+			slog.Warn(fmt.Sprintf("无法安排删除旧文件，请手动删除 %s: %v", aside, err))
+		}
+		decision.Action = "moved"
+		recordLockDecision(decision)
+		return nil
+	}
+
+	// 默认弹窗询问用户
+	canProceed, err := HandleLockedFile(ctx, dst)
+	if ctx.Err() != nil {
+		return fail("canceled", ctx.Err())
+	}
+	if err != nil {
+		return fail("failed", fmt.Errorf("处理文件占用失败: %w", err))
+	}
+	if !canProceed {
+		decision.Action = "skipped"
+		recordLockDecision(decision)
+		return errSkipLockedFile
+	}
+	if err := write(); err != nil {
+		return fail("failed", fmt.Errorf("处理占用后仍无法写入文件: %w", err))
+	}
+	decision.Action = "prompted"
+	recordLockDecision(decision)
+	return nil
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLockPolicy(t *testing.T) {
+	cases := []struct {
+		value string
+		want  LockPolicy
+	}{
+		{"prompt", LockPolicy{Mode: LockPrompt}},
+		{"kill", LockPolicy{Mode: LockKill}},
+		{"skip", LockPolicy{Mode: LockSkip}},
+		{"fail", LockPolicy{Mode: LockFail}},
+		{"move-aside", LockPolicy{Mode: LockMoveAside}},
+		{"retry:3,2", LockPolicy{Mode: LockRetry, Retries: 3, Interval: 2 * time.Second}},
+		{"retry:5, 500ms", LockPolicy{Mode: LockRetry, Retries: 5, Interval: 500 * time.Millisecond}},
+	}
+	for _, c := range cases {
+		got, err := ParseLockPolicy(c.value)
+		if err != nil {
+			t.Errorf("ParseLockPolicy(%q) 返回错误: %v", c.value, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseLockPolicy(%q) = %+v，期望 %+v", c.value, got, c.want)
+		}
+	}
+
+	for _, value := range []string{"", "ask", "retry", "retry:0,1s", "retry:3", "retry:3,-1s", "kill:1"} {
+		if _, err := ParseLockPolicy(value); err == nil {
+			t.Errorf("ParseLockPolicy(%q) 应返回错误", value)
+		}
+	}
+}
+
+func TestResolveLockedFileRetryCanceled(t *testing.T) {
+	saved := OnLocked
+	defer func() { OnLocked = saved }()
+	OnLocked = LockPolicy{Mode: LockRetry, Retries: 3, Interval: time.Minute}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	writes := 0
+	start := time.Now()
+	err := resolveLockedFile(ctx, "locked.txt", func() error {
+		writes++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v，期望 context.Canceled", err)
+	}
+	if writes != 0 {
+		t.Errorf("取消后仍重试了 %d 次", writes)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("取消后等待了 %v", elapsed)
+	}
+}
@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
 var client *http.Client
 
+// downloadClient 下载文件使用的客户端，不限制总耗时
+var downloadClient *http.Client
+
 func init() {
 	// 创建HTTP客户端，禁用SSL验证
 	client = &http.Client{
@@ -18,11 +23,14 @@ func init() {
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		},
 	}
+	downloadClient = &http.Client{
+		Transport: client.Transport,
+	}
 }
 
-// Get 发送GET请求
-func Get(url string) (string, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// Get 发送GET请求，ctx 取消时中止请求
+func Get(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("创建请求失败: %w", err)
 	}
@@ -47,3 +55,53 @@ func Get(url string) (string, error) {
 
 	return string(body), nil
 }
+
+// Download 下载文件，先写入临时文件，完成后再重命名为目标文件；ctx 取消时中止下载并删除临时文件
+// onProgress 不为空时报告已下载和总共需要下载的字节数，服务器未返回文件大小时 total 为 0
+func Download(ctx context.Context, url, dst string, onProgress func(done, total int64)) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", "hs-script-updater/1.0")
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP GET 请求失败: %s, %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，状态码: %d, %s", resp.StatusCode, url)
+	}
+
+	tmpPath := dst + ".download"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	var body io.Reader = resp.Body
+	if onProgress != nil {
+		total := max(resp.ContentLength, 0)
+		var done int64
+		onProgress(done, total)
+		body = &progressReader{r: resp.Body, onProgress: func(_ string, n int64) {
+			done += n
+			onProgress(done, total)
+		}}
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrWaitTimeout 等待进程退出超时
+var ErrWaitTimeout = errors.New("等待进程退出超时")
+
+// ProcessInfo 进程信息
+type ProcessInfo struct {
+	PID  int
+	Name string
+	// Path 可执行文件路径，无权访问时为空
+	Path string
+	// User 进程所属用户，无权访问时为空
+	User string
+}
+
+// ProcessManager 进程管理，按平台选择实现（Windows 使用系统 API，Linux 使用 /proc、pidfd 和信号）
+type ProcessManager interface {
+	// IsRunning 进程是否正在运行
+	IsRunning(pid int) bool
+	// Kill 强制结束进程
+	Kill(pid int) error
+	// WaitForExit 等待进程退出，超时返回 ErrWaitTimeout
+	WaitForExit(pid int, timeout time.Duration) error
+	// StartTime 读取进程的启动时间
+	StartTime(pid int) (time.Time, error)
+	// Command 读取进程的启动命令行，以及能够读取到的工作目录和环境变量
+	Command(pid int) (*CommandInfo, error)
+	// FindFileHolders 查找实际打开了文件的进程（Windows 使用 Restart Manager，Linux 扫描 /proc/*/fd）
+	FindFileHolders(filePath string) ([]ProcessInfo, error)
+}
+
+// CommandInfo 进程的启动信息，无法读取的字段为空
+type CommandInfo struct {
+	Args []string
+	Dir  string
+	Env  []string
+}
+
+// Processes 当前平台的进程管理实现
+var Processes ProcessManager = newProcessManager()
+
+// InstallDir 安装目录，处理文件占用时只允许结束可执行文件位于其中的进程
+var InstallDir string
+
+// IsWithinDir 判断路径是否位于目录中（Windows 下不区分大小写）
+func IsWithinDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// FindProcessesUsingFile 查找占用文件的进程
+func FindProcessesUsingFile(filePath string) ([]ProcessInfo, error) {
+	return Processes.FindFileHolders(filePath)
+}
+
+// KillProcess 杀死进程
+func KillProcess(pid int) error {
+	if err := Processes.Kill(pid); err != nil {
+		return fmt.Errorf("杀死进程失败 (PID: %d): %w", pid, err)
+	}
+	slog.Info(fmt.Sprintf("成功杀死进程 (PID: %d)", pid))
+	return nil
+}
+
+// IsProcessRunning 检查进程是否正在运行
+func IsProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return Processes.IsRunning(pid)
+}
+
+// WaitForProcessExit 等待指定进程退出，超时后强制杀死进程
+func WaitForProcessExit(pid int, maxWaitSeconds int) error {
+	if pid <= 0 {
+		return nil // 无效 PID，跳过等待
+	}
+
+	slog.Info(fmt.Sprintf("等待主程序退出 (PID: %d)...", pid))
+
+	// 每 2 秒显示一次等待信息
+	const reportInterval = 2 * time.Second
+	deadline := time.Now().Add(time.Duration(maxWaitSeconds) * time.Second)
+	for remaining := time.Until(deadline); remaining > 0; remaining = time.Until(deadline) {
+		err := Processes.WaitForExit(pid, min(remaining, reportInterval))
+		if err == nil {
+			slog.Info("主程序已退出")
+			return nil
+		}
+		if !errors.Is(err, ErrWaitTimeout) {
+			return err
+		}
+		waited := maxWaitSeconds - int(time.Until(deadline).Round(time.Second)/time.Second)
+		slog.Info(fmt.Sprintf("仍在等待主程序退出... (%d/%d 秒)", waited, maxWaitSeconds))
+	}
+
+	// 超时后强制杀死进程
+	slog.Info(fmt.Sprintf("等待超时，强制杀死主程序 (PID: %d)", pid))
+	if err := KillProcess(pid); err != nil {
+		return fmt.Errorf("杀死主程序失败: %w", err)
+	}
+
+	// 确保进程完全退出
+	if err := Processes.WaitForExit(pid, 5*time.Second); err != nil {
+		return fmt.Errorf("杀死主程序后等待退出失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+//go:build linux
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval 不支持 pidfd 时检查进程状态的间隔
+const pollInterval = 100 * time.Millisecond
+
+// linuxProcessManager 基于 /proc、pidfd 和信号的进程管理
+type linuxProcessManager struct {
+	// procDir proc 文件系统的挂载位置
+	procDir string
+	// pidfd 是否使用 pidfd 等待进程退出
+	pidfd bool
+}
+
+// newProcessManager 创建当前平台的进程管理实现
+func newProcessManager() ProcessManager {
+	return linuxProcessManager{procDir: "/proc", pidfd: true}
+}
+
+// IsRunning 进程是否正在运行，已退出但未被回收的僵尸进程视为未运行
+func (m linuxProcessManager) IsRunning(pid int) bool {
+	state, err := m.state(pid)
+	if err != nil {
+		return false
+	}
+	return state != 'Z' && state != 'X'
+}
+
+// state 读取 /proc/<pid>/stat 中的进程状态
+func (m linuxProcessManager) state(pid int) (byte, error) {
+	fields, err := m.stat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return fields[0][0], nil
+}
+
+// stat 读取 /proc/<pid>/stat 中进程名之后的字段，第一个字段为进程状态
+func (m linuxProcessManager) stat(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(m.procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// 进程名可能包含空格和括号，其余字段位于最后一个右括号之后
+	index := strings.LastIndexByte(string(data), ')')
+	if index < 0 {
+		return nil, errors.New("无法解析进程状态")
+	}
+	fields := strings.Fields(string(data[index+1:]))
+	if len(fields) == 0 {
+		return nil, errors.New("无法解析进程状态")
+	}
+	return fields, nil
+}
+
+// clockTicks /proc 中时间字段的单位（USER_HZ），Linux 在所有架构上都为 100
+const clockTicks = 100
+
+// StartTime 按 /proc/<pid>/stat 中的启动时刻和 /proc/stat 中的开机时间计算进程的启动时间
+func (m linuxProcessManager) StartTime(pid int) (time.Time, error) {
+	fields, err := m.stat(pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// starttime 为第 22 个字段，即状态之后的第 19 个
+	if len(fields) < 20 {
+		return time.Time{}, errors.New("无法解析进程启动时间")
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无法解析进程启动时间: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.procDir, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			boot, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("无法解析开机时间: %w", err)
+			}
+			return time.Unix(boot, 0).Add(time.Duration(ticks) * time.Second / clockTicks), nil
+		}
+	}
+	return time.Time{}, errors.New("无法读取开机时间")
+}
+
+// Kill 强制结束进程
+func (linuxProcessManager) Kill(pid int) error {
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
+
+// WaitForExit 等待进程退出，超时返回 ErrWaitTimeout
+// 优先使用 pidfd 等待，内核不支持时定时检查 /proc
+func (m linuxProcessManager) WaitForExit(pid int, timeout time.Duration) error {
+	if m.pidfd {
+		fd, _, errno := unix.Syscall(unix.SYS_PIDFD_OPEN, uintptr(pid), 0, 0)
+		switch errno {
+		case 0:
+			defer unix.Close(int(fd))
+			return waitPidfd(int(fd), timeout)
+		case unix.ESRCH:
+			return nil
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for m.IsRunning(pid) {
+		if time.Now().After(deadline) {
+			return ErrWaitTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil
+}
+
+// waitPidfd 等待 pidfd 可读，即进程已退出
+func waitPidfd(fd int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(remaining.Milliseconds()))
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+		return ErrWaitTimeout
+	}
+}
+
+// Command 读取 /proc/<pid> 中的命令行、工作目录和环境变量
+func (m linuxProcessManager) Command(pid int) (*CommandInfo, error) {
+	pidDir := filepath.Join(m.procDir, strconv.Itoa(pid))
+	cmdline, err := os.ReadFile(filepath.Join(pidDir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	info := &CommandInfo{Args: splitNul(cmdline)}
+	if len(info.Args) == 0 {
+		return nil, errors.New("无法读取进程的命令行")
+	}
+	if dir, err := os.Readlink(filepath.Join(pidDir, "cwd")); err == nil {
+		info.Dir = dir
+	}
+	if environ, err := os.ReadFile(filepath.Join(pidDir, "environ")); err == nil {
+		info.Env = splitNul(environ)
+	}
+	return info, nil
+}
+
+// splitNul 拆分以 NUL 分隔的字符串列表
+func splitNul(data []byte) []string {
+	text := strings.TrimRight(string(data), "\x00")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\x00")
+}
+
+// FindFileHolders 扫描 /proc/*/fd 和 /proc/*/maps，查找打开或映射了文件的进程
+func (m linuxProcessManager) FindFileHolders(filePath string) ([]ProcessInfo, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+
+	entries, err := os.ReadDir(m.procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := []ProcessInfo{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if m.holdsFile(pid, absPath) {
+			processes = append(processes, m.info(pid))
+		}
+	}
+	return processes, nil
+}
+
+// holdsFile 判断进程是否打开、映射或正在执行该文件
+func (m linuxProcessManager) holdsFile(pid int, absPath string) bool {
+	pidDir := filepath.Join(m.procDir, strconv.Itoa(pid))
+	if exe, err := os.Readlink(filepath.Join(pidDir, "exe")); err == nil && exe == absPath {
+		return true
+	}
+
+	// 无权访问其他用户的进程时跳过
+	fds, err := os.ReadDir(filepath.Join(pidDir, "fd"))
+	if err == nil {
+		for _, fd := range fds {
+			if target, err := os.Readlink(filepath.Join(pidDir, "fd", fd.Name())); err == nil && target == absPath {
+				return true
+			}
+		}
+	}
+
+	maps, err := os.ReadFile(filepath.Join(pidDir, "maps"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(maps), "\n") {
+		// 映射的文件路径位于第 6 列，路径中可能含有空格
+		fields := strings.Fields(line)
+		if len(fields) >= 6 && strings.Join(fields[5:], " ") == absPath {
+			return true
+		}
+	}
+	return false
+}
+
+// info 读取进程名、可执行文件路径和所属用户
+func (m linuxProcessManager) info(pid int) ProcessInfo {
+	pidDir := filepath.Join(m.procDir, strconv.Itoa(pid))
+	info := ProcessInfo{PID: pid}
+	if comm, err := os.ReadFile(filepath.Join(pidDir, "comm")); err == nil {
+		info.Name = strings.TrimSpace(string(comm))
+	}
+	if exe, err := os.Readlink(filepath.Join(pidDir, "exe")); err == nil {
+		info.Path = exe
+	}
+	if stat, err := os.Stat(pidDir); err == nil {
+		if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+			uid := strconv.Itoa(int(sys.Uid))
+			info.User = uid
+			if u, err := user.LookupId(uid); err == nil {
+				info.User = u.Username
+			}
+		}
+	}
+	return info
+}
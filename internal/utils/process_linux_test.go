@@ -0,0 +1,282 @@
+//go:build linux
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startSleep 启动一个长时间运行的子进程，测试结束时结束并回收
+func startSleep(t *testing.T) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动 sleep: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func TestIsRunning(t *testing.T) {
+	m := newProcessManager()
+	if !m.IsRunning(os.Getpid()) {
+		t.Fatal("当前进程应视为正在运行")
+	}
+
+	cmd := startSleep(t)
+	pid := cmd.Process.Pid
+	if !m.IsRunning(pid) {
+		t.Fatalf("子进程 %d 应视为正在运行", pid)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	if m.IsRunning(pid) {
+		t.Fatalf("子进程 %d 已退出", pid)
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	procDir := t.TempDir()
+	dir := filepath.Join(procDir, "100")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := "100 (hs (script)) S 1 100 100 0 -1 4194304 0 0 0 0 0 0 0 0 20 0 1 0 250 0 0"
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(procDir, "stat"), []byte("cpu  1 2 3\nbtime 1700000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := linuxProcessManager{procDir: procDir}
+	started, err := m.StartTime(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1700000002, 500_000_000); !started.Equal(want) {
+		t.Errorf("StartTime = %v，期望 %v", started, want)
+	}
+
+	if started, err := newProcessManager().StartTime(os.Getpid()); err != nil || time.Since(started) < 0 {
+		t.Errorf("当前进程的启动时间 = %v, %v", started, err)
+	}
+}
+
+func TestIsRunningZombie(t *testing.T) {
+	procDir := t.TempDir()
+	writeStat := func(pid int, stat string) {
+		dir := filepath.Join(procDir, strconv.Itoa(pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeStat(100, "100 (hs script) S 1 100 100 0 -1")
+	writeStat(101, "101 (a) b)) Z 1 101 101 0 -1")
+
+	m := linuxProcessManager{procDir: procDir}
+	if !m.IsRunning(100) {
+		t.Error("状态为 S 的进程应视为正在运行")
+	}
+	if m.IsRunning(101) {
+		t.Error("僵尸进程不应视为正在运行")
+	}
+	if m.IsRunning(102) {
+		t.Error("不存在的进程不应视为正在运行")
+	}
+}
+
+func TestKillAndWaitForExit(t *testing.T) {
+	m := newProcessManager()
+	cmd := startSleep(t)
+	pid := cmd.Process.Pid
+
+	if err := m.WaitForExit(pid, 200*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("进程未退出时应返回 ErrWaitTimeout，实际: %v", err)
+	}
+
+	if err := m.Kill(pid); err != nil {
+		t.Fatalf("结束进程失败: %v", err)
+	}
+	if err := m.WaitForExit(pid, 5*time.Second); err != nil {
+		t.Fatalf("等待进程退出失败: %v", err)
+	}
+}
+
+func TestWaitForExitPolling(t *testing.T) {
+	procDir := t.TempDir()
+	pidDir := filepath.Join(procDir, "4194000")
+	if err := os.MkdirAll(pidDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	statPath := filepath.Join(pidDir, "stat")
+	if err := os.WriteFile(statPath, []byte("4194000 (bot) S 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := linuxProcessManager{procDir: procDir}
+	if err := m.WaitForExit(4194000, 150*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("应返回 ErrWaitTimeout，实际: %v", err)
+	}
+	if err := os.WriteFile(statPath, []byte("4194000 (bot) Z 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WaitForExit(4194000, time.Second); err != nil {
+		t.Fatalf("僵尸进程应视为已退出: %v", err)
+	}
+}
+
+func TestFindFileHolders(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "held.dat")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	holders, err := newProcessManager().FindFileHolders(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, holder := range holders {
+		if holder.PID == os.Getpid() {
+			found = true
+			if holder.Path == "" || holder.Name == "" || holder.User == "" {
+				t.Errorf("进程信息不完整: %+v", holder)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("未找到当前进程，结果: %+v", holders)
+	}
+
+	file.Close()
+	holders, err = newProcessManager().FindFileHolders(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, holder := range holders {
+		if holder.PID == os.Getpid() {
+			t.Fatal("文件关闭后不应再找到当前进程")
+		}
+	}
+}
+
+func TestFindFileHoldersExecutable(t *testing.T) {
+	cmd := startSleep(t)
+	exe, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(cmd.Process.Pid), "exe"))
+	if err != nil {
+		t.Skipf("无法读取可执行文件路径: %v", err)
+	}
+
+	holders, err := newProcessManager().FindFileHolders(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, holder := range holders {
+		if holder.PID == cmd.Process.Pid {
+			return
+		}
+	}
+	t.Fatalf("未找到正在执行 %s 的进程 %d", exe, cmd.Process.Pid)
+}
+
+func TestIsWithinDir(t *testing.T) {
+	cases := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/opt/hs-script/jre/bin/java", "/opt/hs-script", true},
+		{"/opt/hs-script", "/opt/hs-script", true},
+		{"/opt/hs-script-old/hs-script", "/opt/hs-script", false},
+		{"/usr/bin/java", "/opt/hs-script", false},
+		{"/opt/hs-script/../other/app", "/opt/hs-script", false},
+	}
+	for _, c := range cases {
+		if got := IsWithinDir(c.path, c.dir); got != c.want {
+			t.Errorf("IsWithinDir(%q, %q) = %v，期望 %v", c.path, c.dir, got, c.want)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.Dir = t.TempDir()
+	cmd.Env = []string{"HS_TEST=1"}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动 sleep: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	// Start 返回时子进程可能还未完成 exec，命令行暂时为空
+	var info *CommandInfo
+	var err error
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		info, err = newProcessManager().Command(cmd.Process.Pid)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Args) != 2 || info.Args[1] != "30" {
+		t.Errorf("Args = %q", info.Args)
+	}
+	if info.Dir != cmd.Dir {
+		t.Errorf("Dir = %s，期望 %s", info.Dir, cmd.Dir)
+	}
+	if len(info.Env) != 1 || info.Env[0] != "HS_TEST=1" {
+		t.Errorf("Env = %q", info.Env)
+	}
+}
+
+func TestRunHealthCheck(t *testing.T) {
+	cases := []struct {
+		name    string
+		script  string
+		wantErr bool
+	}{
+		{"正常退出", "i=0; while [ $i -lt 2000 ]; do echo line $i; i=$((i+1)); done", false},
+		{"打印就绪标记后结束进程", "echo starting; echo READY; sleep 30", false},
+		{"失败退出", "echo error; exit 3", true},
+		{"超时", "sleep 30", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app")
+			if err := os.WriteFile(path, []byte("#!/bin/sh\n"+c.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			err := RunHealthCheck(path, nil, "READY", 2*time.Second)
+			if (err != nil) != c.wantErr {
+				t.Errorf("RunHealthCheck 错误 = %v，期望出错: %v", err, c.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("健康检查耗时过长: %v", elapsed)
+			}
+		})
+	}
+
+	if err := RunHealthCheck(filepath.Join(t.TempDir(), "missing"), nil, "", time.Second); err == nil {
+		t.Error("程序不存在时应返回错误")
+	}
+}
@@ -0,0 +1,274 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Windows 进程访问权限
+const (
+	processTerminate               = 0x0001
+	processQueryLimitedInformation = 0x1000
+	processSynchronize             = 0x00100000
+)
+
+// windowsProcessManager 基于 Windows API 的进程管理
+type windowsProcessManager struct{}
+
+// newProcessManager 创建当前平台的进程管理实现
+func newProcessManager() ProcessManager {
+	return windowsProcessManager{}
+}
+
+// IsRunning 进程是否正在运行
+func (windowsProcessManager) IsRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation|processSynchronize, false, uint32(pid))
+	if err != nil {
+		// 无权访问说明进程存在
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(handle)
+
+	event, err := syscall.WaitForSingleObject(handle, 0)
+	return err == nil && event == syscall.WAIT_TIMEOUT
+}
+
+// Kill 强制结束进程
+func (windowsProcessManager) Kill(pid int) error {
+	handle, err := syscall.OpenProcess(processTerminate|processSynchronize, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+	return syscall.TerminateProcess(handle, 1)
+}
+
+// WaitForExit 等待进程退出，超时返回 ErrWaitTimeout
+func (m windowsProcessManager) WaitForExit(pid int, timeout time.Duration) error {
+	handle, err := syscall.OpenProcess(processSynchronize, false, uint32(pid))
+	if err != nil {
+		if !m.IsRunning(pid) {
+			return nil
+		}
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+
+	event, err := syscall.WaitForSingleObject(handle, uint32(timeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	if event == syscall.WAIT_TIMEOUT {
+		return ErrWaitTimeout
+	}
+	return nil
+}
+
+// StartTime 读取进程的创建时间
+func (windowsProcessManager) StartTime(pid int) (time.Time, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// NtQueryInformationProcess 常量
+const (
+	processCommandLineInformation = 60
+	statusInfoLengthMismatch      = 0xC0000004
+)
+
+var procNtQueryInformationProcess = syscall.NewLazyDLL("ntdll.dll").NewProc("NtQueryInformationProcess")
+
+// unicodeString UNICODE_STRING 结构
+type unicodeString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+// Command 读取进程的命令行（Windows 8.1 及以上），工作目录和环境变量无法读取
+func (windowsProcessManager) Command(pid int) (*CommandInfo, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	buf := make([]byte, 4096)
+	for {
+		var size uint32
+		status, _, _ := procNtQueryInformationProcess.Call(uintptr(handle), processCommandLineInformation,
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&size)))
+		if status == 0 {
+			break
+		}
+		if status == statusInfoLengthMismatch && int(size) > len(buf) {
+			buf = make([]byte, size)
+			continue
+		}
+		return nil, fmt.Errorf("读取命令行失败: NTSTATUS 0x%X", status)
+	}
+
+	us := (*unicodeString)(unsafe.Pointer(&buf[0]))
+	if us.Buffer == nil || us.Length == 0 {
+		return nil, errors.New("无法读取进程的命令行")
+	}
+	commandLine := syscall.UTF16ToString(unsafe.Slice(us.Buffer, us.Length/2))
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return nil, err
+	}
+	return &CommandInfo{Args: args}, nil
+}
+
+// splitCommandLine 按 Windows 规则拆分命令行
+func splitCommandLine(commandLine string) ([]string, error) {
+	ptr, err := syscall.UTF16PtrFromString(commandLine)
+	if err != nil {
+		return nil, err
+	}
+	var argc int32
+	argv, err := syscall.CommandLineToArgv(ptr, &argc)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(argv)))
+
+	args := make([]string, argc)
+	for i := range args {
+		args[i] = syscall.UTF16ToString((*argv[i])[:])
+	}
+	return args, nil
+}
+
+// Restart Manager 常量
+const (
+	rmSessionKeyLen = 32
+	rmMaxAppName    = 255
+	rmMaxSvcName    = 63
+	errorMoreData   = 234
+)
+
+var (
+	rstrtmgr                 = syscall.NewLazyDLL("rstrtmgr.dll")
+	procRmStartSession       = rstrtmgr.NewProc("RmStartSession")
+	procRmRegisterResources  = rstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList            = rstrtmgr.NewProc("RmGetList")
+	procRmEndSession         = rstrtmgr.NewProc("RmEndSession")
+	procQueryFullProcessName = syscall.NewLazyDLL("kernel32.dll").NewProc("QueryFullProcessImageNameW")
+)
+
+// rmUniqueProcess RM_UNIQUE_PROCESS 结构
+type rmUniqueProcess struct {
+	ProcessID        uint32
+	ProcessStartTime syscall.Filetime
+}
+
+// rmProcessInfo RM_PROCESS_INFO 结构
+type rmProcessInfo struct {
+	Process          rmUniqueProcess
+	AppName          [rmMaxAppName + 1]uint16
+	ServiceShortName [rmMaxSvcName + 1]uint16
+	ApplicationType  uint32
+	AppStatus        uint32
+	TSSessionID      uint32
+	Restartable      int32
+}
+
+// FindFileHolders 通过 Restart Manager 查找占用文件的进程
+func (windowsProcessManager) FindFileHolders(filePath string) ([]ProcessInfo, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	pathPtr, err := syscall.UTF16PtrFromString(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var session uint32
+	sessionKey := make([]uint16, rmSessionKeyLen+1)
+	if ret, _, _ := procRmStartSession.Call(uintptr(unsafe.Pointer(&session)), 0, uintptr(unsafe.Pointer(&sessionKey[0]))); ret != 0 {
+		return nil, fmt.Errorf("创建 Restart Manager 会话失败: %w", syscall.Errno(ret))
+	}
+	defer procRmEndSession.Call(uintptr(session))
+
+	files := []*uint16{pathPtr}
+	if ret, _, _ := procRmRegisterResources.Call(uintptr(session), 1, uintptr(unsafe.Pointer(&files[0])), 0, 0, 0, 0); ret != 0 {
+		return nil, fmt.Errorf("注册文件失败: %w", syscall.Errno(ret))
+	}
+
+	var infos []rmProcessInfo
+	var needed, count, reasons uint32
+	for {
+		count = uint32(len(infos))
+		var infosPtr uintptr
+		if count > 0 {
+			infosPtr = uintptr(unsafe.Pointer(&infos[0]))
+		}
+		ret, _, _ := procRmGetList.Call(uintptr(session), uintptr(unsafe.Pointer(&needed)),
+			uintptr(unsafe.Pointer(&count)), infosPtr, uintptr(unsafe.Pointer(&reasons)))
+		if ret == 0 {
+			break
+		}
+		if ret != errorMoreData {
+			return nil, fmt.Errorf("查询占用进程失败: %w", syscall.Errno(ret))
+		}
+		// 进程列表在两次调用之间可能变化，多分配一些空间
+		infos = make([]rmProcessInfo, needed+4)
+	}
+
+	processes := []ProcessInfo{}
+	for _, info := range infos[:count] {
+		pid := int(info.Process.ProcessID)
+		process := ProcessInfo{PID: pid, Name: syscall.UTF16ToString(info.AppName[:])}
+		process.Path, process.User = processDetails(pid)
+		if process.Path != "" {
+			process.Name = filepath.Base(process.Path)
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// processDetails 读取进程的可执行文件路径和所属用户，无权访问时返回空值
+func processDetails(pid int) (string, string) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return "", ""
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exePath string
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if ret, _, _ := procQueryFullProcessName.Call(uintptr(handle), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); ret != 0 {
+		exePath = syscall.UTF16ToString(buf[:size])
+	}
+
+	var userName string
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(handle, syscall.TOKEN_QUERY, &token); err == nil {
+		defer token.Close()
+		if tokenUser, err := token.GetTokenUser(); err == nil {
+			if account, domain, _, err := tokenUser.User.Sid.LookupAccount(""); err == nil {
+				userName = domain + `\` + account
+			}
+		}
+	}
+	return exePath, userName
+}
@@ -1,15 +1,15 @@
 package utils
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"syscall"
 	"time"
-	"unsafe"
 )
 
 // IsFileLocked 检测文件是否被占用
@@ -29,231 +29,123 @@ func IsFileLocked(filePath string) bool {
 	return false
 }
 
-// ProcessInfo 进程信息
-type ProcessInfo struct {
-	PID  string
-	Name string
-	Path string
-}
+// PromptNotifier 弹窗询问用户之前调用，用于通知嵌入更新器的宿主程序需要用户操作，为空时忽略
+var PromptNotifier func(file, question string)
 
-// FindProcessesUsingFile 查找占用文件的进程
-func FindProcessesUsingFile(filePath string) ([]ProcessInfo, error) {
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		return nil, err
+// askUser 通知宿主程序后弹窗询问用户
+func askUser(file, question string) bool {
+	if PromptNotifier != nil {
+		PromptNotifier(file, question)
 	}
+	return AskUserWithTimeout(question, 5)
+}
 
-	// 使用 handle.exe 或 openfiles（需要管理员权限）
-	// 这里我们尝试使用 tasklist 和逻辑推断
-	// 更可靠的方法是使用 Restart Manager API，但需要更多代码
-
-	// 方法1: 尝试使用简单的方式 - 检查常见进程
-	processes := []ProcessInfo{}
-
-	// 获取文件名（无扩展名）
-	fileName := filepath.Base(absPath)
-	fileNameWithoutExt := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+// HandleLockedFile 处理被占用的文件
+// 只提示结束实际占用文件且可执行文件位于安装目录中的进程，其他进程需要用户手动关闭
+// 返回 true 表示已处理，可以继续复制；false 表示用户拒绝或没有回应，跳过复制
+// ctx 在询问期间取消时不结束任何进程
+func HandleLockedFile(ctx context.Context, filePath string) (bool, error) {
+	slog.Warn(fmt.Sprintf("文件被占用: %s", filePath))
 
-	// 执行 tasklist 命令
-	cmd := exec.Command("tasklist", "/FO", "CSV", "/NH", "/V")
-	output, err := cmd.Output()
+	// 查找占用文件的进程
+	processes, err := FindProcessesUsingFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("执行 tasklist 失败: %w", err)
+		return false, fmt.Errorf("查找占用进程失败: %w", err)
 	}
 
-	// 解析输出
-	lines := strings.Split(string(output), "\n")
-	csvPattern := regexp.MustCompile(`"([^"]*)"`)
-
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		matches := csvPattern.FindAllStringSubmatch(line, -1)
-		if len(matches) < 2 {
+	var killable, others []ProcessInfo
+	for _, proc := range processes {
+		if proc.PID == os.Getpid() {
 			continue
 		}
-
-		processName := matches[0][1]
-		pid := matches[1][1]
-
-		// 检查进程名是否与文件名相关
-		if strings.Contains(strings.ToLower(processName), strings.ToLower(fileNameWithoutExt)) {
-			processes = append(processes, ProcessInfo{
-				PID:  pid,
-				Name: processName,
-				Path: "", // tasklist 不直接提供路径
-			})
+		if InstallDir != "" && proc.Path != "" && IsWithinDir(proc.Path, InstallDir) {
+			killable = append(killable, proc)
+		} else {
+			others = append(others, proc)
 		}
 	}
-
-	return processes, nil
-}
-
-// KillProcess 杀死进程
-func KillProcess(pid string) error {
-	cmd := exec.Command("taskkill", "/F", "/PID", pid)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("杀死进程失败 (PID: %s): %w\n%s", pid, err, string(output))
+	for _, proc := range others {
+		slog.Info("  其他占用进程: " + describeProcess(proc))
 	}
-	fmt.Printf("成功杀死进程 (PID: %s)\n", pid)
-	return nil
-}
-
-// Windows MessageBox 常量
-const (
-	MB_OK              = 0x00000000
-	MB_YESNO           = 0x00000004
-	MB_ICONQUESTION    = 0x00000020
-	MB_ICONINFORMATION = 0x00000040
-	MB_ICONERROR       = 0x00000010
-	MB_SETFOREGROUND   = 0x00010000
-	IDYES              = 6
-	IDNO               = 7
-	WM_CLOSE           = 0x0010
-)
-
-// ShowMessageBox 显示信息提示框
-func ShowMessageBox(message, title string) {
-	user32 := syscall.NewLazyDLL("user32.dll")
-	messageBox := user32.NewProc("MessageBoxW")
-
-	messagePtr, _ := syscall.UTF16PtrFromString(message)
-	titlePtr, _ := syscall.UTF16PtrFromString(title)
-
-	messageBox.Call(
-		0, // hwnd
-		uintptr(unsafe.Pointer(messagePtr)),
-		uintptr(unsafe.Pointer(titlePtr)),
-		uintptr(MB_OK|MB_ICONINFORMATION|MB_SETFOREGROUND),
-	)
-}
-
-// ShowErrorBox 显示错误提示框
-func ShowErrorBox(message, title string) {
-	user32 := syscall.NewLazyDLL("user32.dll")
-	messageBox := user32.NewProc("MessageBoxW")
-
-	messagePtr, _ := syscall.UTF16PtrFromString(message)
-	titlePtr, _ := syscall.UTF16PtrFromString(title)
-
-	messageBox.Call(
-		0, // hwnd
-		uintptr(unsafe.Pointer(messagePtr)),
-		uintptr(unsafe.Pointer(titlePtr)),
-		uintptr(MB_OK|MB_ICONERROR|MB_SETFOREGROUND),
-	)
-}
-
-// AskUserWithTimeout 询问用户，带超时（使用 MessageBox）
-func AskUserWithTimeout(question string, timeoutMinutes int) bool {
-	user32 := syscall.NewLazyDLL("user32.dll")
-	messageBox := user32.NewProc("MessageBoxW")
-	findWindow := user32.NewProc("FindWindowW")
-	sendMessage := user32.NewProc("SendMessageW")
-
-	// 构建提示文本
-	message := fmt.Sprintf("%s\n\n如果 %d 分钟内没有回应，将默认执行操作。", question, timeoutMinutes)
-	messagePtr, _ := syscall.UTF16PtrFromString(message)
-	titlePtr, _ := syscall.UTF16PtrFromString("HS-Script 更新器")
-
-	// 创建一个通道来接收用户选择
-	responseChan := make(chan int, 1)
 
-	// 在新的 goroutine 中显示 MessageBox
-	go func() {
-		ret, _, _ := messageBox.Call(
-			0, // hwnd
-			uintptr(unsafe.Pointer(messagePtr)),
-			uintptr(unsafe.Pointer(titlePtr)),
-			uintptr(MB_YESNO|MB_ICONQUESTION|MB_SETFOREGROUND),
-		)
-		responseChan <- int(ret)
-	}()
-
-	// 等待用户响应或超时
-	timeout := time.Duration(timeoutMinutes) * time.Minute
-	select {
-	case response := <-responseChan:
-		// 收到用户响应
-		fmt.Printf("用户选择: %s\n", map[int]string{IDYES: "是", IDNO: "否"}[response])
-		return response == IDYES
-	case <-time.After(timeout):
-		// 超时，关闭 MessageBox
-		fmt.Printf("超时（%d 分钟），默认执行操作。\n", timeoutMinutes)
-
-		// 查找 MessageBox 窗口并关闭
-		titleSearchPtr, _ := syscall.UTF16PtrFromString("HS-Script 更新器")
-		hwnd, _, _ := findWindow.Call(
-			0, // lpClassName
-			uintptr(unsafe.Pointer(titleSearchPtr)),
-		)
-
-		if hwnd != 0 {
-			// 发送关闭消息
-			sendMessage.Call(hwnd, WM_CLOSE, 0, 0)
+	if len(killable) == 0 {
+		var question string
+		if len(others) == 0 {
+			slog.Info("未找到占用进程，但文件仍被占用。")
+			question = fmt.Sprintf("文件被占用:\n%s\n\n未找到占用进程，可能需要手动关闭相关程序。\n\n是否等待并重试？", filePath)
+		} else {
+			slog.Info("占用进程不在安装目录中，不会自动结束。")
+			question = fmt.Sprintf("文件被占用:\n%s\n\n以下进程不在安装目录中，请手动关闭:\n%s\n是否等待并重试？",
+				filePath, listProcesses(others))
 		}
-
-		return true // 超时默认执行
-	}
-}
-
-// HandleLockedFile 处理被占用的文件
-// 返回 true 表示已处理，可以继续复制；false 表示用户拒绝，跳过复制
-func HandleLockedFile(filePath string) (bool, error) {
-	fmt.Printf("\n警告: 文件被占用: %s\n", filePath)
-
-	// 查找占用文件的进程
-	processes, err := FindProcessesUsingFile(filePath)
-	if err != nil {
-		return false, fmt.Errorf("查找占用进程失败: %w", err)
-	}
-
-	if len(processes) == 0 {
-		fmt.Println("未找到占用进程，但文件仍被占用。")
-		fmt.Println("可能需要手动关闭相关程序后重试。")
+		slog.Info("可能需要手动关闭相关程序后重试。")
 
 		// 询问用户是否重试
-		question := fmt.Sprintf("文件被占用:\n%s\n\n未找到占用进程，可能需要手动关闭相关程序。\n\n是否等待并重试？", filePath)
-		if AskUserWithTimeout(question, 5) {
+		if askUser(filePath, question) {
 			// 等待一下，给用户时间关闭程序
-			time.Sleep(2 * time.Second)
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(2 * time.Second):
+			}
 			return true, nil
 		}
 		return false, fmt.Errorf("文件仍被占用，跳过: %s", filePath)
 	}
 
-	// 构建占用进程信息
-	processInfo := ""
-	for i, proc := range processes {
-		processInfo += fmt.Sprintf("%d. 进程名: %s, PID: %s\n", i+1, proc.Name, proc.PID)
-		fmt.Printf("  %d. 进程名: %s, PID: %s\n", i+1, proc.Name, proc.PID)
+	for _, proc := range killable {
+		slog.Info("  占用进程: " + describeProcess(proc))
 	}
 
 	// 询问用户是否杀死这些进程
-	question := fmt.Sprintf("文件被占用:\n%s\n\n找到以下占用进程:\n%s\n是否杀死这些进程以继续更新？", filePath, processInfo)
-	if AskUserWithTimeout(question, 5) {
-		// 杀死所有占用进程
-		for _, proc := range processes {
+	question := fmt.Sprintf("文件被占用:\n%s\n\n找到以下占用进程:\n%s\n是否杀死这些进程以继续更新？", filePath, listProcesses(killable))
+	if len(others) > 0 {
+		question += fmt.Sprintf("\n\n另有 %d 个不在安装目录中的进程也占用了该文件，需要手动关闭。", len(others))
+	}
+	if askUser(filePath, question) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		// 只杀死安装目录中的占用进程
+		for _, proc := range killable {
 			if err := KillProcess(proc.PID); err != nil {
-				fmt.Printf("警告: %v\n", err)
+				slog.Warn(err.Error())
+				continue
 			}
+			Processes.WaitForExit(proc.PID, 5*time.Second)
 		}
 
-		// 等待一下，确保进程已完全退出
-		time.Sleep(1 * time.Second)
-
-		fmt.Println("进程已杀死，可以继续复制文件。")
+		slog.Info("进程已杀死，可以继续复制文件。")
 		return true, nil
 	}
 
-	// 用户拒绝杀死进程
-	fmt.Printf("用户拒绝杀死进程，跳过文件: %s\n", filePath)
+	// 用户拒绝或没有回应
+	slog.Info(fmt.Sprintf("未同意杀死进程，跳过文件: %s", filePath))
 	return false, nil
 }
 
+// describeProcess 返回进程的描述
+func describeProcess(proc ProcessInfo) string {
+	desc := fmt.Sprintf("%s (PID: %d", proc.Name, proc.PID)
+	if proc.User != "" {
+		desc += ", 用户: " + proc.User
+	}
+	desc += ")"
+	if proc.Path != "" {
+		desc += " " + proc.Path
+	}
+	return desc
+}
+
+// listProcesses 生成进程列表文本
+func listProcesses(processes []ProcessInfo) string {
+	var sb strings.Builder
+	for i, proc := range processes {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeProcess(proc)))
+	}
+	return sb.String()
+}
+
 // IsUpdaterProcess 检查进程是否是更新器进程
 func IsUpdaterProcess(processName string) bool {
 	currentExe, err := os.Executable()
@@ -265,86 +157,236 @@ func IsUpdaterProcess(processName string) bool {
 	return strings.EqualFold(processName, currentName)
 }
 
-// IsProcessRunning 检查进程是否正在运行
-func IsProcessRunning(pid int) bool {
-	if pid <= 0 {
-		return false
+// LaunchOptions 启动主程序的参数
+type LaunchOptions struct {
+	Args []string
+	// Dir 工作目录，为空时使用程序所在目录
+	Dir string
+	// Env 环境变量，为空时继承更新器的环境变量
+	Env []string
+}
+
+// StartProgram 以独立进程启动主程序，不等待其退出，更新器退出后主程序继续运行
+func StartProgram(programPath string, opts LaunchOptions) error {
+	if programPath == "" {
+		return fmt.Errorf("程序路径为空")
 	}
 
-	// 使用 tasklist 检查进程是否存在
-	cmd := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/NH", "/FO", "CSV")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
+	if !Exists(programPath) {
+		return fmt.Errorf("程序不存在: %s", programPath)
+	}
+
+	slog.Info(fmt.Sprintf("启动主程序: %s %s", programPath, strings.Join(opts.Args, " ")))
+
+	cmd := exec.Command(programPath, opts.Args...)
+	cmd.Dir = opts.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = filepath.Dir(programPath)
 	}
+	if len(opts.Env) > 0 {
+		cmd.Env = opts.Env
+	}
+	cmd.SysProcAttr = detachedProcessAttr()
+
+	// 启动程序（不等待其退出）
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动程序失败: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("主程序已启动，PID: %d", cmd.Process.Pid))
+	return cmd.Process.Release()
+}
 
-	// 如果输出包含 PID，说明进程存在
-	return strings.Contains(string(output), fmt.Sprintf("\"%d\"", pid))
+// SplitArgs 拆分命令行参数，支持用单引号或双引号包含空格（反斜杠不作为转义字符，便于书写 Windows 路径）
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("参数中的引号不匹配: %s", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
 }
 
-// WaitForProcessExit 等待指定进程退出，超时后强制杀死进程
-func WaitForProcessExit(pid int, maxWaitSeconds int) error {
-	if pid <= 0 {
-		return nil // 无效 PID，跳过等待
+// outputDrainTimeout 进程退出后等待读完输出的最长时间，防止子进程继承管道导致无法读到结尾
+const outputDrainTimeout = 5 * time.Second
+
+// killedOutputDrainTimeout 结束进程后等待读完输出的最长时间
+const killedOutputDrainTimeout = 500 * time.Millisecond
+
+// RunHealthCheck 以健康检查参数启动程序，等待其正常退出或在标准输出中打印就绪标记
+func RunHealthCheck(programPath string, args []string, readyLine string, timeout time.Duration) error {
+	if !Exists(programPath) {
+		return fmt.Errorf("程序不存在: %s", programPath)
 	}
 
-	fmt.Printf("等待主程序退出 (PID: %d)...\n", pid)
+	slog.Info(fmt.Sprintf("执行健康检查: %s %s", programPath, strings.Join(args, " ")))
+	cmd := exec.Command(programPath, args...)
+	cmd.Dir = filepath.Dir(programPath)
 
-	checkInterval := 500 * time.Millisecond
-	maxChecks := maxWaitSeconds * 2 // 每 500ms 检查一次
+	// 自行创建管道，cmd.Wait 不会关闭读取端，输出读完之前不会被截断
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = pipeWriter
+	if err := cmd.Start(); err != nil {
+		pipeReader.Close()
+		pipeWriter.Close()
+		return fmt.Errorf("启动程序失败: %w", err)
+	}
+	pipeWriter.Close()
 
-	for i := 0; i < maxChecks; i++ {
-		if !IsProcessRunning(pid) {
-			fmt.Println("主程序已退出")
-			return nil
+	// 读取到管道结尾，就绪后继续读取剩余输出
+	readyChan := make(chan struct{}, 1)
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(pipeReader)
+		for scanner.Scan() {
+			line := scanner.Text()
+			slog.Info(fmt.Sprintf("  [health-check] %s", line))
+			if readyLine != "" && strings.Contains(line, readyLine) {
+				select {
+				case readyChan <- struct{}{}:
+				default:
+				}
+			}
 		}
-		time.Sleep(checkInterval)
+	}()
 
-		// 每 2 秒显示一次等待信息
-		if (i+1)%4 == 0 {
-			fmt.Printf("仍在等待主程序退出... (%d/%d 秒)\n", (i+1)/2, maxWaitSeconds)
+	exitChan := make(chan error, 1)
+	go func() {
+		exitChan <- cmd.Wait()
+	}()
+
+	// 进程退出后读完剩余输出再关闭管道，进程被结束时只等待已写入的输出
+	drain := func(wait time.Duration) {
+		select {
+		case <-outputDone:
+		case <-time.After(wait):
 		}
+		pipeReader.Close()
+		<-outputDone
 	}
 
-	// 超时后强制杀死进程
-	fmt.Printf("\n等待超时，强制杀死主程序 (PID: %d)\n", pid)
-	pidStr := fmt.Sprintf("%d", pid)
-	if err := KillProcess(pidStr); err != nil {
-		return fmt.Errorf("杀死主程序失败: %w", err)
+	select {
+	case <-readyChan:
+		// 已就绪，结束健康检查进程
+		cmd.Process.Kill()
+		<-exitChan
+		drain(killedOutputDrainTimeout)
+		return nil
+	case err := <-exitChan:
+		drain(outputDrainTimeout)
+		if err != nil {
+			return fmt.Errorf("健康检查失败: %w", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-exitChan
+		drain(killedOutputDrainTimeout)
+		return fmt.Errorf("健康检查超时 (%v)", timeout)
 	}
-
-	// 等待一秒确保进程完全退出
-	time.Sleep(1 * time.Second)
-	return nil
 }
 
-// StartProgram 启动主程序
-func StartProgram(programPath string, isPause bool) error {
-	if programPath == "" {
-		return fmt.Errorf("程序路径为空")
+// RunCommand 执行命令并逐行回调输出，超时或 ctx 取消后杀死进程
+func RunCommand(ctx context.Context, command []string, dir string, env []string, timeout time.Duration, onOutput func(line string)) error {
+	if len(command) == 0 {
+		return fmt.Errorf("命令为空")
 	}
 
-	if !Exists(programPath) {
-		return fmt.Errorf("程序不存在: %s", programPath)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	// 合并标准输出和标准错误
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		return err
 	}
+	cmd.Stdout = pipeWriter
+	cmd.Stderr = pipeWriter
 
-	fmt.Printf("启动主程序: %s\n", programPath)
+	if err := cmd.Start(); err != nil {
+		pipeReader.Close()
+		pipeWriter.Close()
+		return fmt.Errorf("启动命令失败: %w", err)
+	}
+	pipeWriter.Close()
 
-	// 构建启动命令
-	var cmd *exec.Cmd
-	if isPause {
-		// 如果主程序之前处于暂停状态，不传递 --pause 参数（让它恢复正常运行）
-		cmd = exec.Command(programPath)
-	} else {
-		// 正常启动
-		cmd = exec.Command(programPath)
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(pipeReader)
+		for scanner.Scan() {
+			if onOutput != nil {
+				onOutput(scanner.Text())
+			}
+		}
+	}()
+
+	exitChan := make(chan error, 1)
+	go func() {
+		exitChan <- cmd.Wait()
+	}()
+
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timeoutChan = time.After(timeout)
 	}
 
-	// 启动程序（不等待其退出）
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("启动程序失败: %w", err)
+	drain := outputDrainTimeout
+	select {
+	case err = <-exitChan:
+	case <-timeoutChan:
+		cmd.Process.Kill()
+		<-exitChan
+		err = fmt.Errorf("命令执行超时 (%v)", timeout)
+		drain = killedOutputDrainTimeout
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-exitChan
+		err = ctx.Err()
+		drain = killedOutputDrainTimeout
 	}
+	// 进程退出后读完剩余输出再关闭管道
+	select {
+	case <-outputDone:
+	case <-time.After(drain):
+	}
+	pipeReader.Close()
+	<-outputDone
 
-	fmt.Printf("主程序已启动，PID: %d\n", cmd.Process.Pid)
+	if err != nil {
+		return fmt.Errorf("命令执行失败: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"--a --b", []string{"--a", "--b"}},
+		{`  --dir="C:\Program Files\app"  -x `, []string{`--dir=C:\Program Files\app`, "-x"}},
+		{`'a b' "" c`, []string{"a b", "", "c"}},
+	}
+	for _, c := range cases {
+		got, err := SplitArgs(c.value)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) 返回错误: %v", c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("SplitArgs(%q) = %q，期望 %q", c.value, got, c.want)
+		}
+	}
+
+	if _, err := SplitArgs(`"unterminated`); err == nil {
+		t.Error("引号不匹配时应返回错误")
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UpdaterNewSuffix 待替换的新版本更新器的文件名后缀
+const UpdaterNewSuffix = ".new"
+
+// UpdaterOldSuffix 被替换下来的旧版本更新器的文件名后缀，无法立即删除时在下次启动时清理
+const UpdaterOldSuffix = ".old"
+
+// UpdaterCheckTimeout 新版本更新器 --version 检查的超时时间
+const UpdaterCheckTimeout = 10 * time.Second
+
+// StageSelfUpdate 在解压目录中查找新版本更新器，复制到安装目录作为待替换的新版本更新器
+// 返回 true 表示已准备好新版本更新器，需要调用 ReplaceSelf 完成替换；必须在删除解压目录之前调用
+func StageSelfUpdate(tempExtractDir, targetDir string) (bool, error) {
+	updaterName, ok := currentUpdaterName()
+	if !ok {
+		return false, nil // 无法获取当前进程，跳过自更新
+	}
+
+	// 在解压的文件中查找同名的更新器
+	newUpdaterPath, err := FindFile(tempExtractDir, updaterName)
+	if err != nil || newUpdaterPath == "" {
+		return false, nil // 没有找到更新器，跳过
+	}
+
+	slog.Info("检测到更新器本身需要更新...")
+	stagedPath := filepath.Join(targetDir, updaterName) + UpdaterNewSuffix
+	slog.Info(fmt.Sprintf("复制新版本更新器到: %s", stagedPath))
+	// 直接使用底层复制，因为 CopyFile 会跳过当前进程
+	if err := copyFileDirect(newUpdaterPath, stagedPath); err != nil {
+		return false, fmt.Errorf("复制新更新器失败: %w", err)
+	}
+	if info, err := os.Stat(newUpdaterPath); err == nil {
+		os.Chmod(stagedPath, info.Mode().Perm())
+	}
+	return true, nil
+}
+
+// ReplaceSelf 用待替换的新版本更新器替换安装目录中的更新器
+func ReplaceSelf(targetDir string) error {
+	updaterName, ok := currentUpdaterName()
+	if !ok {
+		return nil
+	}
+	return replaceExecutable(filepath.Join(targetDir, updaterName))
+}
+
+// replaceExecutable 用 <path>.new 替换 path
+// 运行中的可执行文件无法覆盖或删除，但 Windows 和 Linux 都允许重命名，因此先将旧文件移开为 <path>.old 再移入新文件；
+// 新文件未通过 --version 检查时恢复旧文件
+func replaceExecutable(path string) error {
+	newPath := path + UpdaterNewSuffix
+	oldPath := path + UpdaterOldSuffix
+	if !Exists(newPath) {
+		return nil
+	}
+
+	// 上次替换残留的旧文件
+	if Exists(oldPath) {
+		if err := os.Remove(oldPath); err != nil {
+			return fmt.Errorf("删除残留的旧版本更新器失败: %w", err)
+		}
+	}
+
+	hasCurrent := Exists(path)
+	if hasCurrent {
+		if err := os.Rename(path, oldPath); err != nil {
+			return fmt.Errorf("移开当前更新器失败: %w", err)
+		}
+	}
+	if err := os.Rename(newPath, path); err != nil {
+		if hasCurrent {
+			if restoreErr := os.Rename(oldPath, path); restoreErr != nil {
+				return fmt.Errorf("移入新版本更新器失败: %w，且恢复旧版本失败: %v", err, restoreErr)
+			}
+		}
+		return fmt.Errorf("移入新版本更新器失败: %w", err)
+	}
+
+	version, err := checkExecutableVersion(path)
+	if err != nil {
+		// 新文件移回 .new 以便排查，下次自更新时会被覆盖
+		if renameErr := os.Rename(path, newPath); renameErr != nil {
+			return fmt.Errorf("新版本更新器检查失败: %w，且无法移除新版本: %v", err, renameErr)
+		}
+		if hasCurrent {
+			if restoreErr := os.Rename(oldPath, path); restoreErr != nil {
+				return fmt.Errorf("新版本更新器检查失败: %w，且恢复旧版本失败: %v", err, restoreErr)
+			}
+		}
+		return fmt.Errorf("新版本更新器检查失败，已恢复旧版本: %w", err)
+	}
+	slog.Info(fmt.Sprintf("更新器已更新到 %s", version))
+
+	if hasCurrent {
+		removeOldExecutable(oldPath)
+	}
+	return nil
+}
+
+// checkExecutableVersion 以 --version 参数运行程序，检查其能否正常启动并返回输出的版本号
+func checkExecutableVersion(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), UpdaterCheckTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, "--version")
+	cmd.Dir = filepath.Dir(path)
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("运行 --version 超时 (%v)", UpdaterCheckTimeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("运行 --version 失败: %w", err)
+	}
+
+	version := strings.TrimSpace(string(output))
+	if version == "" {
+		return "", errors.New("运行 --version 没有输出版本号")
+	}
+	return version, nil
+}
+
+// removeOldExecutable 删除被替换下来的旧文件，Windows 下当前进程仍在运行时无法删除，留到下次启动时清理
+func removeOldExecutable(oldPath string) {
+	if err := os.Remove(oldPath); err != nil {
+		slog.Info(fmt.Sprintf("旧版本更新器将在下次启动时删除: %s", oldPath))
+	}
+}
+
+// CleanupOldUpdater 删除上次自更新留下的旧版本更新器
+func CleanupOldUpdater() {
+	currentExe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	if currentExe, err = filepath.EvalSymlinks(currentExe); err != nil {
+		return
+	}
+
+	oldPath := currentExe + UpdaterOldSuffix
+	if !Exists(oldPath) {
+		return
+	}
+	if err := os.Remove(oldPath); err != nil {
+		slog.Warn(fmt.Sprintf("删除旧版本更新器失败: %v", err))
+	}
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeScript 写入可执行的 shell 脚本
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readScript 读取脚本内容
+func readScript(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "update")
+	writeScript(t, path, "echo 1.0.0")
+	writeScript(t, path+UpdaterNewSuffix, "echo 1.0.1")
+
+	if err := replaceExecutable(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := readScript(t, path); got != "#!/bin/sh\necho 1.0.1\n" {
+		t.Errorf("未替换为新版本: %q", got)
+	}
+	if Exists(path+UpdaterNewSuffix) || Exists(path+UpdaterOldSuffix) {
+		t.Error("替换后不应残留 .new 或 .old 文件")
+	}
+}
+
+func TestReplaceExecutableRollback(t *testing.T) {
+	for name, body := range map[string]string{
+		"失败退出": "exit 1",
+		"没有输出": "true",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "update")
+			writeScript(t, path, "echo 1.0.0")
+			writeScript(t, path+UpdaterNewSuffix, body)
+
+			if err := replaceExecutable(path); err == nil {
+				t.Fatal("新版本检查失败时应返回错误")
+			}
+			if got := readScript(t, path); got != "#!/bin/sh\necho 1.0.0\n" {
+				t.Errorf("未恢复旧版本: %q", got)
+			}
+			if !Exists(path + UpdaterNewSuffix) {
+				t.Error("检查失败的新版本应保留为 .new 文件")
+			}
+		})
+	}
+}
+
+func TestReplaceExecutableNothingStaged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "update")
+	writeScript(t, path, "echo 1.0.0")
+	if err := replaceExecutable(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := readScript(t, path); got != "#!/bin/sh\necho 1.0.0\n" {
+		t.Errorf("没有新版本时不应修改: %q", got)
+	}
+}
+
+func TestStageSelfUpdate(t *testing.T) {
+	name, ok := currentUpdaterName()
+	if !ok {
+		t.Skip("无法获取当前可执行文件名")
+	}
+	extractDir, targetDir := t.TempDir(), t.TempDir()
+
+	if ready, err := StageSelfUpdate(extractDir, targetDir); err != nil || ready {
+		t.Fatalf("更新包中没有更新器时 StageSelfUpdate = %v, %v", ready, err)
+	}
+
+	if err := os.Mkdir(filepath.Join(extractDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeScript(t, filepath.Join(extractDir, "bin", name), "echo 1.0.1")
+	ready, err := StageSelfUpdate(extractDir, targetDir)
+	if err != nil || !ready {
+		t.Fatalf("StageSelfUpdate = %v, %v，期望已准备好新版本", ready, err)
+	}
+	// 删除解压目录后仍可替换
+	if err := os.RemoveAll(extractDir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readScript(t, filepath.Join(targetDir, name+UpdaterNewSuffix)); got != "#!/bin/sh\necho 1.0.1\n" {
+		t.Errorf("待替换的新版本内容 = %q", got)
+	}
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"club.xiaojiawei/hs-script-update/internal/component"
+	"club.xiaojiawei/hs-script-update/internal/config"
 	"club.xiaojiawei/hs-script-update/internal/core"
 	"club.xiaojiawei/hs-script-update/internal/gui"
+	"club.xiaojiawei/hs-script-update/internal/install"
+	"club.xiaojiawei/hs-script-update/internal/logger"
+	"club.xiaojiawei/hs-script-update/internal/progress"
 	"club.xiaojiawei/hs-script-update/internal/repository"
 	"club.xiaojiawei/hs-script-update/internal/utils"
 )
@@ -15,27 +27,76 @@ const version = "1.0.1"
 
 func main() {
 	helpFlag := flag.Bool("help", false, "显示帮助信息")
+	versionFlag := flag.Bool("version", false, "显示版本号")
 
 	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
 	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
 	latestCmd := flag.NewFlagSet("latest", flag.ExitOnError)
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	pluginCmd := flag.NewFlagSet("plugin", flag.ExitOnError)
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	installedCmd := flag.NewFlagSet("installed", flag.ExitOnError)
 
 	// update 命令的参数
 	updatePause := updateCmd.Bool("pause", false, "主程序是否处于暂停状态")
 	updatePid := updateCmd.Int("pid", 0, "主程序进程 PID（等待其退出后再更新）")
 	updateMainProgram := updateCmd.String("main-program", "", "主程序路径（更新完成后启动）")
 	updateNoGUI := updateCmd.Bool("nogui", false, "使用 GUI 界面显示更新进度")
+	updateFullExtract := updateCmd.Bool("full-extract", false, "先完整解压到临时目录再复制（不使用流式更新）")
+	updateMaxSize := updateCmd.Int64("max-extract-size", config.MaxExtractSize>>20, "解压后的最大总大小（MB）")
+	updateMaxEntries := updateCmd.Int("max-entries", config.MaxArchiveEntries, "更新包的最大条目数")
+	updateMaxRatio := updateCmd.Int64("max-ratio", config.MaxCompressionRatio, "更新包的最大压缩比")
+	updateMaxDepth := updateCmd.Int("max-depth", config.MaxArchivePathDepth, "更新包条目路径的最大层级")
+	updateHealthCheck := updateCmd.Bool("health-check", false, "更新完成后以健康检查参数启动主程序")
+	updateHealthTimeout := updateCmd.Int("health-timeout", 30, "健康检查超时时间（秒）")
+	updateFromVersion := updateCmd.String("from-version", "", "更新前的版本（用于选择配置迁移，默认读取安装目录中的记录）")
+	updateToVersion := updateCmd.String("to-version", "", "更新后的版本（默认从更新包文件名中识别）")
+	updateJSON := updateCmd.Bool("json", false, "更新结束后输出 JSON 格式的结果摘要")
+	updateSource := updateCmd.String("source", "", "更新包来源（如仓库源或下载地址），记录到更新历史中")
+	updateShutdownTimeout := updateCmd.Int("shutdown-timeout", config.ShutdownTimeout, "等待主程序退出的超时时间（秒）")
+	updateNoKill := updateCmd.Bool("no-kill", false, "主程序超时未退出时中止更新，不强制结束主程序")
+	updateMainArgs := updateCmd.String("main-args", "", "重新启动主程序时的参数（默认沿用主程序进程的启动参数）")
+	updateResumePaused := updateCmd.Bool("resume-paused", false, "主程序更新前处于暂停状态时，更新后恢复运行")
+	updateOnLocked := updateCmd.String("on-locked", utils.LockPrompt, "文件被占用时的处理方式 (prompt/kill/skip/retry:<n>,<interval>/fail/move-aside)")
+	updateSwitchVariant := updateCmd.Bool("switch-variant", false, "允许使用另一版本类型的更新包切换安装目录的版本类型（JVM/Native）")
+	updateWait := updateCmd.Bool("wait", false, "同一目录已有更新正在进行时等待其完成（默认直接报错退出）")
+	updateDryRun := updateCmd.Bool("dry-run", false, "只输出将要执行的操作，不修改安装目录")
+	updateProgress := updateCmd.String("progress", "", "以 JSON 行输出更新事件 (jsonl[:fd|:file])，供宿主程序显示进度")
 
 	checkDev := checkCmd.Bool("d", false, "检查开发版")
 	checkNative := checkCmd.Bool("n", false, "Native 版本")
 	checkInteractive := checkCmd.Bool("i", false, "交互模式（控制台显示）")
 	checkRepo := checkCmd.String("r", "gitee", "仓库源 (gitee/github)")
+	checkTarget := checkCmd.String("target", "", "安装目录（未指定版本时从安装目录中识别版本和类型）")
 
 	latestDev := latestCmd.Bool("d", false, "获取开发版")
 	latestNative := latestCmd.Bool("n", false, "Native 版本")
 	latestInteractive := latestCmd.Bool("i", false, "交互模式（控制台显示）")
 	latestRepo := latestCmd.String("r", "gitee", "仓库源 (gitee/github)")
 
+	verifyHealthCheck := verifyCmd.Bool("health-check", false, "以健康检查参数启动主程序")
+	verifyHealthTimeout := verifyCmd.Int("health-timeout", 30, "健康检查超时时间（秒）")
+	verifyMainProgram := verifyCmd.String("main-program", "", "主程序路径（默认为安装目录下的 hs-script.exe）")
+
+	pluginTarget := pluginCmd.String("target", "", "安装目录（默认为更新器所在目录）")
+	pluginCoreVersion := pluginCmd.String("core-version", "", "主程序版本（默认读取安装目录中的记录）")
+	pluginDev := pluginCmd.Bool("d", false, "包括预发布版本")
+	pluginJSON := pluginCmd.Bool("json", false, "输出 JSON")
+
+	historyJSON := historyCmd.Bool("json", false, "输出 JSON")
+	historyLimit := historyCmd.Int("n", 0, "只显示最近的 n 条记录（0 表示全部）")
+
+	installedJSON := installedCmd.Bool("json", false, "输出 JSON")
+
+	updateLog := addLogFlags(updateCmd)
+	checkLog := addLogFlags(checkCmd)
+	latestLog := addLogFlags(latestCmd)
+	verifyLog := addLogFlags(verifyCmd)
+	pluginLog := addLogFlags(pluginCmd)
+	historyLog := addLogFlags(historyCmd)
+	installedLog := addLogFlags(installedCmd)
+	defer logger.Close()
+
 	// 如果没有参数，显示帮助
 	if len(os.Args) < 2 {
 		showHelp()
@@ -48,32 +109,161 @@ func main() {
 		showHelp()
 		return
 	}
+	// 自更新时以 --version 检查新版本更新器能否正常启动
+	if *versionFlag {
+		fmt.Println(version)
+		return
+	}
+
+	// 清理上次自更新留下的旧版本更新器
+	utils.CleanupOldUpdater()
+
+	// 按 Ctrl+C 取消正在进行的操作
+	ctx, cancel := interruptContext()
+	defer cancel()
 
 	switch os.Args[1] {
 	case "update":
 		updateCmd.Parse(os.Args[2:])
 		if updateCmd.NArg() < 2 {
 			fmt.Println("错误: update 命令需要两个参数")
-			fmt.Println("使用方法: hs-script-updater update <zipPath> <targetDir> [--pause] [--pid=<pid>] [--main-program=<path>] [--gui]")
+			fmt.Println("使用方法: hs-script-updater update <packagePath> <targetDir> [--pause] [--pid=<pid>] [--main-program=<path>] [--gui]")
 			os.Exit(1)
 		}
-		zipPath := updateCmd.Arg(0)
+		packagePath := updateCmd.Arg(0)
 		targetDir := updateCmd.Arg(1)
-		handleUpdate(zipPath, targetDir, *updatePause, *updatePid, *updateMainProgram, !(*updateNoGUI))
+		setupLogging(updateLog, targetDir)
+		config.MaxExtractSize = *updateMaxSize << 20
+		config.MaxArchiveEntries = *updateMaxEntries
+		config.MaxCompressionRatio = *updateMaxRatio
+		config.MaxArchivePathDepth = *updateMaxDepth
+		onLocked, err := utils.ParseLockPolicy(*updateOnLocked)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		utils.OnLocked = onLocked
+		var progressOutput *progress.JSONLWriter
+		if *updateProgress != "" {
+			if progressOutput, err = openProgressOutput(*updateProgress); err != nil {
+				fmt.Printf("错误: %v\n", err)
+				os.Exit(1)
+			}
+			defer progressOutput.Close()
+		}
+		var mainArgs []string
+		if flagPassed(updateCmd, "main-args") {
+			if mainArgs, err = utils.SplitArgs(*updateMainArgs); err != nil {
+				fmt.Printf("错误: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		updateOpts := updateOptions{
+			fullExtract:     *updateFullExtract,
+			healthCheck:     *updateHealthCheck,
+			healthTimeout:   time.Duration(*updateHealthTimeout) * time.Second,
+			fromVersion:     *updateFromVersion,
+			toVersion:       *updateToVersion,
+			json:            *updateJSON,
+			source:          *updateSource,
+			shutdownTimeout: time.Duration(*updateShutdownTimeout) * time.Second,
+			noKill:          *updateNoKill,
+			mainArgs:        mainArgs,
+			resumePaused:    *updateResumePaused,
+			switchVariant:   *updateSwitchVariant,
+			dryRun:          *updateDryRun,
+			wait:            *updateWait,
+			progress:        progressOutput,
+		}
+		handleUpdate(ctx, packagePath, targetDir, *updatePause, *updatePid, *updateMainProgram, !(*updateNoGUI), updateOpts)
 
 	case "check":
 		checkCmd.Parse(os.Args[2:])
-		if checkCmd.NArg() < 1 {
-			fmt.Println("错误: check 命令需要一个参数")
-			fmt.Println("使用方法: hs-script-updater check <version> [-d] [-n] [-i]")
+		if checkCmd.NArg() < 1 && *checkTarget == "" {
+			fmt.Println("错误: check 命令需要当前版本或 --target 参数")
+			fmt.Println("使用方法: hs-script-updater check <version> [-d] [-n] [-i] 或 hs-script-updater check --target=<targetDir> [-d] [-i]")
 			os.Exit(1)
 		}
 		currentVersion := checkCmd.Arg(0)
-		handleCheck(currentVersion, *checkDev, *checkNative, *checkInteractive, *checkRepo)
+		native := *checkNative
+		if *checkTarget != "" {
+			setupLogging(checkLog, *checkTarget)
+			info, err := install.Probe(*checkTarget)
+			if err != nil {
+				fmt.Printf("识别安装目录失败: %v\n", err)
+				os.Exit(1)
+			}
+			if currentVersion == "" {
+				if info.Version == "" {
+					fmt.Println("无法识别安装目录中的版本，请直接指定版本号")
+					os.Exit(1)
+				}
+				currentVersion = info.Version
+			}
+			// 未显式指定 -n 时使用安装目录的类型
+			if !flagPassed(checkCmd, "n") {
+				native = !info.IsJVM()
+			}
+		} else {
+			setupLogging(checkLog, executableDir())
+		}
+		handleCheck(ctx, currentVersion, *checkDev, native, *checkInteractive, *checkRepo)
 
 	case "latest":
 		latestCmd.Parse(os.Args[2:])
-		handleLatest(*latestDev, *latestNative, *latestInteractive, *latestRepo)
+		setupLogging(latestLog, executableDir())
+		handleLatest(ctx, *latestDev, *latestNative, *latestInteractive, *latestRepo)
+
+	case "verify":
+		verifyArgs := parseArgs(verifyCmd, os.Args[2:])
+		if len(verifyArgs) < 1 {
+			fmt.Println("错误: verify 命令需要一个参数")
+			fmt.Println("使用方法: hs-script-updater verify <targetDir> [--health-check] [--health-timeout=<秒>] [--main-program=<path>]")
+			os.Exit(1)
+		}
+		setupLogging(verifyLog, verifyArgs[0])
+		handleVerify(verifyArgs[0], *verifyHealthCheck, time.Duration(*verifyHealthTimeout)*time.Second, *verifyMainProgram)
+
+	case "plugin":
+		if len(os.Args) < 3 {
+			fmt.Println("错误: plugin 命令需要子命令")
+			fmt.Println("使用方法: hs-script-updater plugin <list|check|update> [id] [--target=<dir>] [--json]")
+			os.Exit(1)
+		}
+		pluginArgs := parseArgs(pluginCmd, os.Args[3:])
+		targetDir := *pluginTarget
+		if targetDir == "" {
+			targetDir = executableDir()
+		}
+		setupLogging(pluginLog, targetDir)
+		manager := core.NewPluginManager(targetDir, *pluginCoreVersion, *pluginDev)
+		var id string
+		if len(pluginArgs) > 0 {
+			id = pluginArgs[0]
+		}
+		handlePlugin(ctx, manager, os.Args[2], id, *pluginJSON)
+
+	case "installed":
+		var targetDir string
+		if installedArgs := parseArgs(installedCmd, os.Args[2:]); len(installedArgs) > 0 {
+			targetDir = installedArgs[0]
+		}
+		if targetDir == "" {
+			targetDir = executableDir()
+		}
+		setupLogging(installedLog, targetDir)
+		handleInstalled(targetDir, *installedJSON)
+
+	case "history":
+		var targetDir string
+		if historyArgs := parseArgs(historyCmd, os.Args[2:]); len(historyArgs) > 0 {
+			targetDir = historyArgs[0]
+		}
+		if targetDir == "" {
+			targetDir = executableDir()
+		}
+		setupLogging(historyLog, targetDir)
+		handleHistory(targetDir, *historyJSON, *historyLimit)
 
 	case "--help", "-h", "help":
 		showHelp()
@@ -85,9 +275,81 @@ func main() {
 	}
 }
 
+// updateOptions update 命令的可选参数
+type updateOptions struct {
+	fullExtract     bool
+	healthCheck     bool
+	healthTimeout   time.Duration
+	fromVersion     string
+	toVersion       string
+	json            bool
+	source          string
+	shutdownTimeout time.Duration
+	noKill          bool
+	// mainArgs 为 nil 时沿用主程序进程的启动参数
+	mainArgs      []string
+	resumePaused  bool
+	switchVariant bool
+	dryRun        bool
+	wait          bool
+	// progress 不为 nil 时以 JSON 行输出更新事件，不显示 GUI 窗口和结果对话框
+	progress *progress.JSONLWriter
+}
+
+// openProgressOutput 解析 --progress=jsonl[:fd|:file] 并打开输出
+// 输出到标准输出时日志改为输出到标准错误，避免与 JSON 行混在一起
+func openProgressOutput(value string) (*progress.JSONLWriter, error) {
+	format, target, _ := strings.Cut(value, ":")
+	if format != "jsonl" {
+		return nil, fmt.Errorf("未知的进度输出格式: %q（支持 jsonl[:fd|:file]）", value)
+	}
+	writer, err := progress.NewJSONLWriter(target)
+	if err != nil {
+		return nil, err
+	}
+	if writer.Stdout() {
+		logger.SetConsole(os.Stderr)
+	}
+	return writer, nil
+}
+
+// interruptContext 返回收到 Ctrl+C (SIGINT) 时取消的 context，取消后恢复默认处理，再次按下时直接退出
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "正在取消，再次按 Ctrl+C 强制退出...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // handleUpdate 处理更新命令
-func handleUpdate(zipPath, targetDir string, pause bool, pid int, mainProgram string, useGUI bool) {
-	updater := core.NewUpdater(zipPath, targetDir, pause, pid, mainProgram)
+func handleUpdate(ctx context.Context, packagePath, targetDir string, pause bool, pid int, mainProgram string, useGUI bool, opts updateOptions) {
+	updater := core.NewUpdater(packagePath, targetDir, pause, pid, mainProgram)
+	updater.SetStreaming(!opts.fullExtract)
+	updater.SetHealthCheck(opts.healthCheck, opts.healthTimeout)
+	updater.SetVersions(opts.fromVersion, opts.toVersion)
+	updater.SetSource(opts.source)
+	updater.SetShutdown(opts.shutdownTimeout, opts.noKill)
+	if opts.mainArgs != nil {
+		updater.SetMainArgs(opts.mainArgs)
+	}
+	updater.SetResumePaused(opts.resumePaused)
+	updater.SetSwitchVariant(opts.switchVariant)
+	updater.SetDryRun(opts.dryRun)
+	updater.SetWait(opts.wait)
+
+	// 演练结果输出到控制台，宿主程序根据 JSON 行事件自行显示进度
+	if opts.dryRun || opts.progress != nil {
+		useGUI = false
+	}
 
 	if useGUI {
 		// GUI 模式
@@ -97,15 +359,23 @@ func handleUpdate(zipPath, targetDir string, pause bool, pid int, mainProgram st
 			fmt.Println("回退到控制台模式...")
 			useGUI = false
 		} else {
-			// 设置进度回调
+			// 设置进度回调，取消按钮和关闭窗口都会取消更新
 			updater.SetProgressCallback(window)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			window.SetOnCancel(cancel)
 
 			// 在后台执行更新
 			go func() {
-				if err := updater.Update(); err != nil {
+				err := updater.Update(ctx)
+				window.Finish()
+				if opts.json {
+					fmt.Println(updater.Summary().JSON())
+				}
+				// 取消时更新器已提示安装目录已恢复
+				if err != nil && !errors.Is(err, context.Canceled) {
 					errorMsg := fmt.Sprintf("更新失败:\n\n%v", err)
 					window.ShowError(errorMsg)
-					fmt.Printf("\n更新失败: %v\n", err)
 				}
 			}()
 
@@ -115,15 +385,312 @@ func handleUpdate(zipPath, targetDir string, pause bool, pid int, mainProgram st
 		}
 	}
 
-	// 控制台模式
-	if err := updater.Update(); err != nil {
+	// 控制台模式，进度输出到控制台
+	if opts.progress != nil {
+		updater.SetProgressCallback(opts.progress)
+	} else {
+		updater.SetProgressCallback(gui.NewConsoleProgress())
+	}
+	err := updater.Update(ctx)
+	if opts.json {
+		if opts.progress != nil {
+			opts.progress.Summary(updater.Summary())
+		} else {
+			fmt.Println(updater.Summary().JSON())
+		}
+	}
+	// 结果已通过 JSON 行的 error 事件输出
+	if err != nil && opts.progress != nil {
+		os.Exit(1)
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("更新已取消，安装目录已恢复到更新前的状态")
+		os.Exit(1)
+	}
+	if err != nil {
 		errorMsg := fmt.Sprintf("更新失败:\n\n%v", err)
 		utils.ShowErrorBox(errorMsg, "更新失败")
-		fmt.Printf("\n更新失败: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// handleVerify 处理校验安装目录命令
+func handleVerify(targetDir string, healthCheck bool, healthTimeout time.Duration, mainProgram string) {
+	if !utils.Exists(targetDir) {
+		fmt.Printf("目标目录不存在: %s\n", targetDir)
+		os.Exit(1)
+	}
+
+	expected := map[string]string{}
+	manifest, err := core.LoadInstallManifest(targetDir)
+	if err != nil {
+		fmt.Printf("警告: 读取文件清单失败，仅检查入口文件: %v\n", err)
+	} else {
+		expected = manifest.Files
+	}
+
+	verifier := core.NewVerifier(targetDir)
+	isJvmVersion := utils.DetectJVMVersion(targetDir)
+	if info, err := install.Probe(targetDir); err == nil {
+		isJvmVersion = info.IsJVM()
+	}
+	result := verifier.Verify(expected, isJvmVersion)
+	if healthCheck {
+		result.Checked++
+		if err := verifier.HealthCheck(mainProgram, healthTimeout); err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("健康检查失败: %v", err))
+		}
+	}
+
+	if !result.OK() {
+		fmt.Println((&core.VerifyError{Result: result}).Error())
+		os.Exit(1)
+	}
+	fmt.Printf("校验通过: 共 %d 项\n", result.Checked)
+}
+
+// handlePlugin 处理插件命令
+func handlePlugin(ctx context.Context, manager *core.PluginManager, action, id string, jsonOutput bool) {
+	var result interface{}
+	var err error
+	switch action {
+	case "list":
+		var infos []core.PluginInfo
+		if infos, err = manager.List(); err == nil && !jsonOutput {
+			for _, info := range infos {
+				fmt.Printf("%-32s %-12s %-20s %s\n", info.ID, info.Version, info.Requires, info.Source)
+				if info.Error != "" {
+					fmt.Printf("  描述文件无效: %s\n", info.Error)
+				}
+			}
+		}
+		result = infos
+
+	case "check":
+		var updates []core.PluginUpdate
+		if updates, err = manager.Check(ctx, id); err == nil && !jsonOutput {
+			if manager.CoreVersion() == "" {
+				fmt.Println("警告: 无法确定主程序版本，不检查兼容性（可使用 --core-version 指定）")
+			}
+			for _, update := range updates {
+				if update.HasUpdate {
+					fmt.Printf("%-32s %s -> %s\n", update.ID, update.CurrentVersion, update.LatestVersion)
+				} else {
+					fmt.Printf("%-32s %s (%s)\n", update.ID, update.CurrentVersion, update.Reason)
+				}
+			}
+		}
+		result = updates
+
+	case "update":
+		if id == "" {
+			fmt.Println("错误: plugin update 需要插件 id")
+			os.Exit(1)
+		}
+		var update *core.PluginUpdate
+		if update, err = manager.Update(ctx, id); err == nil && !jsonOutput && !update.HasUpdate {
+			fmt.Printf("插件 %s 无需更新: %s\n", id, update.Reason)
+		}
+		result = update
+
+	default:
+		fmt.Printf("未知的插件子命令: %s\n", action)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("插件操作失败: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		jsonBytes, err := json.Marshal(result)
+		if err != nil {
+			fmt.Printf("生成JSON失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+	}
+}
+
+// handleInstalled 处理查看安装信息命令
+func handleInstalled(targetDir string, jsonOutput bool) {
+	info, err := install.Probe(targetDir)
+	if err != nil {
+		fmt.Printf("识别安装目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	state, err := component.LoadState(targetDir)
+	if err != nil {
+		fmt.Printf("读取组件状态失败: %v\n", err)
+		state = &component.State{}
+	}
+
+	if jsonOutput {
+		jsonBytes, err := json.Marshal(struct {
+			*install.Info
+			Components []component.Component `json:"components,omitempty"`
+		}{info, state.Components})
+		if err != nil {
+			fmt.Printf("生成JSON失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+
+	fmt.Printf("安装目录: %s\n", targetDir)
+	fmt.Printf("版本: %s", displayOrUnknown(info.Version))
+	if info.VersionSource != "" {
+		fmt.Printf("（来源: %s）", info.VersionSource)
+	}
+	fmt.Println()
+	fmt.Printf("类型: %s（来源: %s）\n", info.Variant, info.VariantSource)
+	for _, c := range state.Components {
+		fmt.Printf("组件 %-13s 版本: %-14s 文件: %-5d 更新时间: %s\n", c.Name, displayOrUnknown(c.Version), c.Files,
+			c.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+}
+
+// parseArgs 解析参数，允许标志出现在位置参数之后（如 plugin update <id> --json），返回位置参数
+// flag 包遇到第一个位置参数就停止解析，这里取出该参数后继续解析剩余部分，"--" 之后的参数都视为位置参数
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// flagPassed 判断命令行中是否显式指定了参数
+func flagPassed(fs *flag.FlagSet, name string) bool {
+	passed := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// handleHistory 处理查看更新历史命令
+func handleHistory(targetDir string, jsonOutput bool, limit int) {
+	entries, err := core.LoadHistory(targetDir)
+	if err != nil {
+		fmt.Printf("读取更新历史失败: %v\n", err)
+		os.Exit(1)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if jsonOutput {
+		jsonBytes, err := json.Marshal(entries)
+		if err != nil {
+			fmt.Printf("生成JSON失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("没有更新记录")
+		return
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s  %-7s  %s -> %s (%s)  %d 个文件  耗时 %.1fs\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Result,
+			displayOrUnknown(entry.FromVersion), displayOrUnknown(entry.ToVersion), entry.Variant,
+			entry.FilesChanged, float64(entry.Duration)/1000)
+		fmt.Printf("    更新包: %s\n", entry.Package)
+		if entry.PackageHash != "" {
+			fmt.Printf("    SHA-256: %s\n", entry.PackageHash)
+		}
+		if entry.Source != "" {
+			fmt.Printf("    来源: %s\n", entry.Source)
+		}
+		if entry.Error != "" {
+			fmt.Printf("    错误: %s\n", entry.Error)
+		}
+	}
+}
+
+// displayOrUnknown 为空时显示为未知
+func displayOrUnknown(value string) string {
+	if value == "" {
+		return "未知"
+	}
+	return value
+}
+
+// logFlags 日志相关参数
+type logFlags struct {
+	level  *string
+	file   *string
+	format *string
+}
+
+// addLogFlags 为命令添加日志参数
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		level:  fs.String("log-level", "info", "日志级别 (debug/info/warn/error)"),
+		file:   fs.String("log-file", "", "日志文件路径（默认为安装目录下的 log/updater.log，off 表示不写文件）"),
+		format: fs.String("log-format", "text", "日志文件格式 (text/json)"),
+	}
+}
+
+// setupLogging 初始化日志，dir 为默认日志文件所在的安装目录
+// 安装目录不可写时改用用户缓存目录
+func setupLogging(flags *logFlags, dir string) {
+	level, err := logger.ParseLevel(*flags.level)
+	if err != nil {
+		fmt.Printf("%v，使用 info\n", err)
+	}
+
+	options := logger.Options{
+		Level:      level,
+		File:       *flags.file,
+		Format:     *flags.format,
+		MaxSize:    config.LogMaxSize,
+		MaxBackups: config.LogMaxBackups,
+	}
+	if options.File == "off" {
+		options.File = ""
+	} else if options.File == "" {
+		options.File = filepath.Join(dir, config.LogDir, config.LogFileName)
+		if err := logger.Init(options); err == nil {
+			return
+		}
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			options.File = ""
+		} else {
+			options.File = filepath.Join(cacheDir, config.ProgramName+"-updater", config.LogFileName)
+		}
+	}
+
+	if err := logger.Init(options); err != nil {
+		fmt.Printf("警告: %v\n", err)
+	}
+}
+
+// executableDir 返回更新器所在目录
+func executableDir() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(exePath)
+}
+
 // createRepository 根据参数创建仓库实例
 func createRepository(repoName string) repository.Repository {
 	switch repoName {
@@ -138,11 +705,11 @@ func createRepository(repoName string) repository.Repository {
 }
 
 // handleCheck 处理检查版本命令
-func handleCheck(currentVersion string, dev, native, interactive bool, repoName string) {
+func handleCheck(ctx context.Context, currentVersion string, dev, native, interactive bool, repoName string) {
 	repo := createRepository(repoName)
 	checker := core.NewVersionChecker(repo)
 
-	result, err := checker.CheckVersion(currentVersion, dev, native, interactive)
+	result, err := checker.CheckVersion(ctx, currentVersion, dev, native, interactive)
 	if err != nil {
 		fmt.Printf("检查更新失败: %v\n", err)
 		os.Exit(1)
@@ -155,11 +722,11 @@ func handleCheck(currentVersion string, dev, native, interactive bool, repoName
 }
 
 // handleLatest 处理获取最新版本命令
-func handleLatest(dev, native, interactive bool, repoName string) {
+func handleLatest(ctx context.Context, dev, native, interactive bool, repoName string) {
 	repo := createRepository(repoName)
 	checker := core.NewVersionChecker(repo)
 
-	result, err := checker.GetLatestVersion(dev, native, interactive)
+	result, err := checker.GetLatestVersion(ctx, dev, native, interactive)
 	if err != nil {
 		fmt.Printf("获取最新版本失败: %v\n", err)
 		os.Exit(1)
@@ -179,20 +746,35 @@ func showHelp() {
   hs-script-updater <command> [arguments]
 
 命令:
-  update <zipPath> <targetDir> [options]    执行更新
+  update <packagePath> <targetDir> [options]    执行更新（支持 zip/tar/tar.gz/tar.zst）
   check <version> [-d] [-n] [-i] [-r repo]  检查版本更新（需要当前版本号）
+  check --target=<dir> [-d] [-i] [-r repo]  检查版本更新（从安装目录中识别当前版本和类型）
+  installed [targetDir] [--json]            查看安装目录的版本、类型和各组件状态
   latest [-d] [-n] [-i] [-r repo]           获取最新版本信息
+  verify <targetDir> [options]              校验安装目录的完整性
+  plugin <list|check|update> [id] [options] 管理第三方插件
+  history [targetDir] [--json] [-n <n>]     查看安装目录的更新历史
 
 示例:
   # 执行更新
   hs-script-updater update "D:\hs-script_v4.13.0-GA.zip" "D:\hs-script"
 
+  # 使用 tar.zst 更新包执行更新
+  hs-script-updater update "D:\hs-script-native_v4.13.0-GA.tar.zst" "D:\hs-script"
+
   # 执行更新（使用 GUI 界面）
   hs-script-updater update "D:\hs-script_v4.13.0-GA.zip" "D:\hs-script" --gui
 
   # 执行更新（等待主程序退出，更新后自动启动）
   hs-script-updater update "D:\hs-script_v4.13.0-GA.zip" "D:\hs-script" --pid=12345 --pause --main-program="D:\hs-script\hs-script.exe"
 
+  # 将 JVM 版安装目录切换为 Native 版（先演练，确认无误后再执行）
+  hs-script-updater update --switch-variant --dry-run "D:\hs-script-native_v4.13.0-GA.zip" "D:\hs-script"
+  hs-script-updater update --switch-variant "D:\hs-script-native_v4.13.0-GA.zip" "D:\hs-script"
+
+  # 校验安装目录，并以健康检查参数启动主程序
+  hs-script-updater verify "D:\hs-script" --health-check
+
   # 获取最新 JVM 版本（返回 JSON，默认 Gitee）
   hs-script-updater latest
 
@@ -221,18 +803,140 @@ func showHelp() {
   hs-script-updater check "v4.13.0-GA" -i
 
 update 命令选项:
-  --pid=<pid>                  主程序进程 PID（等待其退出后再更新）
-  --pause                      主程序是否处于暂停状态
-  --main-program=<path>        主程序路径（更新完成后自动启动）
+  --pid=<pid>                  主程序进程 PID（请求其退出，等待退出后再更新）
+  --shutdown-timeout=<秒>      等待主程序退出的超时时间（默认 30 秒），超时后强制结束主程序
+  --no-kill                    主程序超时未退出时中止更新，不强制结束主程序
+  --on-locked=<policy>         文件被占用时的处理方式，处理结果记录在 --json 摘要的 lockDecisions 中:
+                                 prompt（默认）弹窗询问，5 分钟无响应时视为拒绝，跳过该文件
+                                 kill 直接结束安装目录中的占用进程
+                                 skip 跳过被占用的文件（保持原内容，不参与校验，记录在摘要的 skippedFiles 中）
+                                 retry:<n>,<interval> 按退避间隔重试 n 次（间隔每次翻倍，如 retry:5,2s）
+                                 fail 中止更新
+                                 move-aside 将被占用的文件重命名移开后写入新文件，旧文件在重启后删除
+  --pause                      主程序是否处于暂停状态（更新后以 --pause 参数启动主程序，保持暂停）
+  --resume-paused              主程序处于暂停状态时，更新后恢复运行（不传递 --pause 参数）
+  --main-program=<path>        主程序路径（更新完成后在安装目录中以独立进程启动）
+  --main-args="<args>"         启动主程序的参数，可用引号包含空格（默认读取 --pid 进程的启动参数和环境变量）
   --gui                        使用 GUI 界面显示更新进度
+  --full-extract               先完整解压到临时目录再复制（默认直接从更新包流式写入）
+  --max-extract-size=<MB>      解压后的最大总大小（默认 4096 MB，0 表示不限制）
+  --max-entries=<n>            更新包的最大条目数（默认 100000）
+  --max-ratio=<n>              更新包的最大压缩比（默认 200）
+  --max-depth=<n>              更新包条目路径的最大层级（默认 32）
+  --health-check               更新完成后以 --health-check 参数启动主程序进行检查
+  --health-timeout=<秒>        健康检查超时时间（默认 30 秒）
+  --from-version=<version>     更新前的版本（默认读取 data/install-manifest.json 中的记录）
+  --to-version=<version>       更新后的版本（默认从更新包文件名中识别）
+  --json                       更新结束后在最后一行输出 JSON 格式的结果摘要（包含插件检查结果），
+                               与 --progress=jsonl 同时使用时作为 summary 事件输出
+  --source=<source>            更新包来源（如仓库源或下载地址），记录到 data/update-history.jsonl
+  --switch-variant             允许使用另一版本类型的更新包（JVM 版与 Native 版互相切换），沿用保留目录，
+                               移除旧版本类型特有的文件（JVM 版: lib/*.jar、jre），切换前的文件备份到 data/backup
+  --dry-run                    只输出将要写入、保留、合并和移除的文件，不修改安装目录
+  --wait                       同一安装目录已有更新器在运行时等待其完成（默认报错“已有更新正在进行”并退出），
+                               锁文件为 data/update.lock，持有锁的进程已退出时自动清理
+  --progress=jsonl[:fd|:file]  以每行一个 JSON 对象输出更新事件，供宿主程序自行显示进度（不显示 GUI 窗口），
+                               默认输出到标准输出（此时日志输出到标准错误），也可指定文件描述符或文件路径
+
+更新规则:
+  更新包或安装目录根目录下的 update-rules.json 按顺序声明规则，第一条匹配的规则生效，
+  安装目录中的规则优先于更新包中的规则，例如:
+    {"rules": [{"pattern": "config/app.json", "action": "merge"}, {"pattern": "logs", "action": "exclude"}]}
+  pattern 相对于安装目录，支持 *、? 和 **，匹配目录时同时作用于目录下的所有内容
+  action: include（覆盖）、exclude（不复制）、preserve（仅在不存在时复制）、merge（合并配置文件）
+
+退出握手:
+  指定 --pid 时，更新器写入 data/shutdown.request（JSON: pid、updaterPid、reason、requestedAt）请求主程序退出，
+  主程序检测到该文件后应写入 data/shutdown.ack（JSON: {"pid": <主程序 PID>}，也可为空文件）确认，
+  保存数据后自行退出。超过 --shutdown-timeout 仍未退出时才强制结束，并记录到日志
+
+进度事件 (--progress=jsonl):
+  每行一个 JSON 对象，type 为事件类型，time 为时间:
+    phase-start / phase-end  阶段开始和结束，phase 为 prepare/download/backup/extract/copy/finalize
+    progress                 总体进度 percent、当前阶段的 bytesDone/bytesTotal、bytesPerSecond、etaMs 和 file
+    file                     阶段中的一个文件处理完成
+    status / warning         状态文本和警告 (message)
+    prompt-required          更新器弹窗询问用户（如文件被占用），file 和 question 为询问内容
+    success                  更新成功 (message)
+    error                    更新失败，code 为 canceled/locked/unsafe-package/preflight/verify/failed
+  例如:
+    {"type":"progress","time":"...","phase":"copy","bytesDone":1048576,"bytesTotal":4194304,"percent":32.5,"bytesPerSecond":524288,"etaMs":6000,"file":"lib/app.jar"}
+
+取消更新:
+  GUI 模式下点击“取消更新”或关闭窗口，控制台模式下按 Ctrl+C，可以在文件写入完成前取消更新。
+  写入前会将被覆盖的文件备份到 data/backup，取消或写入失败时恢复并删除新增的文件，随后重新启动主程序；
+  更新成功后删除备份（切换版本类型时保留）。文件写入完成后不再响应取消
+
+组件:
+  安装目录分为 app（主程序）、runtime（jre 目录中的 Java 运行时）、base-plugins（基础插件）三个组件，
+  各组件的版本和校验值记录在 data/components.json。更新包根目录下的 update-manifest.json 可引用运行时版本，
+  版本未变化时不复制运行时；更新包中不包含运行时时从 url 单独下载，例如:
+    {"files": {...}, "components": {"runtime": {"version": "21.0.5", "url": "https://example.com/jre-21.0.5.zip", "sha256": "..."}}}
+
+配置迁移:
+  更新包根目录下的 update-migrations.json 按版本声明迁移步骤，复制文件后执行，执行前备份原配置到 data/backup，
+  任一步骤失败时恢复备份并中止更新，例如:
+    {"migrations": [{"to": "v4.14.0-GA", "steps": [
+      {"type": "merge", "file": "config/app.json"},
+      {"type": "rename", "file": "config/app.json", "from": "ui.theme", "to": "appearance.theme"},
+      {"type": "remove", "file": "config/app.ini", "section": "old", "key": "unused"},
+      {"type": "script", "command": ["tools/migrate.exe"], "timeout": 60}]}]}
+  当前版本 >= from（可选）且 < to <= 新版本时执行，按 to 升序执行；当前版本未知时只执行 merge 步骤
+  脚本在安装目录中执行，可读取环境变量 HS_UPDATE_FROM_VERSION、HS_UPDATE_TO_VERSION、HS_UPDATE_TARGET_DIR
+
+更新钩子:
+  更新包根目录下的 update-hooks.json 声明各阶段执行的命令或内置动作，输出写入详细日志，例如:
+    {"hooks": [
+      {"event": "pre-update", "action": "delete", "path": "cache"},
+      {"event": "post-update", "command": ["schtasks", "/Create", "/XML", "task.xml", "/TN", "hs-script"], "timeout": 30, "onFailure": "continue"}]}
+  event: pre-update（写入文件前）、post-copy（复制文件后、配置迁移前）、post-update（启动主程序前）、on-rollback（恢复备份后）
+  action: delete、mkdir、move（path/to 相对于安装目录）；command 可配合 workDir、env、timeout（默认 60 秒）
+  onFailure: fail（默认，中止更新）或 continue（记录警告后继续）
+  命令可读取环境变量 HS_UPDATE_FROM_VERSION、HS_UPDATE_TO_VERSION、HS_UPDATE_TARGET_DIR、HS_UPDATE_PACKAGE
+
+第三方插件:
+  更新后检查 plugin/ 下各插件目录中的 plugin.json，例如:
+    {"id": "my-plugin", "name": "我的插件", "version": "1.2.0", "requires": ">=4.13.0 <5.0.0"}
+  requires 不包含新版本的插件会被移动到 plugin-disabled/，并写入 DISABLED.txt 说明原因
+
+plugin 命令:
+  plugin list                  列出已安装的插件
+  plugin check [id]            检查插件是否有兼容当前主程序的新版本
+  plugin update <id>           下载并安装插件的最新兼容版本（安装到 plugin/<id>，失败时保留旧版本）
+  插件需在 plugin.json 中声明发布仓库，例如:
+    "repository": {"type": "github", "user": "someone", "project": "my-plugin", "asset": "{id}_{version}.zip"}
+
+plugin 命令选项:
+  --target=<dir>               安装目录（默认为更新器所在目录）
+  --core-version=<version>     主程序版本（默认读取 data/install-manifest.json 中的记录）
+  -d                           包括预发布版本
+  --json                       输出 JSON
+
+verify 命令选项:
+  --health-check               以 --health-check 参数启动主程序进行检查
+  --health-timeout=<秒>        健康检查超时时间（默认 30 秒）
+  --main-program=<path>        主程序路径（默认为安装目录下的 hs-script.exe）
 
 check/latest 命令选项:
   -d, --dev                    检查/获取开发版
   -n, --native                 Native 版本（默认为 JVM 版本）
   -i, --interactive            交互模式（控制台显示）
   -r, --repo                   仓库源 (gitee/github，默认 gitee)
+  --target=<dir>               (check) 从安装目录中识别当前版本和类型，识别顺序:
+                               data/version.json > lib 中主程序 jar 的清单 > hs-script.exe 的版本资源
+
+日志选项（所有命令）:
+  --log-level=<level>          日志级别 debug/info/warn/error（默认 info，debug 会记录每个文件的处理结果）
+  --log-file=<path>            日志文件（默认为安装目录下的 log/updater.log，不可写时使用用户缓存目录，off 表示不写文件）
+  --log-format=<text|json>     日志文件格式（默认 text），文件超过 5 MB 时轮转，保留 3 个历史文件
 
 通用选项:
   -h, --help                   显示帮助信息
+  --version                    显示版本号
+
+更新器自更新:
+  更新包中包含与当前更新器同名的文件时，更新完成后先将当前更新器重命名为 <name>.old，
+  再将新版本移入原位置并以 --version 检查能否正常启动，检查失败时恢复旧版本。
+  无法立即删除的 <name>.old 在下次启动时清理。
 `, version)
 }